@@ -3,8 +3,11 @@ package bot
 import (
 	"context"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/crypto/attachment"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/format"
 	"maunium.net/go/mautrix/id"
@@ -23,18 +26,158 @@ type SendNoticeOpts struct {
 	Mentions         *event.Mentions
 }
 
-func (bot *Bot) SendNoticeOpts(ctx context.Context, roomID id.RoomID, message string, opts *SendNoticeOpts) {
+// MaxNoticeLength is the maximum length of the markdown source of a single notice.
+// Longer messages are split into multiple events, as the rendered HTML is included
+// in the event too and events are limited to 64 KiB in total.
+const MaxNoticeLength = 16 * 1024
+
+// splitNotice splits the given message into chunks of at most MaxNoticeLength bytes, preferring line boundaries.
+// Fenced code blocks that span multiple chunks are closed at the end of each chunk and reopened in the next one,
+// so that every chunk renders correctly on its own.
+func splitNotice(message string) []string {
+	if len(message) <= MaxNoticeLength {
+		return []string{message}
+	}
+	var chunks []string
+	var current strings.Builder
+	// fence is the marker of the currently open code block and opener is the line that reopens it in the next chunk
+	var fence, opener string
+	// base is the length of the reopened fence at the start of the current chunk, which doesn't count as content
+	var base int
+	flush := func() {
+		chunk := current.String()
+		current.Reset()
+		base = 0
+		if fence != "" {
+			chunk = strings.TrimSuffix(chunk, "\n") + "\n" + fence
+			current.WriteString(opener)
+			base = len(opener)
+		}
+		if chunk = strings.TrimSpace(chunk); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+	for _, line := range strings.SplitAfter(message, "\n") {
+		newFence, newOpener := updateFence(fence, opener, line)
+		for len(line) > 0 {
+			limit := MaxNoticeLength
+			if fence != "" {
+				// Leave room for closing the code block
+				limit -= len(fence) + 1
+			}
+			avail := limit - current.Len()
+			if len(line) <= avail {
+				current.WriteString(line)
+				break
+			} else if current.Len() > base {
+				flush()
+				continue
+			}
+			cut := avail
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			current.WriteString(line[:cut])
+			line = line[cut:]
+			flush()
+		}
+		fence, opener = newFence, newOpener
+	}
+	if current.Len() > base {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// maxFenceOpenerLength is the longest code block opening line that is repeated when reopening the block.
+// Longer lines are reopened with just the fence marker.
+const maxFenceOpenerLength = 100
+
+// updateFence returns the code block state after the given line.
+func updateFence(fence, opener, line string) (string, string) {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) < 3 || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return fence, opener
+	}
+	marker := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+	if len(marker) < 3 {
+		return fence, opener
+	} else if fence == "" {
+		opener = trimmed + "\n"
+		if len(opener) > maxFenceOpenerLength {
+			opener = marker + "\n"
+		}
+		return marker, opener
+	} else if marker[0] == fence[0] && len(marker) >= len(fence) && marker == trimmed {
+		return "", ""
+	}
+	return fence, opener
+}
+
+// SendNoticeOpts sends a notice to the given room, splitting it into multiple events if it's too long.
+// The returned event ID is the ID of the last event that was sent.
+func (bot *Bot) SendNoticeOpts(ctx context.Context, roomID id.RoomID, message string, opts *SendNoticeOpts) id.EventID {
 	if opts == nil {
 		opts = &SendNoticeOpts{}
 	}
-	content := format.RenderMarkdown(message, !opts.DisallowMarkdown, opts.AllowHTML)
-	content.MsgType = event.MsgNotice
-	if opts.Mentions != nil {
-		content.Mentions = opts.Mentions
+	var lastEventID id.EventID
+	for i, chunk := range splitNotice(message) {
+		content := format.RenderMarkdown(chunk, !opts.DisallowMarkdown, opts.AllowHTML)
+		content.MsgType = event.MsgNotice
+		if opts.Mentions != nil {
+			if i == 0 {
+				content.Mentions = opts.Mentions
+			} else {
+				// Only ping once even if the message is split
+				content.Mentions = &event.Mentions{}
+			}
+		}
+		resp, err := bot.Client.SendMessageEvent(ctx, roomID, event.EventMessage, &content)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).
+				Msg("Failed to send management room message")
+			return ""
+		}
+		lastEventID = resp.EventID
+	}
+	return lastEventID
+}
+
+// SendFile uploads the given data and sends it to the given room as a file.
+// The file is encrypted if the room is encrypted.
+func (bot *Bot) SendFile(ctx context.Context, roomID id.RoomID, fileName, mimeType string, data []byte) (id.EventID, error) {
+	content := &event.MessageEventContent{
+		MsgType:  event.MsgFile,
+		Body:     fileName,
+		FileName: fileName,
+		Info: &event.FileInfo{
+			MimeType: mimeType,
+			Size:     len(data),
+		},
+	}
+	uploadMime := mimeType
+	var encryptedFile *attachment.EncryptedFile
+	if encrypted, err := bot.Client.StateStore.IsEncrypted(ctx, roomID); err != nil {
+		return "", fmt.Errorf("failed to check if room is encrypted: %w", err)
+	} else if encrypted {
+		encryptedFile = attachment.NewEncryptedFile()
+		encryptedFile.EncryptInPlace(data)
+		uploadMime = "application/octet-stream"
+	}
+	resp, err := bot.Client.UploadBytesWithName(ctx, data, uploadMime, fileName)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	if encryptedFile != nil {
+		content.File = &event.EncryptedFileInfo{EncryptedFile: *encryptedFile, URL: resp.ContentURI.CUString()}
+	} else {
+		content.URL = resp.ContentURI.CUString()
 	}
-	_, err := bot.Client.SendMessageEvent(ctx, roomID, event.EventMessage, &content)
+	sendResp, err := bot.Client.SendMessageEvent(ctx, roomID, event.EventMessage, content)
 	if err != nil {
-		zerolog.Ctx(ctx).Err(err).
-			Msg("Failed to send management room message")
+		return "", fmt.Errorf("failed to send file message: %w", err)
 	}
+	return sendResp.EventID, nil
 }
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSplitNotice_Short(t *testing.T) {
+	chunks := splitNotice("hello\nworld")
+	if len(chunks) != 1 || chunks[0] != "hello\nworld" {
+		t.Fatalf("expected message to be returned as-is, got %q", chunks)
+	}
+}
+
+func TestSplitNotice_ListItemBoundaries(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf("* [@user%d:example.com](https://matrix.to/#/@user%d:example.com) %s", i, i, strings.Repeat("x", 100)))
+	}
+	message := "Matching users:\n\n" + strings.Join(lines, "\n")
+	if len(message) <= MaxNoticeLength {
+		t.Fatalf("test message is too short (%d bytes) to be split", len(message))
+	}
+	chunks := splitNotice(message)
+	if len(chunks) < 2 {
+		t.Fatalf("expected message to be split, got %d chunks", len(chunks))
+	}
+	var itemCount int
+	for i, chunk := range chunks {
+		if len(chunk) > MaxNoticeLength {
+			t.Errorf("chunk %d is %d bytes, which is over the limit of %d", i, len(chunk), MaxNoticeLength)
+		}
+		for _, line := range strings.Split(chunk, "\n") {
+			if !strings.HasPrefix(line, "* ") {
+				continue
+			}
+			if line != lines[itemCount] {
+				t.Fatalf("list item %d was split or modified: got %q, expected %q", itemCount, line, lines[itemCount])
+			}
+			itemCount++
+		}
+	}
+	if itemCount != len(lines) {
+		t.Errorf("expected %d list items across all chunks, got %d", len(lines), itemCount)
+	}
+}
+
+func TestSplitNotice_LongLine(t *testing.T) {
+	// A single line longer than the limit has to be cut, but not in the middle of a multibyte character.
+	message := strings.Repeat("ä", MaxNoticeLength)
+	chunks := splitNotice(message)
+	if len(chunks) < 2 {
+		t.Fatalf("expected long line to be split, got %d chunks", len(chunks))
+	}
+	if joined := strings.Join(chunks, ""); joined != message {
+		t.Errorf("joined chunks don't match the original message")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > MaxNoticeLength {
+			t.Errorf("chunk %d is %d bytes, which is over the limit of %d", i, len(chunk), MaxNoticeLength)
+		}
+	}
+}
+
+func TestSplitNotice_CodeBlock(t *testing.T) {
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(`  {"user_id": "@user%d:example.com", "reason": "%s"},`, i, strings.Repeat("x", 20))
+	}
+	message := "Output:\n\n```json\n" + strings.Join(lines, "\n") + "\n```\n\nDone"
+	chunks := splitNotice(message)
+	if len(chunks) < 2 {
+		t.Fatalf("expected message to be split, got %d chunks", len(chunks))
+	}
+	var itemCount int
+	for i, chunk := range chunks {
+		if len(chunk) > MaxNoticeLength {
+			t.Errorf("chunk %d is %d bytes, which is over the limit of %d", i, len(chunk), MaxNoticeLength)
+		}
+		if strings.Count(chunk, "```")%2 != 0 {
+			t.Errorf("chunk %d has an unclosed code block", i)
+		}
+		for _, line := range strings.Split(chunk, "\n") {
+			if strings.HasPrefix(line, "  {") {
+				if line != lines[itemCount] {
+					t.Fatalf("line %d was split or modified: got %q, expected %q", itemCount, line, lines[itemCount])
+				}
+				itemCount++
+			}
+		}
+	}
+	if itemCount != len(lines) {
+		t.Errorf("expected %d lines across all chunks, got %d", len(lines), itemCount)
+	}
+	if !strings.HasPrefix(chunks[1], "```json\n") {
+		t.Errorf("expected second chunk to reopen the code block, got %q", chunks[1][:20])
+	}
+	if !strings.HasSuffix(chunks[len(chunks)-1], "Done") {
+		t.Errorf("expected text after the code block to be in the last chunk")
+	}
+}
+
+func TestSplitNotice_NoEmptyChunks(t *testing.T) {
+	message := strings.Repeat("x", MaxNoticeLength-1) + "\n" + strings.Repeat(" \n", MaxNoticeLength) + "y"
+	for i, chunk := range splitNotice(message) {
+		if chunk == "" {
+			t.Errorf("chunk %d is empty", i)
+		}
+	}
+}
@@ -41,11 +41,15 @@ type RespGetBots struct {
 func (m *Meowlnir) ManagementAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHash := sha256.Sum256([]byte(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")))
-		if !hmac.Equal(authHash[:], m.ManagementSecret[:]) {
+		if hmac.Equal(authHash[:], m.ManagementSecret[:]) {
+			next.ServeHTTP(w, r)
+		} else if m.ObserverSecret == nil || !hmac.Equal(authHash[:], m.ObserverSecret[:]) {
 			mautrix.MUnknownToken.WithMessage("Invalid management secret").Write(w)
-			return
+		} else if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			mautrix.MForbidden.WithMessage("The observer secret only allows read-only requests").Write(w)
+		} else {
+			next.ServeHTTP(w, r)
 		}
-		next.ServeHTTP(w, r)
 	})
 }
 
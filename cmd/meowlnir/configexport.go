@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/hlog"
+	"go.mau.fi/util/exhttp"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/bot"
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policyeval"
+)
+
+type ExportedManagementRoom struct {
+	RoomID         id.RoomID                          `json:"room_id"`
+	WatchedLists   *config.WatchedListsEventContent   `json:"watched_lists"`
+	ProtectedRooms *config.ProtectedRoomsEventContent `json:"protected_rooms"`
+}
+
+type ExportedConfig struct {
+	Bot             *database.Bot             `json:"bot"`
+	ManagementRooms []*ExportedManagementRoom `json:"management_rooms"`
+}
+
+type ReqImportConfig struct {
+	Config *ExportedConfig `json:"config"`
+	// RoomMapping maps room IDs in the exported config to room IDs to use in the imported config.
+	// It applies to management rooms, watched lists and protected rooms.
+	RoomMapping map[id.RoomID]id.RoomID `json:"room_mapping"`
+}
+
+func (m *Meowlnir) getBotByUsername(username string) *bot.Bot {
+	userID := id.NewUserID(username, m.AS.HomeserverDomain)
+	m.MapLock.RLock()
+	defer m.MapLock.RUnlock()
+	return m.Bots[userID]
+}
+
+func (m *Meowlnir) GetExportConfig(w http.ResponseWriter, r *http.Request) {
+	bot := m.getBotByUsername(r.PathValue("username"))
+	if bot == nil {
+		mautrix.MNotFound.WithMessage("Bot not found").Write(w)
+		return
+	}
+	m.MapLock.RLock()
+	var evaluators []*policyeval.PolicyEvaluator
+	for _, eval := range m.EvaluatorByManagementRoom {
+		if eval.Bot == bot {
+			evaluators = append(evaluators, eval)
+		}
+	}
+	m.MapLock.RUnlock()
+	resp := &ExportedConfig{
+		Bot:             bot.Meta,
+		ManagementRooms: make([]*ExportedManagementRoom, len(evaluators)),
+	}
+	for i, eval := range evaluators {
+		watchedLists, err := eval.GetWatchedListsContent(r.Context())
+		if err != nil {
+			hlog.FromRequest(r).Err(err).Stringer("management_room", eval.ManagementRoom).Msg("Failed to get watched lists")
+			mautrix.MUnknown.WithMessage("Failed to get watched lists of " + eval.ManagementRoom.String()).Write(w)
+			return
+		}
+		protectedRooms, err := eval.GetProtectedRoomsContent(r.Context())
+		if err != nil {
+			hlog.FromRequest(r).Err(err).Stringer("management_room", eval.ManagementRoom).Msg("Failed to get protected rooms")
+			mautrix.MUnknown.WithMessage("Failed to get protected rooms of " + eval.ManagementRoom.String()).Write(w)
+			return
+		}
+		resp.ManagementRooms[i] = &ExportedManagementRoom{
+			RoomID:         eval.ManagementRoom,
+			WatchedLists:   watchedLists,
+			ProtectedRooms: protectedRooms,
+		}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func remapRoomID(mapping map[id.RoomID]id.RoomID, roomID id.RoomID) id.RoomID {
+	if mapped, ok := mapping[roomID]; ok {
+		return mapped
+	}
+	return roomID
+}
+
+func (m *Meowlnir) PostImportConfig(w http.ResponseWriter, r *http.Request) {
+	bot := m.getBotByUsername(r.PathValue("username"))
+	if bot == nil {
+		mautrix.MNotFound.WithMessage("Bot not found").Write(w)
+		return
+	}
+	var req ReqImportConfig
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		mautrix.MNotJSON.WithMessage("Invalid JSON").Write(w)
+		return
+	} else if req.Config == nil {
+		mautrix.MBadJSON.WithMessage("Missing config to import").Write(w)
+		return
+	}
+	for _, room := range req.Config.ManagementRooms {
+		room.RoomID = remapRoomID(req.RoomMapping, room.RoomID)
+		if room.WatchedLists == nil {
+			room.WatchedLists = &config.WatchedListsEventContent{}
+		}
+		if room.ProtectedRooms == nil {
+			room.ProtectedRooms = &config.ProtectedRoomsEventContent{}
+		}
+		for i := range room.WatchedLists.Lists {
+			room.WatchedLists.Lists[i].RoomID = remapRoomID(req.RoomMapping, room.WatchedLists.Lists[i].RoomID)
+		}
+		for i, roomID := range room.ProtectedRooms.Rooms {
+			room.ProtectedRooms.Rooms[i] = remapRoomID(req.RoomMapping, roomID)
+		}
+		if room.ProtectedRooms.Sources != nil {
+			sources := make(map[id.RoomID]config.ProtectionSource, len(room.ProtectedRooms.Sources))
+			for roomID, source := range room.ProtectedRooms.Sources {
+				sources[remapRoomID(req.RoomMapping, roomID)] = source
+			}
+			room.ProtectedRooms.Sources = sources
+		}
+		if err = room.WatchedLists.Validate(); err != nil {
+			mautrix.MBadJSON.WithMessage("Invalid watched lists for " + room.RoomID.String() + ": " + err.Error()).Write(w)
+			return
+		} else if err = room.ProtectedRooms.Validate(); err != nil {
+			mautrix.MBadJSON.WithMessage("Invalid protected rooms for " + room.RoomID.String() + ": " + err.Error()).Write(w)
+			return
+		}
+	}
+	for _, room := range req.Config.ManagementRooms {
+		log := hlog.FromRequest(r).With().Stringer("management_room", room.RoomID).Logger()
+		_, err = bot.JoinRoomByID(r.Context(), room.RoomID)
+		if err != nil {
+			log.Err(err).Msg("Failed to join management room for import")
+			mautrix.MUnknown.WithMessage("Failed to join " + room.RoomID.String() + ": " + err.Error()).Write(w)
+			return
+		}
+		_, err = bot.SendStateEvent(r.Context(), room.RoomID, config.StateWatchedLists, "", room.WatchedLists)
+		if err != nil {
+			log.Err(err).Msg("Failed to import watched lists")
+			mautrix.MUnknown.WithMessage("Failed to import watched lists to " + room.RoomID.String() + ": " + err.Error()).Write(w)
+			return
+		}
+		_, err = bot.SendStateEvent(r.Context(), room.RoomID, config.StateProtectedRooms, "", room.ProtectedRooms)
+		if err != nil {
+			log.Err(err).Msg("Failed to import protected rooms")
+			mautrix.MUnknown.WithMessage("Failed to import protected rooms to " + room.RoomID.String() + ": " + err.Error()).Write(w)
+			return
+		}
+		err = m.DB.ManagementRoom.Put(r.Context(), room.RoomID, bot.Meta.Username)
+		if err != nil {
+			log.Err(err).Msg("Failed to save imported management room to database")
+			mautrix.MUnknown.WithMessage("Failed to save management room to database").Write(w)
+			return
+		}
+		m.loadManagementRoom(r.Context(), room.RoomID, bot)
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, req.Config)
+}
@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/rs/zerolog"
 	"maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 
+	"go.mau.fi/meowlnir/bot"
 	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policyeval"
+	"go.mau.fi/meowlnir/policylist"
 )
 
 func (m *Meowlnir) AddEventHandlers() {
@@ -38,6 +43,7 @@ func (m *Meowlnir) AddEventHandlers() {
 	m.EventProcessor.On(event.StateUnstablePolicyUser, m.UpdatePolicyList)
 	m.EventProcessor.On(event.StateUnstablePolicyRoom, m.UpdatePolicyList)
 	m.EventProcessor.On(event.StateUnstablePolicyServer, m.UpdatePolicyList)
+	m.EventProcessor.On(policylist.StatePolicyContent, m.UpdatePolicyList)
 	m.EventProcessor.On(event.EventRedaction, m.UpdatePolicyList)
 	// Management room config
 	m.EventProcessor.On(config.StateWatchedLists, m.HandleConfigChange)
@@ -47,7 +53,13 @@ func (m *Meowlnir) AddEventHandlers() {
 	m.EventProcessor.On(event.StateMember, m.HandleMember)
 	m.EventProcessor.On(event.EventMessage, m.HandleMessage)
 	m.EventProcessor.On(event.EventSticker, m.HandleMessage)
+	m.EventProcessor.On(event.EventReaction, m.HandleReaction)
 	m.EventProcessor.On(event.EventEncrypted, m.HandleEncrypted)
+	for _, evtType := range m.Config.Meowlnir.ContentEventTypes {
+		if evtType != event.EventMessage.Type && evtType != event.EventSticker.Type {
+			m.EventProcessor.On(event.Type{Type: evtType, Class: event.MessageEventType}, m.HandleCustomContent)
+		}
+	}
 }
 
 func (m *Meowlnir) HandleToDeviceEvent(ctx context.Context, evt *event.Event) {
@@ -80,11 +92,33 @@ func (m *Meowlnir) HandleOTKCounts(ctx context.Context, evt *mautrix.OTKCount) {
 
 func (m *Meowlnir) UpdatePolicyList(ctx context.Context, evt *event.Event) {
 	added, removed := m.PolicyStore.Update(evt)
+	m.recordPolicyHistory(ctx, evt, removed, database.PolicyChangeRemoved)
+	m.recordPolicyHistory(ctx, evt, added, database.PolicyChangeAdded)
 	for _, eval := range m.EvaluatorByManagementRoom {
 		eval.HandlePolicyListChange(ctx, evt.RoomID, added, removed)
 	}
 }
 
+func (m *Meowlnir) recordPolicyHistory(ctx context.Context, evt *event.Event, policy *policylist.Policy, change database.PolicyChange) {
+	if policy == nil {
+		return
+	}
+	err := m.DB.PolicyHistory.Put(ctx, &database.PolicyHistory{
+		EventID:        evt.ID,
+		PolicyList:     evt.RoomID,
+		EntityType:     string(policy.EntityType),
+		Entity:         policy.Entity,
+		Recommendation: policy.Recommendation,
+		Reason:         policy.Reason,
+		Sender:         evt.Sender,
+		Change:         change,
+		ChangedAt:      time.UnixMilli(evt.Timestamp),
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("event_id", evt.ID).Msg("Failed to save policy history")
+	}
+}
+
 func (m *Meowlnir) HandleConfigChange(ctx context.Context, evt *event.Event) {
 	m.MapLock.RLock()
 	managementRoom, isManagement := m.EvaluatorByManagementRoom[evt.RoomID]
@@ -121,12 +155,24 @@ func (m *Meowlnir) HandleMember(ctx context.Context, evt *event.Event) {
 				Msg("Joined management room after invite, loading room state")
 			managementRoom.Load(ctx)
 		}
+	} else if botOK && !managementOK && !protectedOK && content.Membership == event.MembershipInvite && m.Config.Meowlnir.AutoProtectOnInvite {
+		m.autoProtectInvitedRoom(ctx, bot, evt)
 	}
 	if protectedOK {
 		roomProtector.HandleMember(ctx, evt)
 	}
 }
 
+func (m *Meowlnir) HandleReaction(ctx context.Context, evt *event.Event) {
+	m.MapLock.RLock()
+	_, isBot := m.Bots[evt.Sender]
+	managementRoom, isManagement := m.EvaluatorByManagementRoom[evt.RoomID]
+	m.MapLock.RUnlock()
+	if !isBot && isManagement && managementRoom.Admins.Has(evt.Sender) {
+		managementRoom.HandleReaction(ctx, evt)
+	}
+}
+
 func (m *Meowlnir) HandleEncrypted(ctx context.Context, evt *event.Event) {
 	m.MapLock.RLock()
 	_, isBot := m.Bots[evt.Sender]
@@ -164,3 +210,39 @@ func (m *Meowlnir) HandleMessage(ctx context.Context, evt *event.Event) {
 		roomProtector.HandleMessage(ctx, evt)
 	}
 }
+
+func (m *Meowlnir) HandleCustomContent(ctx context.Context, evt *event.Event) {
+	m.MapLock.RLock()
+	_, isBot := m.Bots[evt.Sender]
+	roomProtector, isProtected := m.EvaluatorByProtectedRoom[evt.RoomID]
+	m.MapLock.RUnlock()
+	if !isBot && isProtected {
+		roomProtector.HandleCustomContent(ctx, evt)
+	}
+}
+
+func (m *Meowlnir) autoProtectInvitedRoom(ctx context.Context, bot *bot.Bot, evt *event.Event) {
+	var target *policyeval.PolicyEvaluator
+	var matchCount int
+	m.MapLock.RLock()
+	for _, eval := range m.EvaluatorByManagementRoom {
+		if eval.Bot == bot && eval.Admins.Has(evt.Sender) {
+			target = eval
+			matchCount++
+		}
+	}
+	m.MapLock.RUnlock()
+	log := zerolog.Ctx(ctx).With().
+		Stringer("room_id", evt.RoomID).
+		Stringer("inviter", evt.Sender).
+		Logger()
+	if matchCount == 0 {
+		log.Debug().Msg("Ignoring invite from non-admin")
+		return
+	} else if matchCount > 1 {
+		log.Warn().Int("management_room_count", matchCount).
+			Msg("Not auto-protecting room as inviter is an admin in multiple management rooms")
+		return
+	}
+	target.AutoProtectInvitedRoom(ctx, evt.RoomID, evt.Sender)
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"net/http"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/hlog"
 	"go.mau.fi/util/exhttp"
@@ -10,6 +12,8 @@ import (
 )
 
 func (m *Meowlnir) AddHTTPEndpoints() {
+	m.AS.Router.Use(m.trackTransactions)
+
 	clientRouter := http.NewServeMux()
 	clientRouter.HandleFunc("POST /v3/rooms/{roomID}/report/{eventID}", m.PostReport)
 	clientRouter.HandleFunc("POST /v3/rooms/{roomID}", m.PostReport)
@@ -21,11 +25,29 @@ func (m *Meowlnir) AddHTTPEndpoints() {
 		m.ClientAuth,
 	))
 
+	appealRouter := http.NewServeMux()
+	appealRouter.HandleFunc("POST /v1/appeal", m.PostAppeal)
+	// The appeal endpoint is authenticated with the user's own access token rather than the management secret,
+	// so it must be registered before the management API prefix.
+	m.AS.Router.Path("/_matrix/meowlnir/v1/appeal").Handler(applyMiddleware(
+		http.StripPrefix("/_matrix/meowlnir", appealRouter),
+		hlog.NewHandler(m.Log.With().Str("component", "appeal api").Logger()),
+		exhttp.CORSMiddleware,
+		requestlog.AccessLogger(false),
+		m.ClientAuth,
+	))
+
 	managementRouter := http.NewServeMux()
 	managementRouter.HandleFunc("GET /v1/bots", m.GetBots)
 	managementRouter.HandleFunc("PUT /v1/bot/{username}", m.PutBot)
 	managementRouter.HandleFunc("POST /v1/bot/{username}/verify", m.PostVerifyBot)
 	managementRouter.HandleFunc("PUT /v1/management_room/{roomID}", m.PutManagementRoom)
+	managementRouter.HandleFunc("GET /v1/bot/{username}/config/export", m.GetExportConfig)
+	managementRouter.HandleFunc("POST /v1/bot/{username}/config/import", m.PostImportConfig)
+	managementRouter.HandleFunc("GET /v1/bot/{username}/management_room/{roomID}/watched_lists", m.GetWatchedLists)
+	managementRouter.HandleFunc("PUT /v1/bot/{username}/management_room/{roomID}/watched_lists", m.PutWatchedLists)
+	managementRouter.HandleFunc("GET /v1/bot/{username}/management_room/{roomID}/protected_rooms", m.GetProtectedRooms)
+	managementRouter.HandleFunc("PUT /v1/bot/{username}/management_room/{roomID}/protected_rooms", m.PutProtectedRooms)
 
 	m.AS.Router.PathPrefix("/_matrix/meowlnir").Handler(applyMiddleware(
 		http.StripPrefix("/_matrix/meowlnir", managementRouter),
@@ -33,9 +55,19 @@ func (m *Meowlnir) AddHTTPEndpoints() {
 		exhttp.CORSMiddleware,
 		requestlog.AccessLogger(false),
 		m.ManagementAuth,
+		m.IdempotencyCache.Middleware,
 	))
 }
 
+func (m *Meowlnir) trackTransactions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/transactions/") {
+			m.lastTransaction.Store(time.Now().UnixMilli())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func applyMiddleware(router http.Handler, middleware ...func(http.Handler) http.Handler) http.Handler {
 	slices.Reverse(middleware)
 	for _, m := range middleware {
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"maunium.net/go/mautrix"
+)
+
+const (
+	idempotencyCacheSize = 1000
+	idempotencyCacheTTL  = 10 * time.Minute
+)
+
+var ErrIdempotentRequestInProgress = mautrix.RespError{
+	ErrCode:    "FI.MAU.MEOWLNIR.REQUEST_IN_PROGRESS",
+	Err:        "A request with the same idempotency key is still being processed.",
+	StatusCode: http.StatusConflict,
+}
+
+var ErrIdempotencyKeyReused = mautrix.RespError{
+	ErrCode:    "FI.MAU.MEOWLNIR.IDEMPOTENCY_KEY_REUSED",
+	Err:        "The idempotency key was already used for a request with a different body.",
+	StatusCode: http.StatusUnprocessableEntity,
+}
+
+type cachedResponse struct {
+	key         string
+	bodyHash    [32]byte
+	status      int
+	contentType string
+	body        []byte
+	createdAt   time.Time
+	done        bool
+}
+
+// IdempotencyCache is an in-memory LRU of responses to mutating requests keyed by the Idempotency-Key header
+// and the credentials used to make the request.
+type IdempotencyCache struct {
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func NewIdempotencyCache() *IdempotencyCache {
+	return &IdempotencyCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// reserve returns the cached response for the given key if there is one.
+// If there isn't, a placeholder is stored and nil is returned.
+func (ic *IdempotencyCache) reserve(key string, bodyHash [32]byte) *cachedResponse {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	if elem, ok := ic.entries[key]; ok {
+		resp := elem.Value.(*cachedResponse)
+		if time.Since(resp.createdAt) < idempotencyCacheTTL {
+			ic.order.MoveToFront(elem)
+			return resp
+		}
+		ic.order.Remove(elem)
+		delete(ic.entries, key)
+	}
+	ic.entries[key] = ic.order.PushFront(&cachedResponse{key: key, bodyHash: bodyHash, createdAt: time.Now()})
+	for ic.order.Len() > idempotencyCacheSize {
+		oldest := ic.order.Back()
+		ic.order.Remove(oldest)
+		delete(ic.entries, oldest.Value.(*cachedResponse).key)
+	}
+	return nil
+}
+
+func (ic *IdempotencyCache) finish(key string, status int, contentType string, body []byte) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	if elem, ok := ic.entries[key]; ok {
+		resp := elem.Value.(*cachedResponse)
+		resp.status = status
+		resp.contentType = contentType
+		resp.body = body
+		resp.done = true
+	}
+}
+
+func (ic *IdempotencyCache) forget(key string) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	if elem, ok := ic.entries[key]; ok {
+		ic.order.Remove(elem)
+		delete(ic.entries, key)
+	}
+}
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rrw *recordingResponseWriter) WriteHeader(status int) {
+	if rrw.status == 0 {
+		rrw.status = status
+	}
+	rrw.ResponseWriter.WriteHeader(status)
+}
+
+func (rrw *recordingResponseWriter) Write(data []byte) (int, error) {
+	if rrw.status == 0 {
+		rrw.status = http.StatusOK
+	}
+	rrw.body.Write(data)
+	return rrw.ResponseWriter.Write(data)
+}
+
+// Middleware makes mutating requests with an Idempotency-Key header return the original response when retried.
+func (ic *IdempotencyCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get("Idempotency-Key")
+		if idempotencyKey == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			mautrix.MUnknown.WithMessage("Failed to read request body").Write(w)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		// Keys are scoped to the credentials, so that different callers can't see each other's responses
+		authHash := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+		key := r.Method + " " + r.URL.Path + " " + hex.EncodeToString(authHash[:]) + " " + idempotencyKey
+		bodyHash := sha256.Sum256(body)
+		if cached := ic.reserve(key, bodyHash); cached != nil {
+			if cached.bodyHash != bodyHash {
+				ErrIdempotencyKeyReused.Write(w)
+				return
+			} else if !cached.done {
+				ErrIdempotentRequestInProgress.Write(w)
+				return
+			}
+			if cached.contentType != "" {
+				w.Header().Set("Content-Type", cached.contentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(cached.status)
+			_, _ = w.Write(cached.body)
+			return
+		}
+		recorder := &recordingResponseWriter{ResponseWriter: w}
+		defer func() {
+			if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+				// Don't cache server errors (or panics) so that the request can be retried
+				ic.forget(key)
+			} else {
+				ic.finish(key, recorder.status, w.Header().Get("Content-Type"), recorder.body.Bytes())
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
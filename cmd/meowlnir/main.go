@@ -10,7 +10,9 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/rs/zerolog"
@@ -50,32 +52,61 @@ type Meowlnir struct {
 	EventProcessor *appservice.EventProcessor
 
 	ManagementSecret [32]byte
+	ObserverSecret   *[32]byte
+	IdempotencyCache *IdempotencyCache
 
 	PolicyStore               *policylist.Store
 	MapLock                   sync.RWMutex
 	Bots                      map[id.UserID]*bot.Bot
 	EvaluatorByProtectedRoom  map[id.RoomID]*policyeval.PolicyEvaluator
 	EvaluatorByManagementRoom map[id.RoomID]*policyeval.PolicyEvaluator
+
+	lastTransaction atomic.Int64
+}
+
+// LastTransaction returns the time when the homeserver last sent an appservice transaction,
+// or the zero time if no transactions have been received since startup.
+func (m *Meowlnir) LastTransaction() time.Time {
+	if ts := m.lastTransaction.Load(); ts != 0 {
+		return time.UnixMilli(ts)
+	}
+	return time.Time{}
+}
+
+// hashSecret returns the SHA-256 hash of the given secret,
+// or decodes the hash directly if the secret is prefixed with "sha256:".
+func hashSecret(secret string) ([32]byte, error) {
+	if !strings.HasPrefix(secret, "sha256:") {
+		return sha256.Sum256([]byte(secret)), nil
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(secret, "sha256:"))
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("failed to decode secret hash: %w", err)
+	} else if len(decoded) != 32 {
+		return [32]byte{}, fmt.Errorf("secret hash is not 32 bytes long")
+	}
+	return [32]byte(decoded), nil
 }
 
 func (m *Meowlnir) Init(configPath string, noSaveConfig bool) {
 	var err error
 	m.Config = loadConfig(configPath, noSaveConfig)
-	if strings.HasPrefix(m.Config.Meowlnir.ManagementSecret, "sha256:") {
-		var decoded []byte
-		decoded, err = hex.DecodeString(strings.TrimPrefix(m.Config.Meowlnir.ManagementSecret, "sha256:"))
+	m.ManagementSecret, err = hashSecret(m.Config.Meowlnir.ManagementSecret)
+	if err != nil {
+		m.Log.WithLevel(zerolog.FatalLevel).Err(err).Msg("Failed to parse management secret")
+		os.Exit(10)
+	}
+	if m.Config.Meowlnir.ObserverSecret != "" {
+		var observerSecret [32]byte
+		observerSecret, err = hashSecret(m.Config.Meowlnir.ObserverSecret)
 		if err != nil {
-			m.Log.WithLevel(zerolog.FatalLevel).Err(err).Msg("Failed to decode management secret hash")
-			os.Exit(10)
-		} else if len(decoded) != 32 {
-			m.Log.WithLevel(zerolog.FatalLevel).Msg("Management secret hash is not 32 bytes long")
+			m.Log.WithLevel(zerolog.FatalLevel).Err(err).Msg("Failed to parse observer secret")
 			os.Exit(10)
 		}
-		m.ManagementSecret = [32]byte(decoded)
-	} else {
-		m.ManagementSecret = sha256.Sum256([]byte(m.Config.Meowlnir.ManagementSecret))
+		m.ObserverSecret = &observerSecret
 	}
 	policylist.HackyRuleFilter = m.Config.Meowlnir.HackyRuleFilter
+	policylist.RecommendationMapping = m.Config.Meowlnir.RecommendationMapping
 
 	m.Log, err = m.Config.Logging.Compile()
 	if err != nil {
@@ -139,6 +170,7 @@ func (m *Meowlnir) Init(configPath string, noSaveConfig bool) {
 	m.AS.Log = m.Log.With().Str("component", "matrix").Logger()
 	m.AS.StateStore = m.StateStore
 	m.EventProcessor = appservice.NewEventProcessor(m.AS)
+	m.IdempotencyCache = NewIdempotencyCache()
 	m.AddEventHandlers()
 	m.AddHTTPEndpoints()
 
@@ -191,7 +223,7 @@ func (m *Meowlnir) initBot(ctx context.Context, db *database.Bot) *bot.Bot {
 	}
 	for _, roomID := range managementRooms {
 		m.EvaluatorByManagementRoom[roomID] = policyeval.NewPolicyEvaluator(
-			wrapped, m.PolicyStore, roomID, m.DB, m.SynapseDB, m.claimProtectedRoom, m.Config.Meowlnir.DryRun,
+			wrapped, m.PolicyStore, roomID, m.DB, m.SynapseDB, m.claimProtectedRoom, m.LastTransaction, evaluatorVersion(), &m.Config.Meowlnir,
 		)
 	}
 	return wrapped
@@ -213,7 +245,7 @@ func (m *Meowlnir) loadManagementRoom(ctx context.Context, roomID id.RoomID, bot
 		}
 	}
 	eval = policyeval.NewPolicyEvaluator(
-		bot, m.PolicyStore, roomID, m.DB, m.SynapseDB, m.claimProtectedRoom, m.Config.Meowlnir.DryRun,
+		bot, m.PolicyStore, roomID, m.DB, m.SynapseDB, m.claimProtectedRoom, m.LastTransaction, evaluatorVersion(), &m.Config.Meowlnir,
 	)
 	m.EvaluatorByManagementRoom[roomID] = eval
 	go eval.Load(ctx)
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/hlog"
+	"go.mau.fi/util/exhttp"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/policyeval"
+)
+
+func (m *Meowlnir) getEvaluatorForRequest(w http.ResponseWriter, r *http.Request) *policyeval.PolicyEvaluator {
+	roomID := id.RoomID(r.PathValue("roomID"))
+	m.MapLock.RLock()
+	eval, ok := m.EvaluatorByManagementRoom[roomID]
+	m.MapLock.RUnlock()
+	if !ok || eval.Bot.Meta.Username != r.PathValue("username") {
+		mautrix.MNotFound.WithMessage("Management room not found").Write(w)
+		return nil
+	}
+	return eval
+}
+
+func (m *Meowlnir) GetWatchedLists(w http.ResponseWriter, r *http.Request) {
+	eval := m.getEvaluatorForRequest(w, r)
+	if eval == nil {
+		return
+	}
+	content, err := eval.GetWatchedListsContent(r.Context())
+	if err != nil {
+		hlog.FromRequest(r).Err(err).Msg("Failed to get watched lists")
+		mautrix.MUnknown.WithMessage("Failed to get watched lists").Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, content)
+}
+
+func (m *Meowlnir) PutWatchedLists(w http.ResponseWriter, r *http.Request) {
+	eval := m.getEvaluatorForRequest(w, r)
+	if eval == nil {
+		return
+	}
+	var req config.WatchedListsEventContent
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		mautrix.MNotJSON.WithMessage("Invalid JSON").Write(w)
+		return
+	} else if err = req.Validate(); err != nil {
+		mautrix.MBadJSON.WithMessage(err.Error()).Write(w)
+		return
+	}
+	if req.Lists == nil {
+		req.Lists = []config.WatchedPolicyList{}
+	}
+	_, err = eval.Bot.SendStateEvent(r.Context(), eval.ManagementRoom, config.StateWatchedLists, "", &req)
+	if err != nil {
+		hlog.FromRequest(r).Err(err).Msg("Failed to update watched lists")
+		mautrix.MUnknown.WithMessage("Failed to update watched lists: " + err.Error()).Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &req)
+}
+
+func (m *Meowlnir) GetProtectedRooms(w http.ResponseWriter, r *http.Request) {
+	eval := m.getEvaluatorForRequest(w, r)
+	if eval == nil {
+		return
+	}
+	content, err := eval.GetProtectedRoomsContent(r.Context())
+	if err != nil {
+		hlog.FromRequest(r).Err(err).Msg("Failed to get protected rooms")
+		mautrix.MUnknown.WithMessage("Failed to get protected rooms").Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, content)
+}
+
+func (m *Meowlnir) PutProtectedRooms(w http.ResponseWriter, r *http.Request) {
+	eval := m.getEvaluatorForRequest(w, r)
+	if eval == nil {
+		return
+	}
+	var req config.ProtectedRoomsEventContent
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		mautrix.MNotJSON.WithMessage("Invalid JSON").Write(w)
+		return
+	} else if err = req.Validate(); err != nil {
+		mautrix.MBadJSON.WithMessage(err.Error()).Write(w)
+		return
+	}
+	if req.Rooms == nil {
+		req.Rooms = []id.RoomID{}
+	}
+	_, err = eval.Bot.SendStateEvent(r.Context(), eval.ManagementRoom, config.StateProtectedRooms, "", &req)
+	if err != nil {
+		hlog.FromRequest(r).Err(err).Msg("Failed to update protected rooms")
+		mautrix.MUnknown.WithMessage("Failed to update protected rooms: " + err.Error()).Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &req)
+}
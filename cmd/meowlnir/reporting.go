@@ -83,3 +83,41 @@ func (m *Meowlnir) PostReport(w http.ResponseWriter, r *http.Request) {
 		exhttp.WriteEmptyJSONResponse(w, http.StatusOK)
 	}
 }
+
+type ReqAppeal struct {
+	Reason string `json:"reason"`
+}
+
+func (m *Meowlnir) PostAppeal(w http.ResponseWriter, r *http.Request) {
+	var req ReqAppeal
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		mautrix.MBadJSON.WithMessage("Invalid JSON").Write(w)
+		return
+	}
+	m.MapLock.RLock()
+	mgmtRoom, ok := m.EvaluatorByManagementRoom[m.Config.Meowlnir.ReportRoom]
+	m.MapLock.RUnlock()
+	if !ok {
+		mautrix.MUnrecognized.WithMessage("Appeals are not configured correctly").Write(w)
+		return
+	}
+	userID := r.Context().Value(contextKeyClientUserID).(id.UserID)
+	log := hlog.FromRequest(r).With().
+		Stringer("appeal_sender", userID).
+		Str("action", "handle appeal").
+		Logger()
+	ctx := context.WithoutCancel(log.WithContext(r.Context()))
+	err = mgmtRoom.HandleAppeal(ctx, userID, req.Reason)
+	if err != nil {
+		log.Err(err).Msg("Failed to handle appeal")
+		var respErr mautrix.RespError
+		if errors.As(err, &respErr) {
+			respErr.Write(w)
+		} else {
+			mautrix.MUnknown.WithMessage(err.Error()).Write(w)
+		}
+	} else {
+		exhttp.WriteEmptyJSONResponse(w, http.StatusOK)
+	}
+}
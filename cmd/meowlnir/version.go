@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"maunium.net/go/mautrix"
+
+	"go.mau.fi/meowlnir/policyeval"
 )
 
 const (
@@ -56,3 +58,7 @@ func initVersion() {
 	mautrix.DefaultUserAgent = fmt.Sprintf("%s/%s %s", Name, VersionWithCommit, mautrix.DefaultUserAgent)
 	VersionDescription = fmt.Sprintf("%s %s (%s)", Name, VersionWithCommit, builtWith)
 }
+
+func evaluatorVersion() policyeval.VersionInfo {
+	return policyeval.VersionInfo{Version: VersionWithCommit, Linkified: LinkifiedVersion}
+}
@@ -2,9 +2,11 @@ package config
 
 import (
 	_ "embed"
+	"time"
 
 	"go.mau.fi/util/dbutil"
 	"go.mau.fi/zeroconfig"
+	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 )
 
@@ -26,10 +28,90 @@ type MeowlnirConfig struct {
 	Port     uint16 `yaml:"port"`
 
 	ManagementSecret string `yaml:"management_secret"`
+	ObserverSecret   string `yaml:"observer_secret"`
 	DryRun           bool   `yaml:"dry_run"`
+	QuietStartup     bool   `yaml:"quiet_startup"`
 
-	ReportRoom      id.RoomID `yaml:"report_room"`
-	HackyRuleFilter []string  `yaml:"hacky_rule_filter"`
+	HideBanReasons  bool   `yaml:"hide_ban_reasons"`
+	PublicBanReason string `yaml:"public_ban_reason"`
+
+	ResyncInterval        time.Duration `yaml:"resync_interval"`
+	AutoProtectOnInvite   bool          `yaml:"auto_protect_on_invite"`
+	DenyBannedKnocks      bool          `yaml:"deny_banned_knocks"`
+	MemberCache           bool          `yaml:"member_cache"`
+	MaxConcurrentProtects int           `yaml:"max_concurrent_protects"`
+	ReviewManualUnbans    bool          `yaml:"review_manual_unbans"`
+	ManualUnbanCooldown   time.Duration `yaml:"manual_unban_cooldown"`
+
+	EvaluateMessageSenders bool `yaml:"evaluate_message_senders"`
+	BanOnJoin              bool `yaml:"ban_on_join"`
+	RequireRoomEncryption  bool `yaml:"require_room_encryption"`
+	EnableRoomEncryption   bool `yaml:"enable_room_encryption"`
+
+	RequirePolicyReason   bool `yaml:"require_policy_reason"`
+	RedactionConfirmLimit int  `yaml:"redaction_confirm_limit"`
+	CommandRateLimit      int  `yaml:"command_rate_limit"`
+
+	UntrustedCommandFeedback string `yaml:"untrusted_command_feedback"`
+
+	PoisoningThreshold float64 `yaml:"poisoning_threshold"`
+
+	ReportRoom            id.RoomID              `yaml:"report_room"`
+	ReportEscalation      ReportEscalationConfig `yaml:"report_escalation"`
+	PolicyBurst           PolicyBurstConfig      `yaml:"policy_burst"`
+	InviteRate            InviteRateConfig       `yaml:"invite_rate"`
+	JoinPartFlood         JoinPartFloodConfig    `yaml:"join_part_flood"`
+	ProtectionExemptLevel *int                   `yaml:"protection_exempt_level"`
+	HackyRuleFilter       []string               `yaml:"hacky_rule_filter"`
+
+	RecommendationMapping map[event.PolicyRecommendation]event.PolicyRecommendation `yaml:"recommendation_mapping"`
+
+	ContentEventTypes []string `yaml:"content_event_types"`
+
+	NoticeTemplates map[string]string `yaml:"notice_templates"`
+
+	UpdateCheck UpdateCheckConfig `yaml:"update_check"`
+
+	DuplicateShortcodes DuplicateShortcodeBehavior `yaml:"duplicate_shortcodes"`
+}
+
+// DuplicateShortcodeBehavior controls what happens when multiple watched lists have the same shortcode.
+type DuplicateShortcodeBehavior string
+
+const (
+	// DuplicateShortcodesRefuse watches all the lists, but makes commands refuse the ambiguous shortcode.
+	DuplicateShortcodesRefuse DuplicateShortcodeBehavior = "refuse"
+	// DuplicateShortcodesFirst only watches the first list with each shortcode.
+	DuplicateShortcodesFirst DuplicateShortcodeBehavior = "first"
+)
+
+type UpdateCheckConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	URL     string `yaml:"url"`
+}
+
+type ReportEscalationConfig struct {
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+	Redact    bool          `yaml:"redact"`
+}
+
+type PolicyBurstConfig struct {
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+	Hold      bool          `yaml:"hold"`
+}
+
+type InviteRateConfig struct {
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+	Revoke    bool          `yaml:"revoke"`
+}
+
+type JoinPartFloodConfig struct {
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+	Ban       bool          `yaml:"ban"`
 }
 
 type EncryptionConfig struct {
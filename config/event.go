@@ -1,15 +1,19 @@
 package config
 
 import (
+	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 )
 
 var (
-	StateWatchedLists   = event.Type{Type: "fi.mau.meowlnir.watched_lists", Class: event.StateEventType}
-	StateProtectedRooms = event.Type{Type: "fi.mau.meowlnir.protected_rooms", Class: event.StateEventType}
+	StateWatchedLists    = event.Type{Type: "fi.mau.meowlnir.watched_lists", Class: event.StateEventType}
+	StateProtectedRooms  = event.Type{Type: "fi.mau.meowlnir.protected_rooms", Class: event.StateEventType}
+	StateEvaluatorConfig = event.Type{Type: "fi.mau.meowlnir.evaluator_config", Class: event.StateEventType}
 )
 
 type WatchedPolicyList struct {
@@ -18,17 +22,115 @@ type WatchedPolicyList struct {
 	Shortcode string    `json:"shortcode"`
 	DontApply bool      `json:"dont_apply"`
 	AutoUnban bool      `json:"auto_unban"`
+
+	// AutoRedact makes bans from the list always redact the user's messages regardless of the ban reason.
+	AutoRedact bool `json:"auto_redact,omitempty"`
+
+	// Categories are free-form labels (e.g. "spam" or "csam") shown in notices about actions caused by the list.
+	Categories []string `json:"categories,omitempty"`
+
+	ActionOverride ActionOverride `json:"action_override,omitempty"`
+
+	// Writers limits which admins can send policies to the list using commands.
+	// If empty, all admins of the management room can write to the list.
+	Writers []id.UserID `json:"writers,omitempty"`
+
+	// PublishTo is the shortcode of another watched list where new ban policies from this list are copied.
+	PublishTo string `json:"publish_to,omitempty"`
+	// PublishReview requires an admin to confirm each policy before it's published to the PublishTo list.
+	PublishReview bool `json:"publish_review,omitempty"`
 }
 
+// CanWrite checks whether the given user is allowed to send policies to the list using commands.
+func (l *WatchedPolicyList) CanWrite(userID id.UserID) bool {
+	return len(l.Writers) == 0 || slices.Contains(l.Writers, userID)
+}
+
+// ActionOverride changes what is done when a ban policy from a watched list matches a user.
+type ActionOverride string
+
+const (
+	// ActionOverrideKick makes ban policies kick users instead of banning them.
+	ActionOverrideKick ActionOverride = "kick"
+)
+
 type WatchedListsEventContent struct {
 	Lists []WatchedPolicyList `json:"lists"`
 }
 
+// EvaluatorConfigEventContent contains per-management-room overrides for the global config.
+// The overrides are applied when the management room is loaded.
+type EvaluatorConfigEventContent struct {
+	// DryRun overrides the global dry_run option if set.
+	DryRun *bool `json:"dry_run,omitempty"`
+}
+
 type ProtectedRoomsEventContent struct {
 	Rooms []id.RoomID `json:"rooms"`
+
+	// Sources records how rooms were added to the protected rooms list.
+	// Rooms without an entry were added by editing the list directly.
+	Sources map[id.RoomID]ProtectionSource `json:"sources,omitempty"`
+}
+
+// ProtectionSource describes how a room entered protection.
+type ProtectionSource string
+
+const (
+	ProtectionSourceConfig  ProtectionSource = "config"
+	ProtectionSourceCommand ProtectionSource = "command"
+	ProtectionSourceInvite  ProtectionSource = "invite"
+)
+
+// GetSource returns how the given room was added to the protected rooms list.
+func (c *ProtectedRoomsEventContent) GetSource(roomID id.RoomID) ProtectionSource {
+	if source, ok := c.Sources[roomID]; ok {
+		return source
+	}
+	return ProtectionSourceConfig
+}
+
+// Validate checks that the watched lists event doesn't contain invalid or duplicate lists.
+func (c *WatchedListsEventContent) Validate() error {
+	roomIDs := make(map[id.RoomID]struct{}, len(c.Lists))
+	shortcodes := make(map[string]struct{}, len(c.Lists))
+	for _, list := range c.Lists {
+		if !strings.HasPrefix(string(list.RoomID), "!") {
+			return fmt.Errorf("invalid room ID %q", list.RoomID)
+		} else if list.Shortcode == "" {
+			return fmt.Errorf("missing shortcode for %s", list.RoomID)
+		} else if _, dup := roomIDs[list.RoomID]; dup {
+			return fmt.Errorf("duplicate watched list %s", list.RoomID)
+		} else if _, dup = shortcodes[strings.ToLower(list.Shortcode)]; dup {
+			return fmt.Errorf("duplicate shortcode %q", list.Shortcode)
+		}
+		switch list.ActionOverride {
+		case "", ActionOverrideKick:
+		default:
+			return fmt.Errorf("unknown action override %q for %s", list.ActionOverride, list.RoomID)
+		}
+		roomIDs[list.RoomID] = struct{}{}
+		shortcodes[strings.ToLower(list.Shortcode)] = struct{}{}
+	}
+	return nil
+}
+
+// Validate checks that the protected rooms event doesn't contain invalid or duplicate rooms.
+func (c *ProtectedRoomsEventContent) Validate() error {
+	roomIDs := make(map[id.RoomID]struct{}, len(c.Rooms))
+	for _, roomID := range c.Rooms {
+		if !strings.HasPrefix(string(roomID), "!") {
+			return fmt.Errorf("invalid room ID %q", roomID)
+		} else if _, dup := roomIDs[roomID]; dup {
+			return fmt.Errorf("duplicate protected room %s", roomID)
+		}
+		roomIDs[roomID] = struct{}{}
+	}
+	return nil
 }
 
 func init() {
 	event.TypeMap[StateWatchedLists] = reflect.TypeOf(WatchedListsEventContent{})
 	event.TypeMap[StateProtectedRooms] = reflect.TypeOf(ProtectedRoomsEventContent{})
+	event.TypeMap[StateEvaluatorConfig] = reflect.TypeOf(EvaluatorConfigEventContent{})
 }
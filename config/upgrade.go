@@ -31,9 +31,48 @@ func upgradeConfig(helper up.Helper) {
 	helper.Copy(up.Int, "meowlnir", "port")
 
 	generateOrCopy(helper, "meowlnir", "management_secret")
+	helper.Copy(up.Str, "meowlnir", "observer_secret")
 	helper.Copy(up.Bool, "meowlnir", "dry_run")
+	helper.Copy(up.Bool, "meowlnir", "quiet_startup")
+	helper.Copy(up.Bool, "meowlnir", "hide_ban_reasons")
+	helper.Copy(up.Str, "meowlnir", "public_ban_reason")
+	helper.Copy(up.Str|up.Null, "meowlnir", "resync_interval")
+	helper.Copy(up.Bool, "meowlnir", "auto_protect_on_invite")
+	helper.Copy(up.Bool, "meowlnir", "deny_banned_knocks")
+	helper.Copy(up.Bool, "meowlnir", "member_cache")
+	helper.Copy(up.Int, "meowlnir", "max_concurrent_protects")
+	helper.Copy(up.Bool, "meowlnir", "review_manual_unbans")
+	helper.Copy(up.Str, "meowlnir", "manual_unban_cooldown")
+	helper.Copy(up.Bool, "meowlnir", "evaluate_message_senders")
+	helper.Copy(up.Bool, "meowlnir", "ban_on_join")
+	helper.Copy(up.Bool, "meowlnir", "require_room_encryption")
+	helper.Copy(up.Bool, "meowlnir", "enable_room_encryption")
+	helper.Copy(up.Bool, "meowlnir", "require_policy_reason")
+	helper.Copy(up.Int, "meowlnir", "redaction_confirm_limit")
+	helper.Copy(up.Int, "meowlnir", "command_rate_limit")
+	helper.Copy(up.Str, "meowlnir", "untrusted_command_feedback")
+	helper.Copy(up.Float, "meowlnir", "poisoning_threshold")
 	helper.Copy(up.Str|up.Null, "meowlnir", "report_room")
+	helper.Copy(up.Int, "meowlnir", "report_escalation", "threshold")
+	helper.Copy(up.Str, "meowlnir", "report_escalation", "window")
+	helper.Copy(up.Bool, "meowlnir", "report_escalation", "redact")
+	helper.Copy(up.Int, "meowlnir", "policy_burst", "threshold")
+	helper.Copy(up.Str, "meowlnir", "policy_burst", "window")
+	helper.Copy(up.Bool, "meowlnir", "policy_burst", "hold")
+	helper.Copy(up.Int, "meowlnir", "invite_rate", "threshold")
+	helper.Copy(up.Str, "meowlnir", "invite_rate", "window")
+	helper.Copy(up.Bool, "meowlnir", "invite_rate", "revoke")
+	helper.Copy(up.Int, "meowlnir", "join_part_flood", "threshold")
+	helper.Copy(up.Str, "meowlnir", "join_part_flood", "window")
+	helper.Copy(up.Bool, "meowlnir", "join_part_flood", "ban")
+	helper.Copy(up.Int|up.Null, "meowlnir", "protection_exempt_level")
 	helper.Copy(up.List, "meowlnir", "hacky_rule_filter")
+	helper.Copy(up.Map, "meowlnir", "recommendation_mapping")
+	helper.Copy(up.List, "meowlnir", "content_event_types")
+	helper.Copy(up.Map, "meowlnir", "notice_templates")
+	helper.Copy(up.Bool, "meowlnir", "update_check", "enabled")
+	helper.Copy(up.Str, "meowlnir", "update_check", "url")
+	helper.Copy(up.Str, "meowlnir", "duplicate_shortcodes")
 
 	if secret, ok := helper.Get(up.Str, "meowlnir", "pickle_key"); ok && secret != "generate" {
 		helper.Set(up.Str, secret, "encryption", "pickle_key")
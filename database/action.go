@@ -11,17 +11,25 @@ import (
 
 const (
 	getTakenActionBaseQuery = `
-		SELECT target_user, in_room_id, action_type, policy_list, rule_entity, action, taken_at
+		SELECT target_user, in_room_id, action_type, policy_list, rule_entity, action, categories, reason_code, taken_at
 		FROM taken_action
 	`
 	getTakenActionsByPolicyListQuery = getTakenActionBaseQuery + `WHERE policy_list=$1`
 	getTakenActionsByRuleEntityQuery = getTakenActionBaseQuery + `WHERE policy_list=$1 AND rule_entity=$2`
 	getTakenActionByTargetUserQuery  = getTakenActionBaseQuery + `WHERE target_user=$1 AND action_type=$2`
+	getTakenActionsByTypeQuery       = getTakenActionBaseQuery + `WHERE action_type=$1`
 	insertTakenActionQuery           = `
-		INSERT INTO taken_action (target_user, in_room_id, action_type, policy_list, rule_entity, action, taken_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO taken_action (target_user, in_room_id, action_type, policy_list, rule_entity, action, categories, reason_code, taken_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (target_user, in_room_id, action_type) DO UPDATE
-			SET policy_list=excluded.policy_list, rule_entity=excluded.rule_entity, action=excluded.action, taken_at=excluded.taken_at
+			SET policy_list=excluded.policy_list, rule_entity=excluded.rule_entity, action=excluded.action,
+			    categories=excluded.categories, reason_code=excluded.reason_code, taken_at=excluded.taken_at
+	`
+	deleteTakenActionQuery = `
+		DELETE FROM taken_action WHERE target_user=$1 AND in_room_id=$2 AND action_type=$3
+	`
+	countTakenActionsByReasonCodeQuery = `
+		SELECT in_room_id, reason_code, COUNT(*) FROM taken_action WHERE reason_code<>'' GROUP BY in_room_id, reason_code
 	`
 )
 
@@ -45,10 +53,40 @@ func (taq *TakenActionQuery) GetAllByTargetUser(ctx context.Context, userID id.U
 	return taq.QueryMany(ctx, getTakenActionByTargetUserQuery, userID, actionType)
 }
 
+func (taq *TakenActionQuery) GetAllByType(ctx context.Context, actionType TakenActionType) ([]*TakenAction, error) {
+	return taq.QueryMany(ctx, getTakenActionsByTypeQuery, actionType)
+}
+
+func (taq *TakenActionQuery) Delete(ctx context.Context, ta *TakenAction) error {
+	return taq.Exec(ctx, deleteTakenActionQuery, ta.TargetUser, ta.InRoomID, ta.ActionType)
+}
+
+type ReasonCodeCount struct {
+	RoomID id.RoomID
+	Code   string
+	Count  int
+}
+
+var reasonCodeCountScanner = dbutil.ConvertRowFn[ReasonCodeCount](func(row dbutil.Scannable) (rcc ReasonCodeCount, err error) {
+	err = row.Scan(&rcc.RoomID, &rcc.Code, &rcc.Count)
+	return
+})
+
+// CountByReasonCode returns the number of taken actions per reason code in each room.
+func (taq *TakenActionQuery) CountByReasonCode(ctx context.Context) ([]ReasonCodeCount, error) {
+	return reasonCodeCountScanner.NewRowIter(taq.GetDB().Query(ctx, countTakenActionsByReasonCodeQuery)).AsList()
+}
+
 type TakenActionType string
 
 const (
 	TakenActionTypeBanOrUnban TakenActionType = "ban_or_unban"
+	TakenActionTypeKick       TakenActionType = "kick"
+	// TakenActionTypeContentBan is a ban caused by a content policy, in which case RuleEntity is the pattern.
+	TakenActionTypeContentBan TakenActionType = "content_ban"
+	// TakenActionTypeProtectionBan is a ban caused by a protection rather than a policy list,
+	// in which case PolicyList is empty and RuleEntity describes the protection.
+	TakenActionTypeProtectionBan TakenActionType = "protection_ban"
 )
 
 type TakenAction struct {
@@ -58,16 +96,22 @@ type TakenAction struct {
 	PolicyList id.RoomID
 	RuleEntity string
 	Action     event.PolicyRecommendation
+	Categories []string
+	ReasonCode string
 	TakenAt    time.Time
 }
 
 func (t *TakenAction) sqlVariables() []any {
-	return []any{t.TargetUser, t.InRoomID, t.ActionType, t.PolicyList, t.RuleEntity, t.Action, t.TakenAt.UnixMilli()}
+	var categories dbutil.JSON
+	if len(t.Categories) > 0 {
+		categories.Data = t.Categories
+	}
+	return []any{t.TargetUser, t.InRoomID, t.ActionType, t.PolicyList, t.RuleEntity, t.Action, categories, t.ReasonCode, t.TakenAt.UnixMilli()}
 }
 
 func (t *TakenAction) Scan(row dbutil.Scannable) (*TakenAction, error) {
 	var takenAt int64
-	err := row.Scan(&t.TargetUser, &t.InRoomID, &t.ActionType, &t.PolicyList, &t.RuleEntity, &t.Action, &takenAt)
+	err := row.Scan(&t.TargetUser, &t.InRoomID, &t.ActionType, &t.PolicyList, &t.RuleEntity, &t.Action, dbutil.JSON{Data: &t.Categories}, &t.ReasonCode, &takenAt)
 	if err != nil {
 		return nil, err
 	}
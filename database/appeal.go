@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getAppealBaseQuery = `
+		SELECT notice_event_id, management_room, user_id, reason, status, created_at FROM appeal
+	`
+	getAppealByNoticeEventQuery = getAppealBaseQuery + `WHERE management_room=$1 AND notice_event_id=$2`
+	getPendingAppealByUserQuery = getAppealBaseQuery + `WHERE user_id=$1 AND status='pending'`
+	insertAppealQuery           = `
+		INSERT INTO appeal (notice_event_id, management_room, user_id, reason, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	updateAppealStatusQuery = `
+		UPDATE appeal SET status=$3 WHERE management_room=$1 AND notice_event_id=$2
+	`
+)
+
+type AppealQuery struct {
+	*dbutil.QueryHelper[*Appeal]
+}
+
+func (aq *AppealQuery) Put(ctx context.Context, appeal *Appeal) error {
+	return aq.Exec(ctx, insertAppealQuery, appeal.sqlVariables()...)
+}
+
+func (aq *AppealQuery) GetByNoticeEvent(ctx context.Context, managementRoom id.RoomID, eventID id.EventID) (*Appeal, error) {
+	return aq.QueryOne(ctx, getAppealByNoticeEventQuery, managementRoom, eventID)
+}
+
+func (aq *AppealQuery) GetPendingByUser(ctx context.Context, userID id.UserID) (*Appeal, error) {
+	return aq.QueryOne(ctx, getPendingAppealByUserQuery, userID)
+}
+
+func (aq *AppealQuery) SetStatus(ctx context.Context, appeal *Appeal, status AppealStatus) error {
+	appeal.Status = status
+	return aq.Exec(ctx, updateAppealStatusQuery, appeal.ManagementRoom, appeal.NoticeEventID, status)
+}
+
+type AppealStatus string
+
+const (
+	AppealStatusPending AppealStatus = "pending"
+	AppealStatusGranted AppealStatus = "granted"
+	AppealStatusDenied  AppealStatus = "denied"
+)
+
+type Appeal struct {
+	NoticeEventID  id.EventID
+	ManagementRoom id.RoomID
+	UserID         id.UserID
+	Reason         string
+	Status         AppealStatus
+	CreatedAt      time.Time
+}
+
+func (a *Appeal) sqlVariables() []any {
+	return []any{a.NoticeEventID, a.ManagementRoom, a.UserID, a.Reason, a.Status, a.CreatedAt.UnixMilli()}
+}
+
+func (a *Appeal) Scan(row dbutil.Scannable) (*Appeal, error) {
+	var createdAt int64
+	err := row.Scan(&a.NoticeEventID, &a.ManagementRoom, &a.UserID, &a.Reason, &a.Status, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	a.CreatedAt = time.UnixMilli(createdAt)
+	return a, nil
+}
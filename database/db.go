@@ -8,9 +8,19 @@ import (
 
 type Database struct {
 	*dbutil.Database
-	TakenAction    *TakenActionQuery
-	Bot            *BotQuery
-	ManagementRoom *ManagementRoomQuery
+	TakenAction     *TakenActionQuery
+	Bot             *BotQuery
+	ManagementRoom  *ManagementRoomQuery
+	ListStats       *ListStatsQuery
+	Appeal          *AppealQuery
+	MemberCache     *MemberCacheQuery
+	PolicyHistory   *PolicyHistoryQuery
+	RoomBlacklist   *RoomBlacklistQuery
+	ScheduledAction *ScheduledActionQuery
+	HardenedRoom    *HardenedRoomQuery
+	FailedBan       *FailedBanQuery
+	PowerGrant      *PowerGrantQuery
+	HeldPolicy      *HeldPolicyQuery
 }
 
 func New(db *dbutil.Database) *Database {
@@ -30,5 +40,51 @@ func New(db *dbutil.Database) *Database {
 		ManagementRoom: &ManagementRoomQuery{
 			Database: db,
 		},
+		ListStats: &ListStatsQuery{
+			QueryHelper: dbutil.MakeQueryHelper(db, func(qh *dbutil.QueryHelper[*ListStats]) *ListStats {
+				return &ListStats{}
+			}),
+		},
+		Appeal: &AppealQuery{
+			QueryHelper: dbutil.MakeQueryHelper(db, func(qh *dbutil.QueryHelper[*Appeal]) *Appeal {
+				return &Appeal{}
+			}),
+		},
+		MemberCache: &MemberCacheQuery{
+			Database: db,
+		},
+		PolicyHistory: &PolicyHistoryQuery{
+			QueryHelper: dbutil.MakeQueryHelper(db, func(qh *dbutil.QueryHelper[*PolicyHistory]) *PolicyHistory {
+				return &PolicyHistory{}
+			}),
+		},
+		RoomBlacklist: &RoomBlacklistQuery{
+			Database: db,
+		},
+		ScheduledAction: &ScheduledActionQuery{
+			QueryHelper: dbutil.MakeQueryHelper(db, func(qh *dbutil.QueryHelper[*ScheduledAction]) *ScheduledAction {
+				return &ScheduledAction{}
+			}),
+		},
+		HardenedRoom: &HardenedRoomQuery{
+			QueryHelper: dbutil.MakeQueryHelper(db, func(qh *dbutil.QueryHelper[*HardenedRoom]) *HardenedRoom {
+				return &HardenedRoom{}
+			}),
+		},
+		FailedBan: &FailedBanQuery{
+			QueryHelper: dbutil.MakeQueryHelper(db, func(qh *dbutil.QueryHelper[*FailedBan]) *FailedBan {
+				return &FailedBan{}
+			}),
+		},
+		PowerGrant: &PowerGrantQuery{
+			QueryHelper: dbutil.MakeQueryHelper(db, func(qh *dbutil.QueryHelper[*PowerGrant]) *PowerGrant {
+				return &PowerGrant{}
+			}),
+		},
+		HeldPolicy: &HeldPolicyQuery{
+			QueryHelper: dbutil.MakeQueryHelper(db, func(qh *dbutil.QueryHelper[*HeldPolicy]) *HeldPolicy {
+				return &HeldPolicy{}
+			}),
+		},
 	}
 }
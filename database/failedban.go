@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getFailedBansByManagementRoomQuery = `
+		SELECT management_room, user_id, room_id, policy_list, rule_entity, error, failed_at
+		FROM failed_ban
+		WHERE management_room=$1
+		ORDER BY failed_at
+	`
+	upsertFailedBanQuery = `
+		INSERT INTO failed_ban (management_room, user_id, room_id, policy_list, rule_entity, error, failed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (management_room, user_id, room_id) DO UPDATE
+			SET policy_list=excluded.policy_list,
+				rule_entity=excluded.rule_entity,
+				error=excluded.error,
+				failed_at=excluded.failed_at
+	`
+	deleteFailedBanQuery = `
+		DELETE FROM failed_ban WHERE management_room=$1 AND user_id=$2 AND room_id=$3
+	`
+)
+
+type FailedBanQuery struct {
+	*dbutil.QueryHelper[*FailedBan]
+}
+
+func (fbq *FailedBanQuery) Put(ctx context.Context, fb *FailedBan) error {
+	return fbq.Exec(ctx, upsertFailedBanQuery, fb.sqlVariables()...)
+}
+
+func (fbq *FailedBanQuery) GetAll(ctx context.Context, managementRoom id.RoomID) ([]*FailedBan, error) {
+	return fbq.QueryMany(ctx, getFailedBansByManagementRoomQuery, managementRoom)
+}
+
+func (fbq *FailedBanQuery) Delete(ctx context.Context, managementRoom id.RoomID, userID id.UserID, roomID id.RoomID) error {
+	return fbq.Exec(ctx, deleteFailedBanQuery, managementRoom, userID, roomID)
+}
+
+// FailedBan is a ban that couldn't be applied to a protected room, e.g. because the bot lacked power.
+type FailedBan struct {
+	ManagementRoom id.RoomID
+	UserID         id.UserID
+	RoomID         id.RoomID
+	PolicyList     id.RoomID
+	RuleEntity     string
+	Error          string
+	FailedAt       time.Time
+}
+
+func (fb *FailedBan) sqlVariables() []any {
+	return []any{fb.ManagementRoom, fb.UserID, fb.RoomID, fb.PolicyList, fb.RuleEntity, fb.Error, fb.FailedAt.UnixMilli()}
+}
+
+func (fb *FailedBan) Scan(row dbutil.Scannable) (*FailedBan, error) {
+	var failedAt int64
+	err := row.Scan(&fb.ManagementRoom, &fb.UserID, &fb.RoomID, &fb.PolicyList, &fb.RuleEntity, &fb.Error, &failedAt)
+	if err != nil {
+		return nil, err
+	}
+	fb.FailedAt = time.UnixMilli(failedAt)
+	return fb, nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getHardenedRoomsByManagementRoomQuery = `
+		SELECT management_room, room_id, preset, prev_join_rules, created_by, expires_at, created_at
+		FROM hardened_room
+		WHERE management_room=$1
+		ORDER BY expires_at
+	`
+	getHardenedRoomQuery = `
+		SELECT management_room, room_id, preset, prev_join_rules, created_by, expires_at, created_at
+		FROM hardened_room
+		WHERE management_room=$1 AND room_id=$2
+	`
+	insertHardenedRoomQuery = `
+		INSERT INTO hardened_room (management_room, room_id, preset, prev_join_rules, created_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	deleteHardenedRoomQuery = `
+		DELETE FROM hardened_room WHERE management_room=$1 AND room_id=$2
+	`
+)
+
+type HardenedRoomQuery struct {
+	*dbutil.QueryHelper[*HardenedRoom]
+}
+
+func (hrq *HardenedRoomQuery) Put(ctx context.Context, room *HardenedRoom) error {
+	return hrq.Exec(ctx, insertHardenedRoomQuery, room.sqlVariables()...)
+}
+
+func (hrq *HardenedRoomQuery) Get(ctx context.Context, managementRoom, roomID id.RoomID) (*HardenedRoom, error) {
+	return hrq.QueryOne(ctx, getHardenedRoomQuery, managementRoom, roomID)
+}
+
+func (hrq *HardenedRoomQuery) GetAll(ctx context.Context, managementRoom id.RoomID) ([]*HardenedRoom, error) {
+	return hrq.QueryMany(ctx, getHardenedRoomsByManagementRoomQuery, managementRoom)
+}
+
+func (hrq *HardenedRoomQuery) Delete(ctx context.Context, managementRoom, roomID id.RoomID) (bool, error) {
+	res, err := hrq.GetDB().Exec(ctx, deleteHardenedRoomQuery, managementRoom, roomID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// HardenedRoom is a protected room whose join rules were temporarily tightened,
+// along with the previous join rules that will be restored when it expires.
+type HardenedRoom struct {
+	ManagementRoom id.RoomID
+	RoomID         id.RoomID
+	Preset         string
+	PrevJoinRules  map[string]any
+	CreatedBy      id.UserID
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+}
+
+func (hr *HardenedRoom) sqlVariables() []any {
+	return []any{
+		hr.ManagementRoom, hr.RoomID, hr.Preset, dbutil.JSON{Data: hr.PrevJoinRules}, hr.CreatedBy,
+		hr.ExpiresAt.UnixMilli(), hr.CreatedAt.UnixMilli(),
+	}
+}
+
+func (hr *HardenedRoom) Scan(row dbutil.Scannable) (*HardenedRoom, error) {
+	var expiresAt, createdAt int64
+	err := row.Scan(
+		&hr.ManagementRoom, &hr.RoomID, &hr.Preset, dbutil.JSON{Data: &hr.PrevJoinRules}, &hr.CreatedBy,
+		&expiresAt, &createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	hr.ExpiresAt = time.UnixMilli(expiresAt)
+	hr.CreatedAt = time.UnixMilli(createdAt)
+	return hr, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getHeldPoliciesByManagementRoomQuery = `
+		SELECT management_room, policy_list, policy_type, state_key, reason, held_at
+		FROM held_policy
+		WHERE management_room=$1
+		ORDER BY held_at
+	`
+	upsertHeldPolicyQuery = `
+		INSERT INTO held_policy (management_room, policy_list, policy_type, state_key, reason, held_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (management_room, policy_list, policy_type, state_key) DO UPDATE
+			SET reason=excluded.reason, held_at=excluded.held_at
+	`
+	deleteHeldPolicyQuery = `
+		DELETE FROM held_policy WHERE management_room=$1 AND policy_list=$2 AND policy_type=$3 AND state_key=$4
+	`
+)
+
+type HeldPolicyQuery struct {
+	*dbutil.QueryHelper[*HeldPolicy]
+}
+
+func (hpq *HeldPolicyQuery) Put(ctx context.Context, hp *HeldPolicy) error {
+	return hpq.Exec(ctx, upsertHeldPolicyQuery, hp.sqlVariables()...)
+}
+
+func (hpq *HeldPolicyQuery) GetAll(ctx context.Context, managementRoom id.RoomID) ([]*HeldPolicy, error) {
+	return hpq.QueryMany(ctx, getHeldPoliciesByManagementRoomQuery, managementRoom)
+}
+
+func (hpq *HeldPolicyQuery) Delete(ctx context.Context, managementRoom, policyList id.RoomID, policyType, stateKey string) error {
+	return hpq.Exec(ctx, deleteHeldPolicyQuery, managementRoom, policyList, policyType, stateKey)
+}
+
+type HoldReason string
+
+const (
+	HoldReasonTooBroad HoldReason = "too_broad"
+	HoldReasonBurst    HoldReason = "burst"
+)
+
+// HeldPolicy is a policy that a management room isn't applying until an admin approves it.
+type HeldPolicy struct {
+	ManagementRoom id.RoomID
+	PolicyList     id.RoomID
+	PolicyType     string
+	StateKey       string
+	Reason         HoldReason
+	HeldAt         time.Time
+}
+
+func (hp *HeldPolicy) sqlVariables() []any {
+	return []any{hp.ManagementRoom, hp.PolicyList, hp.PolicyType, hp.StateKey, hp.Reason, hp.HeldAt.UnixMilli()}
+}
+
+func (hp *HeldPolicy) Scan(row dbutil.Scannable) (*HeldPolicy, error) {
+	var heldAt int64
+	err := row.Scan(&hp.ManagementRoom, &hp.PolicyList, &hp.PolicyType, &hp.StateKey, &hp.Reason, &heldAt)
+	if err != nil {
+		return nil, err
+	}
+	hp.HeldAt = time.UnixMilli(heldAt)
+	return hp, nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getAllListStatsQuery = `
+		SELECT policy_list, hit_count, last_hit_at FROM policy_list_stats WHERE management_room=$1
+	`
+	incrementListHitCountQuery = `
+		INSERT INTO policy_list_stats (management_room, policy_list, hit_count, last_hit_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (management_room, policy_list) DO UPDATE
+			SET hit_count=policy_list_stats.hit_count+1, last_hit_at=excluded.last_hit_at
+	`
+)
+
+type ListStatsQuery struct {
+	*dbutil.QueryHelper[*ListStats]
+}
+
+func (lsq *ListStatsQuery) IncrementHitCount(ctx context.Context, managementRoom, policyList id.RoomID) error {
+	return lsq.Exec(ctx, incrementListHitCountQuery, managementRoom, policyList, time.Now().UnixMilli())
+}
+
+func (lsq *ListStatsQuery) GetAll(ctx context.Context, managementRoom id.RoomID) ([]*ListStats, error) {
+	return lsq.QueryMany(ctx, getAllListStatsQuery, managementRoom)
+}
+
+type ListStats struct {
+	PolicyList id.RoomID
+	HitCount   int64
+	LastHitAt  time.Time
+}
+
+func (ls *ListStats) Scan(row dbutil.Scannable) (*ListStats, error) {
+	var lastHitAt int64
+	err := row.Scan(&ls.PolicyList, &ls.HitCount, &lastHitAt)
+	if err != nil {
+		return nil, err
+	}
+	ls.LastHitAt = time.UnixMilli(lastHitAt)
+	return ls, nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getCachedMembersQuery = `
+		SELECT user_id, membership FROM member_cache WHERE management_room=$1 AND room_id=$2
+	`
+	putCachedMemberQuery = `
+		INSERT INTO member_cache (management_room, room_id, user_id, membership)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (management_room, room_id, user_id) DO UPDATE
+			SET membership=excluded.membership
+	`
+	deleteCachedRoomQuery = `
+		DELETE FROM member_cache WHERE management_room=$1 AND room_id=$2
+	`
+)
+
+type MemberCacheQuery struct {
+	*dbutil.Database
+}
+
+type CachedMember struct {
+	UserID     id.UserID
+	Membership event.Membership
+}
+
+var cachedMemberScanner = dbutil.ConvertRowFn[CachedMember](func(row dbutil.Scannable) (member CachedMember, err error) {
+	err = row.Scan(&member.UserID, &member.Membership)
+	return
+})
+
+func (mcq *MemberCacheQuery) GetRoom(ctx context.Context, managementRoom, roomID id.RoomID) ([]CachedMember, error) {
+	return cachedMemberScanner.NewRowIter(mcq.Query(ctx, getCachedMembersQuery, managementRoom, roomID)).AsList()
+}
+
+func (mcq *MemberCacheQuery) Put(ctx context.Context, managementRoom, roomID id.RoomID, userID id.UserID, membership event.Membership) error {
+	_, err := mcq.Exec(ctx, putCachedMemberQuery, managementRoom, roomID, userID, membership)
+	return err
+}
+
+// ReplaceRoom replaces all cached members of the given room.
+func (mcq *MemberCacheQuery) ReplaceRoom(ctx context.Context, managementRoom, roomID id.RoomID, members []CachedMember) error {
+	return mcq.DoTxn(ctx, nil, func(ctx context.Context) error {
+		_, err := mcq.Exec(ctx, deleteCachedRoomQuery, managementRoom, roomID)
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			_, err = mcq.Exec(ctx, putCachedMemberQuery, managementRoom, roomID, member.UserID, member.Membership)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (mcq *MemberCacheQuery) DeleteRoom(ctx context.Context, managementRoom, roomID id.RoomID) error {
+	_, err := mcq.Exec(ctx, deleteCachedRoomQuery, managementRoom, roomID)
+	return err
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getPolicyHistoryByEntityQuery = `
+		SELECT event_id, policy_list, entity_type, entity, recommendation, reason, sender, change, changed_at
+		FROM policy_history
+		WHERE entity=$1
+		ORDER BY changed_at
+	`
+	insertPolicyHistoryQuery = `
+		INSERT INTO policy_history (event_id, policy_list, entity_type, entity, recommendation, reason, sender, change, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_id, change) DO NOTHING
+	`
+)
+
+type PolicyHistoryQuery struct {
+	*dbutil.QueryHelper[*PolicyHistory]
+}
+
+func (phq *PolicyHistoryQuery) Put(ctx context.Context, ph *PolicyHistory) error {
+	return phq.Exec(ctx, insertPolicyHistoryQuery, ph.sqlVariables()...)
+}
+
+func (phq *PolicyHistoryQuery) GetAllByEntity(ctx context.Context, entity string) ([]*PolicyHistory, error) {
+	return phq.QueryMany(ctx, getPolicyHistoryByEntityQuery, entity)
+}
+
+type PolicyChange string
+
+const (
+	PolicyChangeAdded   PolicyChange = "added"
+	PolicyChangeRemoved PolicyChange = "removed"
+)
+
+type PolicyHistory struct {
+	EventID        id.EventID
+	PolicyList     id.RoomID
+	EntityType     string
+	Entity         string
+	Recommendation event.PolicyRecommendation
+	Reason         string
+	Sender         id.UserID
+	Change         PolicyChange
+	ChangedAt      time.Time
+}
+
+func (ph *PolicyHistory) sqlVariables() []any {
+	return []any{
+		ph.EventID, ph.PolicyList, ph.EntityType, ph.Entity, ph.Recommendation, ph.Reason, ph.Sender, ph.Change,
+		ph.ChangedAt.UnixMilli(),
+	}
+}
+
+func (ph *PolicyHistory) Scan(row dbutil.Scannable) (*PolicyHistory, error) {
+	var changedAt int64
+	err := row.Scan(
+		&ph.EventID, &ph.PolicyList, &ph.EntityType, &ph.Entity, &ph.Recommendation, &ph.Reason, &ph.Sender, &ph.Change,
+		&changedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	ph.ChangedAt = time.UnixMilli(changedAt)
+	return ph, nil
+}
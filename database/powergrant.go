@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getPowerGrantsByManagementRoomQuery = `
+		SELECT management_room, user_id, room_id, prev_level, granted_level, created_by, expires_at
+		FROM power_grant
+		WHERE management_room=$1
+		ORDER BY expires_at
+	`
+	upsertPowerGrantQuery = `
+		INSERT INTO power_grant (management_room, user_id, room_id, prev_level, granted_level, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (management_room, user_id, room_id) DO UPDATE
+			SET granted_level=excluded.granted_level,
+				created_by=excluded.created_by,
+				expires_at=excluded.expires_at
+	`
+	deletePowerGrantQuery = `
+		DELETE FROM power_grant WHERE management_room=$1 AND user_id=$2 AND room_id=$3
+	`
+)
+
+type PowerGrantQuery struct {
+	*dbutil.QueryHelper[*PowerGrant]
+}
+
+// Put saves a power grant. If the user already has a pending grant in the room,
+// the original previous level is kept so that reverting restores the level from before any grants.
+func (pgq *PowerGrantQuery) Put(ctx context.Context, grant *PowerGrant) error {
+	return pgq.Exec(ctx, upsertPowerGrantQuery, grant.sqlVariables()...)
+}
+
+func (pgq *PowerGrantQuery) GetAll(ctx context.Context, managementRoom id.RoomID) ([]*PowerGrant, error) {
+	return pgq.QueryMany(ctx, getPowerGrantsByManagementRoomQuery, managementRoom)
+}
+
+func (pgq *PowerGrantQuery) Delete(ctx context.Context, managementRoom id.RoomID, userID id.UserID, roomID id.RoomID) (bool, error) {
+	res, err := pgq.GetDB().Exec(ctx, deletePowerGrantQuery, managementRoom, userID, roomID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// PowerGrant is a temporary power level given to a user in a protected room, which is reverted when it expires.
+type PowerGrant struct {
+	ManagementRoom id.RoomID
+	UserID         id.UserID
+	RoomID         id.RoomID
+	PrevLevel      int
+	GrantedLevel   int
+	CreatedBy      id.UserID
+	ExpiresAt      time.Time
+}
+
+func (pg *PowerGrant) sqlVariables() []any {
+	return []any{
+		pg.ManagementRoom, pg.UserID, pg.RoomID, pg.PrevLevel, pg.GrantedLevel, pg.CreatedBy, pg.ExpiresAt.UnixMilli(),
+	}
+}
+
+func (pg *PowerGrant) Scan(row dbutil.Scannable) (*PowerGrant, error) {
+	var expiresAt int64
+	err := row.Scan(
+		&pg.ManagementRoom, &pg.UserID, &pg.RoomID, &pg.PrevLevel, &pg.GrantedLevel, &pg.CreatedBy, &expiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	pg.ExpiresAt = time.UnixMilli(expiresAt)
+	return pg, nil
+}
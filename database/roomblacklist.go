@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getRoomBlacklistReasonQuery = `
+		SELECT reason FROM room_blacklist WHERE bot_username=$1 AND room_id=$2
+	`
+	putRoomBlacklistQuery = `
+		INSERT INTO room_blacklist (bot_username, room_id, reason, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (bot_username, room_id) DO UPDATE SET reason=excluded.reason
+	`
+	deleteRoomBlacklistQuery = `
+		DELETE FROM room_blacklist WHERE bot_username=$1 AND room_id=$2
+	`
+)
+
+type RoomBlacklistQuery struct {
+	*dbutil.Database
+}
+
+func (rbq *RoomBlacklistQuery) Put(ctx context.Context, botUsername string, roomID id.RoomID, reason string) error {
+	_, err := rbq.Exec(ctx, putRoomBlacklistQuery, botUsername, roomID, reason, time.Now().UnixMilli())
+	return err
+}
+
+func (rbq *RoomBlacklistQuery) Delete(ctx context.Context, botUsername string, roomID id.RoomID) (bool, error) {
+	res, err := rbq.Exec(ctx, deleteRoomBlacklistQuery, botUsername, roomID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// IsBlacklisted checks whether the given room is blacklisted for the given bot.
+func (rbq *RoomBlacklistQuery) IsBlacklisted(ctx context.Context, botUsername string, roomID id.RoomID) (bool, error) {
+	var reason string
+	err := rbq.QueryRow(ctx, getRoomBlacklistReasonQuery, botUsername, roomID).Scan(&reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
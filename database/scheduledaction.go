@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	getScheduledActionsByManagementRoomQuery = `
+		SELECT id, management_room, policy_list, entity_type, entity, reason, extra, created_by, scheduled_at, created_at
+		FROM scheduled_action
+		WHERE management_room=$1
+		ORDER BY scheduled_at
+	`
+	insertScheduledActionQuery = `
+		INSERT INTO scheduled_action (
+			id, management_room, policy_list, entity_type, entity, reason, extra, created_by, scheduled_at, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	deleteScheduledActionQuery = `
+		DELETE FROM scheduled_action WHERE management_room=$1 AND id=$2
+	`
+)
+
+type ScheduledActionQuery struct {
+	*dbutil.QueryHelper[*ScheduledAction]
+}
+
+func (saq *ScheduledActionQuery) Put(ctx context.Context, action *ScheduledAction) error {
+	return saq.Exec(ctx, insertScheduledActionQuery, action.sqlVariables()...)
+}
+
+func (saq *ScheduledActionQuery) GetAll(ctx context.Context, managementRoom id.RoomID) ([]*ScheduledAction, error) {
+	return saq.QueryMany(ctx, getScheduledActionsByManagementRoomQuery, managementRoom)
+}
+
+func (saq *ScheduledActionQuery) Delete(ctx context.Context, managementRoom id.RoomID, actionID string) (bool, error) {
+	res, err := saq.GetDB().Exec(ctx, deleteScheduledActionQuery, managementRoom, actionID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// ScheduledAction is a policy that will be sent to a policy list at a specific time.
+type ScheduledAction struct {
+	ID             string
+	ManagementRoom id.RoomID
+	PolicyList     id.RoomID
+	EntityType     string
+	Entity         string
+	Reason         string
+	Extra          map[string]any
+	CreatedBy      id.UserID
+	ScheduledAt    time.Time
+	CreatedAt      time.Time
+}
+
+func (sa *ScheduledAction) sqlVariables() []any {
+	var extra dbutil.JSON
+	if len(sa.Extra) > 0 {
+		extra.Data = sa.Extra
+	}
+	return []any{
+		sa.ID, sa.ManagementRoom, sa.PolicyList, sa.EntityType, sa.Entity, sa.Reason, extra, sa.CreatedBy,
+		sa.ScheduledAt.UnixMilli(), sa.CreatedAt.UnixMilli(),
+	}
+}
+
+func (sa *ScheduledAction) Scan(row dbutil.Scannable) (*ScheduledAction, error) {
+	var scheduledAt, createdAt int64
+	err := row.Scan(
+		&sa.ID, &sa.ManagementRoom, &sa.PolicyList, &sa.EntityType, &sa.Entity, &sa.Reason,
+		dbutil.JSON{Data: &sa.Extra}, &sa.CreatedBy, &scheduledAt, &createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	sa.ScheduledAt = time.UnixMilli(scheduledAt)
+	sa.CreatedAt = time.UnixMilli(createdAt)
+	return sa, nil
+}
@@ -1,4 +1,4 @@
--- v0 -> v1 (compatible with v1+): Latest schema
+-- v0 -> v13 (compatible with v1+): Latest schema
 CREATE TABLE bot (
     username     TEXT PRIMARY KEY NOT NULL,
     displayname  TEXT NOT NULL,
@@ -20,6 +20,8 @@ CREATE TABLE taken_action (
     policy_list TEXT   NOT NULL,
     rule_entity TEXT   NOT NULL,
     action      TEXT   NOT NULL,
+    categories  TEXT,
+    reason_code TEXT   NOT NULL DEFAULT '',
     taken_at    BIGINT NOT NULL,
 
     PRIMARY KEY (target_user, in_room_id, action_type)
@@ -27,3 +29,123 @@ CREATE TABLE taken_action (
 
 CREATE INDEX taken_action_list_idx ON taken_action (policy_list);
 CREATE INDEX taken_action_entity_idx ON taken_action (policy_list, rule_entity);
+
+CREATE TABLE policy_list_stats (
+    management_room TEXT   NOT NULL,
+    policy_list     TEXT   NOT NULL,
+    hit_count       BIGINT NOT NULL,
+    last_hit_at     BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, policy_list)
+);
+
+CREATE TABLE appeal (
+    notice_event_id TEXT   NOT NULL,
+    management_room TEXT   NOT NULL,
+    user_id         TEXT   NOT NULL,
+    reason          TEXT   NOT NULL,
+    status          TEXT   NOT NULL,
+    created_at      BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, notice_event_id)
+);
+
+CREATE INDEX appeal_user_idx ON appeal (user_id);
+
+CREATE TABLE member_cache (
+    management_room TEXT NOT NULL,
+    room_id         TEXT NOT NULL,
+    user_id         TEXT NOT NULL,
+    membership      TEXT NOT NULL,
+
+    PRIMARY KEY (management_room, room_id, user_id)
+);
+
+CREATE TABLE policy_history (
+    event_id       TEXT   NOT NULL,
+    policy_list    TEXT   NOT NULL,
+    entity_type    TEXT   NOT NULL,
+    entity         TEXT   NOT NULL,
+    recommendation TEXT   NOT NULL,
+    reason         TEXT   NOT NULL,
+    sender         TEXT   NOT NULL,
+    change         TEXT   NOT NULL,
+    changed_at     BIGINT NOT NULL,
+
+    PRIMARY KEY (event_id, change)
+);
+
+CREATE INDEX policy_history_entity_idx ON policy_history (entity);
+
+CREATE TABLE room_blacklist (
+    bot_username TEXT   NOT NULL,
+    room_id      TEXT   NOT NULL,
+    reason       TEXT   NOT NULL,
+    created_at   BIGINT NOT NULL,
+
+    PRIMARY KEY (bot_username, room_id),
+    CONSTRAINT room_blacklist_bot_fkey FOREIGN KEY (bot_username) REFERENCES bot (username)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE scheduled_action (
+    id              TEXT   NOT NULL,
+    management_room TEXT   NOT NULL,
+    policy_list     TEXT   NOT NULL,
+    entity_type     TEXT   NOT NULL,
+    entity          TEXT   NOT NULL,
+    reason          TEXT   NOT NULL,
+    extra           TEXT,
+    created_by      TEXT   NOT NULL,
+    scheduled_at    BIGINT NOT NULL,
+    created_at      BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, id)
+);
+
+CREATE TABLE hardened_room (
+    management_room TEXT   NOT NULL,
+    room_id         TEXT   NOT NULL,
+    preset          TEXT   NOT NULL,
+    prev_join_rules TEXT   NOT NULL,
+    created_by      TEXT   NOT NULL,
+    expires_at      BIGINT NOT NULL,
+    created_at      BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, room_id)
+);
+
+CREATE TABLE failed_ban (
+    management_room TEXT   NOT NULL,
+    user_id         TEXT   NOT NULL,
+    room_id         TEXT   NOT NULL,
+    policy_list     TEXT   NOT NULL,
+    rule_entity     TEXT   NOT NULL,
+    error           TEXT   NOT NULL,
+    failed_at       BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, user_id, room_id)
+);
+
+CREATE TABLE power_grant (
+    management_room TEXT    NOT NULL,
+    user_id         TEXT    NOT NULL,
+    room_id         TEXT    NOT NULL,
+    prev_level      INTEGER NOT NULL,
+    granted_level   INTEGER NOT NULL,
+    created_by      TEXT    NOT NULL,
+    expires_at      BIGINT  NOT NULL,
+
+    PRIMARY KEY (management_room, user_id, room_id)
+);
+
+CREATE TABLE held_policy (
+    management_room TEXT   NOT NULL,
+    policy_list     TEXT   NOT NULL,
+    policy_type     TEXT   NOT NULL,
+    state_key       TEXT   NOT NULL,
+    reason          TEXT   NOT NULL,
+    held_at         BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, policy_list, policy_type, state_key)
+);
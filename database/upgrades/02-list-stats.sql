@@ -0,0 +1,9 @@
+-- v1 -> v2: Add table for policy list statistics
+CREATE TABLE policy_list_stats (
+    management_room TEXT   NOT NULL,
+    policy_list     TEXT   NOT NULL,
+    hit_count       BIGINT NOT NULL,
+    last_hit_at     BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, policy_list)
+);
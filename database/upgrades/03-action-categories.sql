@@ -0,0 +1,2 @@
+-- v2 -> v3: Store policy list categories in taken actions
+ALTER TABLE taken_action ADD COLUMN categories TEXT;
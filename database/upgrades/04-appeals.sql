@@ -0,0 +1,13 @@
+-- v3 -> v4: Add table for ban appeals
+CREATE TABLE appeal (
+    notice_event_id TEXT   NOT NULL,
+    management_room TEXT   NOT NULL,
+    user_id         TEXT   NOT NULL,
+    reason          TEXT   NOT NULL,
+    status          TEXT   NOT NULL,
+    created_at      BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, notice_event_id)
+);
+
+CREATE INDEX appeal_user_idx ON appeal (user_id);
@@ -0,0 +1,9 @@
+-- v4 -> v5: Add table for caching protected room members
+CREATE TABLE member_cache (
+    management_room TEXT NOT NULL,
+    room_id         TEXT NOT NULL,
+    user_id         TEXT NOT NULL,
+    membership      TEXT NOT NULL,
+
+    PRIMARY KEY (management_room, room_id, user_id)
+);
@@ -0,0 +1,17 @@
+-- v5 -> v6: Add table for policy change history
+-- One event can both remove and add a policy, so the change type is a part of the key.
+CREATE TABLE policy_history (
+    event_id       TEXT   NOT NULL,
+    policy_list    TEXT   NOT NULL,
+    entity_type    TEXT   NOT NULL,
+    entity         TEXT   NOT NULL,
+    recommendation TEXT   NOT NULL,
+    reason         TEXT   NOT NULL,
+    sender         TEXT   NOT NULL,
+    change         TEXT   NOT NULL,
+    changed_at     BIGINT NOT NULL,
+
+    PRIMARY KEY (event_id, change)
+);
+
+CREATE INDEX policy_history_entity_idx ON policy_history (entity);
@@ -0,0 +1,11 @@
+-- v6 -> v7: Add table for rooms the bot refuses to join
+CREATE TABLE room_blacklist (
+    bot_username TEXT   NOT NULL,
+    room_id      TEXT   NOT NULL,
+    reason       TEXT   NOT NULL,
+    created_at   BIGINT NOT NULL,
+
+    PRIMARY KEY (bot_username, room_id),
+    CONSTRAINT room_blacklist_bot_fkey FOREIGN KEY (bot_username) REFERENCES bot (username)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- v7 -> v8: Store policy reason codes in taken actions
+ALTER TABLE taken_action ADD COLUMN reason_code TEXT NOT NULL DEFAULT '';
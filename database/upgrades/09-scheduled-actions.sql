@@ -0,0 +1,15 @@
+-- v8 -> v9: Add table for scheduled policies
+CREATE TABLE scheduled_action (
+    id              TEXT   NOT NULL,
+    management_room TEXT   NOT NULL,
+    policy_list     TEXT   NOT NULL,
+    entity_type     TEXT   NOT NULL,
+    entity          TEXT   NOT NULL,
+    reason          TEXT   NOT NULL,
+    extra           TEXT,
+    created_by      TEXT   NOT NULL,
+    scheduled_at    BIGINT NOT NULL,
+    created_at      BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, id)
+);
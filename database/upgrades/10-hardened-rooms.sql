@@ -0,0 +1,12 @@
+-- v9 -> v10: Add table for temporarily hardened rooms
+CREATE TABLE hardened_room (
+    management_room TEXT   NOT NULL,
+    room_id         TEXT   NOT NULL,
+    preset          TEXT   NOT NULL,
+    prev_join_rules TEXT   NOT NULL,
+    created_by      TEXT   NOT NULL,
+    expires_at      BIGINT NOT NULL,
+    created_at      BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, room_id)
+);
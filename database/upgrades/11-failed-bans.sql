@@ -0,0 +1,12 @@
+-- v10 -> v11: Add table for bans that failed to apply
+CREATE TABLE failed_ban (
+    management_room TEXT   NOT NULL,
+    user_id         TEXT   NOT NULL,
+    room_id         TEXT   NOT NULL,
+    policy_list     TEXT   NOT NULL,
+    rule_entity     TEXT   NOT NULL,
+    error           TEXT   NOT NULL,
+    failed_at       BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, user_id, room_id)
+);
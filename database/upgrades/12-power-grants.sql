@@ -0,0 +1,12 @@
+-- v11 -> v12: Add table for temporary power level grants
+CREATE TABLE power_grant (
+    management_room TEXT    NOT NULL,
+    user_id         TEXT    NOT NULL,
+    room_id         TEXT    NOT NULL,
+    prev_level      INTEGER NOT NULL,
+    granted_level   INTEGER NOT NULL,
+    created_by      TEXT    NOT NULL,
+    expires_at      BIGINT  NOT NULL,
+
+    PRIMARY KEY (management_room, user_id, room_id)
+);
@@ -0,0 +1,11 @@
+-- v12 -> v13: Add table for policies held for admin approval
+CREATE TABLE held_policy (
+    management_room TEXT   NOT NULL,
+    policy_list     TEXT   NOT NULL,
+    policy_type     TEXT   NOT NULL,
+    state_key       TEXT   NOT NULL,
+    reason          TEXT   NOT NULL,
+    held_at         BIGINT NOT NULL,
+
+    PRIMARY KEY (management_room, policy_list, policy_type, state_key)
+);
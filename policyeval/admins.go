@@ -0,0 +1,61 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+)
+
+func (pe *PolicyEvaluator) getManagementRoomPowerLevels(ctx context.Context) (*event.PowerLevelsEventContent, error) {
+	var powerLevels event.PowerLevelsEventContent
+	err := pe.Bot.StateEvent(ctx, pe.ManagementRoom, event.StatePowerLevels, "", &powerLevels)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get management room power levels")
+		return nil, err
+	}
+	return &powerLevels, nil
+}
+
+func (pe *PolicyEvaluator) sendAdminList(ctx context.Context) {
+	powerLevels, err := pe.getManagementRoomPowerLevels(ctx)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get management room power levels: %v", err)
+		return
+	}
+	adminLevel := powerLevels.GetEventLevel(config.StateWatchedLists)
+	admins := pe.Admins.AsList()
+	slices.Sort(admins)
+	lines := make([]string, len(admins))
+	for i, userID := range admins {
+		lines[i] = fmt.Sprintf("* [%s](%s) (power level %d)", userID, userID.URI().MatrixToURL(), powerLevels.GetUserLevel(userID))
+	}
+	pe.sendNotice(ctx,
+		"%s in this management room:\n\n%s\n\n"+
+			"Users need a power level higher than %d (the level required to send `%s` events) to be admins. "+
+			"The admin list can be changed by editing the power levels of the management room.",
+		pluralize(len(admins), "admin"), strings.Join(lines, "\n"), adminLevel, config.StateWatchedLists.Type)
+}
+
+func (pe *PolicyEvaluator) checkAdmin(ctx context.Context, userID id.UserID) {
+	powerLevels, err := pe.getManagementRoomPowerLevels(ctx)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get management room power levels: %v", err)
+		return
+	}
+	adminLevel := powerLevels.GetEventLevel(config.StateWatchedLists)
+	userLevel := powerLevels.GetUserLevel(userID)
+	if pe.Admins.Has(userID) {
+		pe.sendNotice(ctx, "[%s](%s) is an admin (power level %d, must be higher than %d)",
+			userID, userID.URI().MatrixToURL(), userLevel, adminLevel)
+	} else {
+		pe.sendNotice(ctx, "[%s](%s) is not an admin (power level %d, must be higher than %d)",
+			userID, userID.URI().MatrixToURL(), userLevel, adminLevel)
+	}
+}
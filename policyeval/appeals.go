@@ -0,0 +1,207 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/bot"
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+const (
+	appealGrantReaction = "/grant"
+	appealDenyReaction  = "/deny"
+)
+
+// maxAppealReasonLength is the maximum number of characters in an appeal reason.
+const maxAppealReasonLength = 2000
+
+// getBansOf returns all policy, content policy and protection bans of the given user in this management room's
+// protected rooms. Taken actions aren't scoped to management rooms, so bans in other rooms are filtered out.
+func (pe *PolicyEvaluator) getBansOf(ctx context.Context, userID id.UserID) ([]*database.TakenAction, error) {
+	var bans []*database.TakenAction
+	for _, actionType := range []database.TakenActionType{
+		database.TakenActionTypeBanOrUnban, database.TakenActionTypeContentBan, database.TakenActionTypeProtectionBan,
+	} {
+		typeBans, err := pe.DB.TakenAction.GetAllByTargetUser(ctx, userID, actionType)
+		if err != nil {
+			return nil, err
+		}
+		for _, ban := range typeBans {
+			if pe.IsProtectedRoom(ban.InRoomID) {
+				bans = append(bans, ban)
+			}
+		}
+	}
+	return bans, nil
+}
+
+// quoteUserText wraps untrusted text in a code block, so that it isn't rendered as markdown.
+// The fence is made longer than any backtick run in the text so the text can't close it.
+func quoteUserText(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("%s\n%s\n%s", fence, text, fence)
+}
+
+// HandleAppeal posts a ban appeal from the given user to the management room.
+func (pe *PolicyEvaluator) HandleAppeal(ctx context.Context, userID id.UserID, reason string) error {
+	existing, err := pe.DB.Appeal.GetPendingByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check for existing appeals: %w", err)
+	} else if existing != nil {
+		return mautrix.MForbidden.WithMessage("You already have a pending appeal")
+	} else if utf8.RuneCountInString(reason) > maxAppealReasonLength {
+		return mautrix.MTooLarge.WithMessage(fmt.Sprintf("Appeal reason can't be longer than %d characters", maxAppealReasonLength))
+	}
+	actions, err := pe.getBansOf(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get bans: %w", err)
+	} else if len(actions) == 0 {
+		return mautrix.MForbidden.WithMessage("You haven't been banned by this bot")
+	}
+	rooms := make([]string, len(actions))
+	for i, action := range actions {
+		rooms[i] = fmt.Sprintf("* [%s](%s) due to %s",
+			action.InRoomID, action.InRoomID.URI().MatrixToURL(), pe.describeActionCause(action))
+	}
+	eventID := pe.Bot.SendNoticeOpts(ctx, pe.ManagementRoom, fmt.Sprintf(
+		"[%s](%s) appealed their ban:\n\n%s\n\nBanned in:\n\n%s\n\nReact with `%s` or `%s` to grant or deny the appeal.",
+		userID, userID.URI().MatrixToURL(), quoteUserText(reason), strings.Join(rooms, "\n"), appealGrantReaction, appealDenyReaction,
+	), &bot.SendNoticeOpts{Mentions: &event.Mentions{}})
+	if eventID == "" {
+		return fmt.Errorf("failed to send appeal to management room")
+	}
+	err = pe.DB.Appeal.Put(ctx, &database.Appeal{
+		NoticeEventID:  eventID,
+		ManagementRoom: pe.ManagementRoom,
+		UserID:         userID,
+		Reason:         reason,
+		Status:         database.AppealStatusPending,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save appeal: %w", err)
+	}
+	for _, key := range []string{appealGrantReaction, appealDenyReaction} {
+		_, err = pe.Bot.SendReaction(ctx, pe.ManagementRoom, eventID, key)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Str("key", key).Msg("Failed to send reaction to appeal")
+		}
+	}
+	return nil
+}
+
+func (pe *PolicyEvaluator) handleAppealReaction(ctx context.Context, evt *event.Event, targetID id.EventID, grant bool) {
+	appeal, err := pe.DB.Appeal.GetByNoticeEvent(ctx, pe.ManagementRoom, targetID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get appeal")
+		return
+	} else if appeal == nil || appeal.Status != database.AppealStatusPending {
+		return
+	}
+	log := zerolog.Ctx(ctx).With().
+		Stringer("appeal_user_id", appeal.UserID).
+		Stringer("handled_by", evt.Sender).
+		Bool("grant", grant).
+		Logger()
+	if !grant {
+		err = pe.DB.Appeal.SetStatus(ctx, appeal, database.AppealStatusDenied)
+		if err != nil {
+			log.Err(err).Msg("Failed to mark appeal as denied")
+			pe.sendNotice(ctx, "Failed to mark appeal as denied: %v", err)
+			return
+		}
+		log.Info().Msg("Appeal denied")
+		pe.sendNotice(ctx, "[%s](%s) denied the appeal of [%s](%s)",
+			evt.Sender, evt.Sender.URI().MatrixToURL(), appeal.UserID, appeal.UserID.URI().MatrixToURL())
+		return
+	}
+	allActions, err := pe.getBansOf(ctx, appeal.UserID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get bans to undo")
+		pe.sendNotice(ctx, "Failed to get bans of [%s](%s): %v", appeal.UserID, appeal.UserID.URI().MatrixToURL(), err)
+		return
+	}
+	var actions []*database.TakenAction
+	for _, action := range allActions {
+		if action.Action == event.PolicyRecommendationBan {
+			actions = append(actions, action)
+		}
+	}
+	var errorMessages []string
+	var policyLists []id.RoomID
+	for _, action := range actions {
+		if slices.Contains(policyLists, action.PolicyList) {
+			continue
+		} else if meta := pe.GetWatchedListMeta(action.PolicyList); meta == nil {
+			continue
+		} else if !pe.checkListWriteAccess(ctx, meta, evt.Sender) {
+			// The ban policy would stay in the list and ban the user again, so don't grant the appeal partially.
+			return
+		}
+		policyLists = append(policyLists, action.PolicyList)
+	}
+	for _, list := range policyLists {
+		_, err = pe.SendPolicy(ctx, list, policylist.EntityTypeUser, "", &event.ModPolicyContent{
+			Entity:         string(appeal.UserID),
+			Reason:         "appeal granted",
+			Recommendation: event.PolicyRecommendationUnban,
+		}, nil)
+		if err != nil {
+			log.Err(err).Stringer("policy_list", list).Msg("Failed to send unban policy")
+			errorMessages = append(errorMessages, fmt.Sprintf("* Failed to add unban policy to [%s](%s): %v", list, list.URI().MatrixToURL(), err))
+		}
+	}
+	var unbanned int
+	for _, action := range actions {
+		var err error
+		if !pe.DryRun {
+			_, err = pe.Bot.UnbanUser(ctx, action.InRoomID, &mautrix.ReqUnbanUser{
+				UserID: appeal.UserID,
+				Reason: "appeal granted",
+			})
+		}
+		if err != nil {
+			log.Err(err).Stringer("room_id", action.InRoomID).Msg("Failed to unban user")
+			errorMessages = append(errorMessages, fmt.Sprintf("* Failed to unban in [%s](%s): %v", action.InRoomID, action.InRoomID.URI().MatrixToURL(), err))
+			continue
+		}
+		unbanned++
+		if !pe.DryRun {
+			err = pe.DB.TakenAction.Delete(ctx, action)
+			if err != nil {
+				log.Err(err).Stringer("room_id", action.InRoomID).Msg("Failed to delete handled ban action")
+			}
+		}
+	}
+	err = pe.DB.Appeal.SetStatus(ctx, appeal, database.AppealStatusGranted)
+	if err != nil {
+		log.Err(err).Msg("Failed to mark appeal as granted")
+		errorMessages = append(errorMessages, fmt.Sprintf("* Failed to mark appeal as granted: %v", err))
+	}
+	log.Info().Msg("Appeal granted")
+	data := newNoticeTemplateData(appeal.UserID, "").withSender(evt.Sender)
+	data.Reason = "appeal granted"
+	data.RoomCount = unbanned
+	output := pe.formatNotice(ctx, noticeTemplateUnban, data,
+		"[%s](%s) granted the appeal of [%s](%s), unbanned from %s",
+		evt.Sender, evt.Sender.URI().MatrixToURL(), appeal.UserID, appeal.UserID.URI().MatrixToURL(),
+		pluralize(unbanned, "room"))
+	if len(errorMessages) > 0 {
+		output += "\n\n" + strings.Join(errorMessages, "\n")
+	}
+	pe.sendNotice(ctx, output)
+}
@@ -0,0 +1,186 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// looksLikeEntity checks whether a ban command argument is an entity rather than a list shortcode.
+func looksLikeEntity(entityType policylist.EntityType, arg string) bool {
+	if entityType == policylist.EntityTypeServer {
+		return strings.ContainsAny(arg, ".:*?")
+	}
+	return strings.HasPrefix(arg, "@")
+}
+
+// sendBanPolicy sends a ban policy to the given list and returns true if it was sent.
+// Previewed server bans are only sent after confirmation, so they always return false.
+func (pe *PolicyEvaluator) sendBanPolicy(
+	ctx context.Context,
+	evt *event.Event,
+	list *config.WatchedPolicyList,
+	entityType policylist.EntityType,
+	target, reason string,
+	extra map[string]any,
+	preview bool,
+) bool {
+	existingStateKey, unban := pe.findExistingBan(list, entityType, target)
+	if unban != nil {
+		pe.sendNotice(ctx, "`%s` has an unban recommendation: %s", target, unban.Reason)
+		return false
+	}
+	policy := &event.ModPolicyContent{
+		Entity:         target,
+		Reason:         reason,
+		Recommendation: event.PolicyRecommendationBan,
+	}
+	sendPolicy := func(ctx context.Context) bool {
+		resp, err := pe.sendPolicyUndoable(ctx, list.RoomID, entityType, existingStateKey, policy, extra)
+		if err != nil {
+			pe.sendNotice(ctx, `Failed to send ban policy: %v`, err)
+			return false
+		}
+		zerolog.Ctx(ctx).Info().
+			Stringer("policy_list", list.RoomID).
+			Any("policy", policy).
+			Stringer("policy_event_id", resp.EventID).
+			Msg("Sent ban policy from command")
+		pe.sendSuccessReaction(ctx, evt.ID)
+		return true
+	}
+	if preview && entityType == policylist.EntityTypeServer {
+		pe.previewServerBan(ctx, list.Name, target, func(ctx context.Context) {
+			sendPolicy(ctx)
+		})
+		return false
+	}
+	return sendPolicy(ctx)
+}
+
+// findExistingBan returns the state key of an existing ban policy for the target in the given list,
+// so that it can be replaced instead of adding a duplicate. If the target has an unban recommendation,
+// it's returned instead.
+func (pe *PolicyEvaluator) findExistingBan(list *config.WatchedPolicyList, entityType policylist.EntityType, target string) (string, *policylist.Policy) {
+	var match policylist.Match
+	if entityType == policylist.EntityTypeServer {
+		match = pe.Store.MatchServer(pe.GetWatchedLists(), target)
+	} else {
+		match = pe.Store.MatchUser(pe.GetWatchedLists(), id.UserID(target))
+	}
+	if rec := match.Recommendations().BanOrUnban; rec != nil {
+		if rec.Recommendation == event.PolicyRecommendationUnban {
+			return "", rec
+		} else if rec.RoomID == list.RoomID {
+			return rec.StateKey, nil
+		}
+	}
+	return "", nil
+}
+
+// getWritableLists returns the watched lists where the bot has permission to send policies of the given type.
+func (pe *PolicyEvaluator) getWritableLists(ctx context.Context, entityType policylist.EntityType) []*config.WatchedPolicyList {
+	return pe.getListsWritableBy(ctx, pe.Bot.UserID, entityType)
+}
+
+// getListsWritableBy returns the watched lists where the given user has permission to send policies of the given type.
+func (pe *PolicyEvaluator) getListsWritableBy(ctx context.Context, userID id.UserID, entityType policylist.EntityType) []*config.WatchedPolicyList {
+	pe.watchedListsLock.RLock()
+	lists := make([]*config.WatchedPolicyList, 0, len(pe.watchedListsList))
+	for _, roomID := range pe.watchedListsList {
+		lists = append(lists, pe.watchedListsMap[roomID])
+	}
+	pe.watchedListsLock.RUnlock()
+	writable := lists[:0]
+	for _, list := range lists {
+		var powerLevels event.PowerLevelsEventContent
+		err := pe.Bot.StateEvent(ctx, list.RoomID, event.StatePowerLevels, "", &powerLevels)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Stringer("room_id", list.RoomID).Msg("Failed to get power levels of policy list")
+			continue
+		}
+		if powerLevels.GetUserLevel(userID) >= powerLevels.GetEventLevel(entityType.EventType()) {
+			writable = append(writable, list)
+		}
+	}
+	return writable
+}
+
+// pickWritableList asks admins to choose which writable list to use by reacting to a message.
+// Only lists that the given sender is allowed to write to are offered.
+func (pe *PolicyEvaluator) pickWritableList(
+	ctx context.Context,
+	sender id.UserID,
+	entityType policylist.EntityType,
+	fn func(ctx context.Context, list *config.WatchedPolicyList),
+) {
+	lists := slices.DeleteFunc(pe.getWritableLists(ctx, entityType), func(list *config.WatchedPolicyList) bool {
+		return !list.CanWrite(sender)
+	})
+	if len(lists) == 0 {
+		pe.sendNotice(ctx, "There are no watched lists where both the bot and [%s](%s) can send %s policies",
+			sender, sender.URI().MatrixToURL(), entityType)
+		return
+	} else if len(lists) > len(choiceReactions) {
+		lists = lists[:len(choiceReactions)]
+	}
+	lines := make([]string, len(lists))
+	for i, list := range lists {
+		lines[i] = fmt.Sprintf("* %s `%s` - %s", choiceReactions[i], list.Shortcode, list.Name)
+	}
+	message := fmt.Sprintf("No list shortcode specified. Which list should the policy be sent to?\n\n%s", strings.Join(lines, "\n"))
+	pe.requestChoice(ctx, message, choiceReactions[:len(lists)], func(ctx context.Context, choice int) {
+		fn(ctx, lists[choice])
+	})
+}
+
+// banCreatedRooms sends room ban policies for all rooms created by the given user.
+// Protected rooms and the management room are never banned.
+func (pe *PolicyEvaluator) banCreatedRooms(ctx context.Context, sender id.UserID, list *config.WatchedPolicyList, userID id.UserID, reason string) {
+	if !pe.checkListWriteAccess(ctx, list, sender) {
+		return
+	}
+	rooms, err := pe.SynapseDB.GetRoomsCreatedBy(ctx, userID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Msg("Failed to get rooms created by user")
+		pe.sendNotice(ctx, "Failed to get rooms created by [%s](%s): %v", userID, userID.URI().MatrixToURL(), err)
+		return
+	} else if len(rooms) == 0 {
+		pe.sendNotice(ctx, "[%s](%s) hasn't created any rooms", userID, userID.URI().MatrixToURL())
+		return
+	}
+	lines := make([]string, len(rooms))
+	var bannedCount int
+	for i, roomID := range rooms {
+		line := fmt.Sprintf("* [%s](%s)", roomID, roomID.URI().MatrixToURL())
+		if roomID == pe.ManagementRoom || pe.IsProtectedRoom(roomID) || pe.IsWatchingList(roomID) {
+			lines[i] = line + " (skipped: used by this bot)"
+			continue
+		} else if existing := pe.Store.MatchRoom(pe.GetWatchedLists(), roomID).Recommendations().BanOrUnban; existing != nil {
+			lines[i] = fmt.Sprintf("%s (skipped: already has a %s policy in %s)", line, existing.Recommendation, pe.getListName(existing.RoomID))
+			continue
+		}
+		_, err = pe.SendPolicy(ctx, list.RoomID, policylist.EntityTypeRoom, "", &event.ModPolicyContent{
+			Entity:         string(roomID),
+			Reason:         reason,
+			Recommendation: event.PolicyRecommendationBan,
+		}, nil)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to send room ban policy")
+			lines[i] = fmt.Sprintf("%s (failed: %v)", line, err)
+		} else {
+			lines[i] = line
+			bannedCount++
+		}
+	}
+	pe.sendNotice(ctx, "Found %s created by [%s](%s), sent ban policies for %d:\n\n%s",
+		pluralize(len(rooms), "room"), userID, userID.URI().MatrixToURL(), bannedCount, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,104 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+const roomBlacklistUsage = "Usage: `!rooms blacklist <room ID or alias> [--ban <list shortcode>] [reason]`"
+
+// IsRoomBlacklisted checks whether the bot has been told to stay out of the given room.
+func (pe *PolicyEvaluator) IsRoomBlacklisted(ctx context.Context, roomID id.RoomID) bool {
+	blacklisted, err := pe.DB.RoomBlacklist.IsBlacklisted(ctx, pe.Bot.Meta.Username, roomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to check if room is blacklisted")
+	}
+	return blacklisted
+}
+
+func (pe *PolicyEvaluator) blacklistRoom(ctx context.Context, evt *event.Event, args []string) {
+	var list *config.WatchedPolicyList
+	args, listShortcode, hasList := extractValueFlag(args, "--ban")
+	if hasList {
+		if listShortcode == "" {
+			pe.sendNotice(ctx, roomBlacklistUsage)
+			return
+		}
+		list = pe.FindListByShortcode(listShortcode)
+		if list == nil {
+			pe.sendListNotFound(ctx, listShortcode)
+			return
+		} else if !pe.checkListWriteAccess(ctx, list, evt.Sender) {
+			return
+		}
+	}
+	if len(args) < 1 {
+		pe.sendNotice(ctx, roomBlacklistUsage)
+		return
+	}
+	roomID, err := pe.resolveRoom(ctx, args[0])
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", args[0], err)
+		return
+	} else if roomID == pe.ManagementRoom {
+		pe.sendNotice(ctx, "Refusing to blacklist the management room")
+		return
+	}
+	reason := strings.Join(args[1:], " ")
+	err = pe.DB.RoomBlacklist.Put(ctx, pe.Bot.Meta.Username, roomID, reason)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to blacklist [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err)
+		return
+	}
+	var output []string
+	if _, err = pe.RemoveProtectedRoom(ctx, roomID); err != nil {
+		output = append(output, fmt.Sprintf("* Failed to remove room from protected rooms: %v", err))
+	}
+	if _, err = pe.Bot.LeaveRoom(ctx, roomID, nil); err != nil {
+		output = append(output, fmt.Sprintf("* Failed to leave room: %v", err))
+	}
+	if list != nil {
+		policy := &event.ModPolicyContent{
+			Entity:         string(roomID),
+			Reason:         reason,
+			Recommendation: event.PolicyRecommendationBan,
+		}
+		if _, err = pe.sendPolicyUndoable(ctx, list.RoomID, policylist.EntityTypeRoom, "", policy, nil); err != nil {
+			output = append(output, fmt.Sprintf("* Failed to send room ban policy: %v", err))
+		}
+	}
+	zerolog.Ctx(ctx).Info().
+		Stringer("room_id", roomID).
+		Str("reason", reason).
+		Msg("Blacklisted room from command")
+	if len(output) > 0 {
+		pe.sendNotice(ctx, "Blacklisted [%s](%s), but some steps failed:\n\n%s",
+			roomID, roomID.URI().MatrixToURL(), strings.Join(output, "\n"))
+	} else {
+		pe.sendSuccessReaction(ctx, evt.ID)
+	}
+}
+
+func (pe *PolicyEvaluator) unblacklistRoom(ctx context.Context, evt *event.Event, roomIDOrAlias string) {
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	}
+	removed, err := pe.DB.RoomBlacklist.Delete(ctx, pe.Bot.Meta.Username, roomID)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to remove [%s](%s) from the blacklist: %v", roomID, roomID.URI().MatrixToURL(), err)
+	} else if !removed {
+		pe.sendNotice(ctx, "[%s](%s) is not blacklisted", roomID, roomID.URI().MatrixToURL())
+	} else {
+		pe.sendSuccessReaction(ctx, evt.ID)
+	}
+}
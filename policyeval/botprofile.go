@@ -0,0 +1,71 @@
+package policyeval
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const botCommandUsage = "Usage: `!bot profile <displayname|avatar> <value>`"
+
+func (pe *PolicyEvaluator) handleBotCommand(ctx context.Context, evt *event.Event, args []string) {
+	if len(args) < 3 || strings.ToLower(args[0]) != "profile" {
+		pe.sendNotice(ctx, botCommandUsage)
+		return
+	}
+	value := strings.Join(args[2:], " ")
+	switch strings.ToLower(args[1]) {
+	case "displayname":
+		pe.setBotDisplayname(ctx, evt, value)
+	case "avatar":
+		pe.setBotAvatar(ctx, evt, value)
+	default:
+		pe.sendNotice(ctx, botCommandUsage)
+	}
+}
+
+func (pe *PolicyEvaluator) setBotDisplayname(ctx context.Context, evt *event.Event, displayname string) {
+	oldDisplayname := pe.Bot.Meta.Displayname
+	err := pe.Bot.Intent.SetDisplayName(ctx, displayname)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to set displayname")
+		pe.sendNotice(ctx, "Failed to set displayname: %v", err)
+		return
+	}
+	pe.Bot.Meta.Displayname = displayname
+	err = pe.DB.Bot.Put(ctx, pe.Bot.Meta)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to save bot to database")
+		pe.sendNotice(ctx, "Changed displayname, but failed to save to database: %v", err)
+		return
+	}
+	pe.sendNotice(ctx, "Changed displayname from `%s` to `%s`", oldDisplayname, displayname)
+	pe.sendSuccessReaction(ctx, evt.ID)
+}
+
+func (pe *PolicyEvaluator) setBotAvatar(ctx context.Context, evt *event.Event, rawURI string) {
+	avatarURL, err := id.ParseContentURI(rawURI)
+	if err != nil {
+		pe.sendNotice(ctx, "Invalid avatar URL: %v", err)
+		return
+	}
+	oldAvatarURL := pe.Bot.Meta.AvatarURL
+	err = pe.Bot.Intent.SetAvatarURL(ctx, avatarURL)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to set avatar")
+		pe.sendNotice(ctx, "Failed to set avatar: %v", err)
+		return
+	}
+	pe.Bot.Meta.AvatarURL = avatarURL
+	err = pe.DB.Bot.Put(ctx, pe.Bot.Meta)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to save bot to database")
+		pe.sendNotice(ctx, "Changed avatar, but failed to save to database: %v", err)
+		return
+	}
+	pe.sendNotice(ctx, "Changed avatar from `%s` to `%s`", oldAvatarURL, avatarURL)
+	pe.sendSuccessReaction(ctx, evt.ID)
+}
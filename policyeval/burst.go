@@ -0,0 +1,148 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+
+	"go.mau.fi/meowlnir/bot"
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// maxBurstReminders is the number of times admins are reminded about an unapproved burst
+// after the first confirmation request expires.
+const maxBurstReminders = 3
+
+type policyBurst struct {
+	timestamps []time.Time
+	active     bool
+	held       []*policylist.Policy
+	reminders  int
+}
+
+// checkPolicyBurst records a new policy in the given list and checks whether the list is adding policies
+// faster than the configured threshold. If it is, the individual notice for the policy should be skipped.
+// If holding is enabled, the policy is also held until an admin approves the burst.
+func (pe *PolicyEvaluator) checkPolicyBurst(ctx context.Context, list *config.WatchedPolicyList, policy *policylist.Policy) (inBurst, held bool) {
+	cfg := pe.Config.PolicyBurst
+	if cfg.Threshold <= 0 {
+		return false, false
+	}
+	pe.policyBurstsLock.Lock()
+	defer pe.policyBurstsLock.Unlock()
+	burst, ok := pe.policyBursts[list.RoomID]
+	if !ok {
+		burst = &policyBurst{}
+		pe.policyBursts[list.RoomID] = burst
+	}
+	now := time.Now()
+	cutoff := now.Add(-cfg.Window)
+	burst.timestamps = slices.DeleteFunc(burst.timestamps, func(ts time.Time) bool {
+		return ts.Before(cutoff)
+	})
+	burst.timestamps = append(burst.timestamps, now)
+	if burst.active && cfg.Hold {
+		pe.holdPolicy(ctx, policy, database.HoldReasonBurst)
+		burst.held = append(burst.held, policy)
+		return true, true
+	} else if len(burst.timestamps) < cfg.Threshold {
+		burst.active = false
+		return false, false
+	} else if burst.active {
+		return true, false
+	}
+	burst.active = true
+	count := len(burst.timestamps)
+	zerolog.Ctx(ctx).Warn().
+		Stringer("policy_list", list.RoomID).
+		Int("policy_count", count).
+		Msg("Detected burst of new policies")
+	message := fmt.Sprintf("⚠️ @room %s added %d policies in the last %s.", list.Name, count, cfg.Window)
+	if !cfg.Hold {
+		go pe.Bot.SendNoticeOpts(context.WithoutCancel(ctx), pe.ManagementRoom,
+			message+" Notices about further new policies are suppressed until the burst ends.",
+			&bot.SendNoticeOpts{Mentions: &event.Mentions{Room: true}})
+		return true, false
+	}
+	pe.holdPolicy(ctx, policy, database.HoldReasonBurst)
+	burst.held = append(burst.held, policy)
+	message += " New policies from the list are being held until an admin approves them."
+	go pe.promptPolicyBurst(context.WithoutCancel(ctx), list, burst, message, &bot.SendNoticeOpts{Mentions: &event.Mentions{Room: true}})
+	return true, true
+}
+
+// promptPolicyBurst asks admins to approve the policies held due to a burst. If the confirmation request
+// expires while the burst is still waiting for approval, a reminder is sent without pinging the room,
+// up to maxBurstReminders times. After that, the burst can only be resolved with the `!held` command.
+func (pe *PolicyEvaluator) promptPolicyBurst(ctx context.Context, list *config.WatchedPolicyList, burst *policyBurst, message string, opts *bot.SendNoticeOpts) {
+	pe.requestConfirmationOpts(ctx, message, opts, func(ctx context.Context) {
+		pe.releasePolicyBurst(ctx, list)
+	})
+	time.AfterFunc(confirmationTimeout, func() {
+		pe.policyBurstsLock.Lock()
+		stillPending := pe.policyBursts[list.RoomID] == burst
+		heldCount := len(burst.held)
+		burst.reminders++
+		reminders := burst.reminders
+		pe.policyBurstsLock.Unlock()
+		if !stillPending {
+			return
+		} else if reminders > maxBurstReminders {
+			pe.sendNotice(ctx,
+				"%d held policies from %s are still waiting for approval. Use `!held approve %s` to apply them or `!held drop %s` to end the burst without applying them.",
+				heldCount, list.Name, list.Shortcode, list.Shortcode)
+			return
+		}
+		pe.promptPolicyBurst(ctx, list, burst, fmt.Sprintf(
+			"⚠️ %d held policies from %s are still waiting for approval, and new policies from the list are still being held.",
+			heldCount, list.Name,
+		), nil)
+	})
+}
+
+// dropPolicyBurst ends the burst in the given list and removes the holds on its policies without applying them.
+func (pe *PolicyEvaluator) dropPolicyBurst(ctx context.Context, list *config.WatchedPolicyList) {
+	pe.policyBurstsLock.Lock()
+	burst, ok := pe.policyBursts[list.RoomID]
+	delete(pe.policyBursts, list.RoomID)
+	pe.policyBurstsLock.Unlock()
+	if !ok || !burst.active {
+		pe.sendNotice(ctx, "%s doesn't have an active burst", list.Name)
+		return
+	}
+	var dropped int
+	for _, policy := range burst.held {
+		if pe.releaseHeldPolicy(ctx, policy) {
+			dropped++
+		}
+	}
+	pe.sendNotice(ctx, "Ended the burst in %s. %d held policies were not applied to current members, "+
+		"but they will still apply to users who join later.", list.Name, dropped)
+}
+
+// releasePolicyBurst applies all policies held due to a burst in the given list.
+func (pe *PolicyEvaluator) releasePolicyBurst(ctx context.Context, list *config.WatchedPolicyList) {
+	pe.policyBurstsLock.Lock()
+	burst, ok := pe.policyBursts[list.RoomID]
+	delete(pe.policyBursts, list.RoomID)
+	pe.policyBurstsLock.Unlock()
+	if !ok || !burst.active {
+		pe.sendNotice(ctx, "%s doesn't have an active burst", list.Name)
+		return
+	}
+	var released []*policylist.Policy
+	for _, policy := range burst.held {
+		// Policies that were removed or replaced in the meantime aren't held anymore
+		if pe.releaseHeldPolicy(ctx, policy) {
+			released = append(released, policy)
+		}
+	}
+	pe.sendNotice(ctx, "Approved held policies from %s (%d total), applying them now", list.Name, len(released))
+	pe.applyReleasedPolicies(ctx, released)
+}
@@ -0,0 +1,67 @@
+package policyeval
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// sendPoliciesBySender lists all policies sent by the given user in all watched lists or in the list with the given shortcode.
+func (pe *PolicyEvaluator) sendPoliciesBySender(ctx context.Context, sender id.UserID, shortcode string) {
+	var lists []id.RoomID
+	if shortcode != "" {
+		list := pe.FindListByShortcode(shortcode)
+		if list == nil {
+			pe.sendListNotFound(ctx, shortcode)
+			return
+		}
+		lists = []id.RoomID{list.RoomID}
+	} else {
+		lists = pe.GetWatchedLists()
+	}
+	var sections []string
+	total := 0
+	for _, listID := range lists {
+		room := pe.Store.GetRoom(listID)
+		if room == nil {
+			continue
+		}
+		var policies []*policylist.Policy
+		for _, policy := range room.Policies() {
+			if policy.Sender == sender {
+				policies = append(policies, policy)
+			}
+		}
+		if len(policies) == 0 {
+			continue
+		}
+		slices.SortFunc(policies, func(a, b *policylist.Policy) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+		lines := make([]string, len(policies))
+		for i, policy := range policies {
+			lines[i] = fmt.Sprintf("* `%s` %s `%s`: %s", policy.Recommendation, policy.EntityType, policy.Entity, policy.Reason)
+			if policy.Ignored {
+				lines[i] += " (ignored)"
+			}
+		}
+		total += len(policies)
+		listName := string(listID)
+		if meta := pe.GetWatchedListMeta(listID); meta != nil {
+			listName = meta.Name
+		}
+		sections = append(sections, fmt.Sprintf("**%s** (%d):\n\n%s", listName, len(policies), strings.Join(lines, "\n")))
+	}
+	if total == 0 {
+		pe.sendNotice(ctx, "[%s](%s) hasn't sent any policies in watched lists", sender, sender.URI().MatrixToURL())
+		return
+	}
+	pe.sendNotice(ctx, "Policies sent by [%s](%s) (%d total):\n\n%s",
+		sender, sender.URI().MatrixToURL(), total, strings.Join(sections, "\n\n"))
+}
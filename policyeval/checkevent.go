@@ -0,0 +1,102 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/policylist"
+)
+
+func (pe *PolicyEvaluator) fetchEvent(ctx context.Context, roomID id.RoomID, eventID id.EventID) (*event.Event, error) {
+	evt, err := pe.Bot.Client.GetEvent(ctx, roomID, eventID)
+	if err != nil && pe.SynapseDB != nil {
+		var synErr error
+		evt, synErr = pe.SynapseDB.GetEvent(ctx, eventID)
+		if synErr == nil {
+			return evt, nil
+		}
+		zerolog.Ctx(ctx).Debug().Err(synErr).Stringer("event_id", eventID).Msg("Failed to get event from Synapse database")
+	}
+	return evt, err
+}
+
+func (pe *PolicyEvaluator) parseEventLink(ctx context.Context, link string) (id.RoomID, id.EventID, error) {
+	uri, err := id.ParseMatrixURIOrMatrixToURL(link)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid event link: %w", err)
+	}
+	eventID := uri.EventID()
+	if eventID == "" {
+		return "", "", fmt.Errorf("link doesn't point at an event")
+	}
+	roomID := uri.RoomID()
+	if roomID == "" && uri.RoomAlias() != "" {
+		resp, err := pe.Bot.ResolveAlias(ctx, uri.RoomAlias())
+		if err != nil {
+			return "", "", fmt.Errorf("failed to resolve room alias: %w", err)
+		}
+		roomID = resp.RoomID
+	}
+	if roomID == "" {
+		return "", "", fmt.Errorf("link doesn't contain a room")
+	}
+	return roomID, eventID, nil
+}
+
+func (pe *PolicyEvaluator) formatMatch(match policylist.Match) string {
+	lines := make([]string, len(match))
+	for i, policy := range match {
+		lines[i] = fmt.Sprintf("  * `%s` for `%s` in [%s](%s) by [%s](%s): %s%s",
+			policy.Recommendation, policy.Entity, policy.RoomID, policy.RoomID.URI().MatrixToURL(),
+			policy.Sender, policy.Sender.URI().MatrixToURL(), policy.Reason, pe.listCategorySuffix(policy.RoomID))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (pe *PolicyEvaluator) checkEvent(ctx context.Context, link string) {
+	roomID, eventID, err := pe.parseEventLink(ctx, link)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to parse event link: %v", err)
+		return
+	}
+	evt, err := pe.fetchEvent(ctx, roomID, eventID)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get [event](%s): %v", roomID.EventURI(eventID).MatrixToURL(), err)
+		return
+	}
+	lists := pe.GetWatchedLists()
+	userMatch := pe.matchUser(lists, evt.Sender)
+	serverMatch := pe.matchServer(lists, evt.Sender.Homeserver())
+	var lines []string
+	verdict := "no action"
+	if recs := userMatch.Recommendations(); recs.BanOrUnban != nil {
+		if recs.IsBan() {
+			verdict = "sender should be banned"
+		} else {
+			verdict = "sender is explicitly allowed"
+		}
+	}
+	if len(userMatch) > 0 {
+		lines = append(lines, "* Sender matches:\n"+pe.formatMatch(userMatch))
+	} else {
+		lines = append(lines, "* Sender doesn't match any policies")
+	}
+	if len(serverMatch) > 0 {
+		lines = append(lines, fmt.Sprintf("* Sender's server `%s` matches:\n%s", evt.Sender.Homeserver(), pe.formatMatch(serverMatch)))
+		if verdict == "no action" {
+			verdict = "sender's server has policies"
+		}
+	} else {
+		lines = append(lines, fmt.Sprintf("* Sender's server `%s` doesn't match any policies", evt.Sender.Homeserver()))
+	}
+	if !pe.IsProtectedRoom(roomID) {
+		lines = append(lines, "* The room is not protected by this management room")
+	}
+	pe.sendNotice(ctx, "[Event](%s) from [%s](%s): **%s**\n\n%s",
+		roomID.EventURI(eventID).MatrixToURL(), evt.Sender, evt.Sender.URI().MatrixToURL(), verdict, strings.Join(lines, "\n"))
+}
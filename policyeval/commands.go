@@ -4,8 +4,11 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 
+	"go.mau.fi/meowlnir/config"
 	"go.mau.fi/meowlnir/policylist"
 )
 
@@ -22,11 +26,20 @@ func (pe *PolicyEvaluator) HandleCommand(ctx context.Context, evt *event.Event)
 		zerolog.Ctx(ctx).Warn().
 			Stringer("trust_state", evt.Mautrix.TrustState).
 			Msg("Dropping encrypted event with insufficient trust state")
+		pe.sendUntrustedCommandFeedback(ctx, evt)
 		return
 	}
 	fields := strings.Fields(evt.Content.AsMessage().Body)
 	cmd := strings.ToLower(fields[0])
 	args := fields[1:]
+	if allowed, notify := pe.checkCommandRateLimit(evt.Sender); !allowed {
+		zerolog.Ctx(ctx).Warn().Str("command", cmd).Msg("Dropping command from rate limited sender")
+		if notify {
+			pe.sendNotice(ctx, "[%s](%s) is sending commands too fast, ignoring commands for a while",
+				evt.Sender, evt.Sender.URI().MatrixToURL())
+		}
+		return
+	}
 	zerolog.Ctx(ctx).Info().Str("command", cmd).Msg("Handling command")
 	switch cmd {
 	case "!join":
@@ -39,12 +52,25 @@ func (pe *PolicyEvaluator) HandleCommand(ctx context.Context, evt *event.Event)
 			}
 		}
 		pe.sendSuccessReaction(ctx, evt.ID)
+	case "!ping":
+		pe.sendPong(ctx, evt)
+	case "!whoami":
+		pe.sendWhoami(ctx, evt)
+	case "!undo":
+		pe.undoLastAction(ctx, evt)
+	case "!version":
+		_, check := extractFlag(args, "--check")
+		pe.sendVersion(ctx, check)
+	case "!preview-config":
+		pe.previewConfig(ctx, evt, args)
 	case "!redact":
 		if len(args) < 1 {
 			pe.sendNotice(ctx, "Usage: `!redact <user ID> [reason]`")
 			return
 		}
-		pe.RedactUser(ctx, id.UserID(args[0]), strings.Join(args[1:], " "), false)
+		pe.redactUser(ctx, id.UserID(args[0]), strings.Join(args[1:], " "), false, func(ctx context.Context) {
+			pe.pushUndo(&undoableAction{description: fmt.Sprintf("redaction of messages from `%s`", args[0])})
+		})
 		pe.sendSuccessReaction(ctx, evt.ID)
 	case "!kick":
 		if len(args) < 1 {
@@ -70,79 +96,393 @@ func (pe *PolicyEvaluator) HandleCommand(ctx context.Context, evt *event.Event)
 				successCount++
 			}
 		}
+		if successCount > 0 {
+			pe.pushUndo(&undoableAction{description: fmt.Sprintf("kick of `%s` from %s", userID, pluralize(successCount, "room"))})
+		}
 		pe.sendSuccessReaction(ctx, evt.ID)
 	case "!ban", "!ban-user", "!ban-server":
-		if len(args) < 2 {
+		var silentReason, preview, withRooms, hasCode, hasAt bool
+		var reasonCode, atStr string
+		var at time.Time
+		args, silentReason = extractFlag(args, "--silent-reason")
+		args, preview = extractFlag(args, "--preview")
+		args, withRooms = extractFlag(args, "--with-rooms")
+		args, reasonCode, hasCode = extractValueFlag(args, "--code")
+		args, atStr, hasAt = extractValueFlag(args, "--at")
+		if withRooms && (cmd == "!ban-server" || hasAt) {
+			pe.sendNotice(ctx, "`--with-rooms` can only be used for immediate user bans")
+			return
+		} else if withRooms && pe.SynapseDB == nil {
+			pe.sendNotice(ctx, "`--with-rooms` requires Synapse database access")
+			return
+		} else if hasCode && !policylist.IsValidReasonCode(reasonCode) {
+			pe.sendNotice(ctx, "Invalid reason code %q: must be 1-64 characters of `a-z`, `0-9`, `_`, `.` or `-`", reasonCode)
+			return
+		} else if hasAt {
+			var err error
+			at, err = time.Parse(time.RFC3339, atStr)
+			if err != nil {
+				pe.sendNotice(ctx, "Invalid time %q: must be in RFC 3339 format, e.g. `2006-01-02T15:04:05Z`", atStr)
+				return
+			} else if !at.After(time.Now()) {
+				pe.sendNotice(ctx, "Scheduled time must be in the future")
+				return
+			} else if preview {
+				pe.sendNotice(ctx, "`--preview` can't be combined with `--at`")
+				return
+			}
+		}
+		entityType := policylist.EntityTypeUser
+		if cmd == "!ban-server" {
+			entityType = policylist.EntityTypeServer
+		}
+		sendUsage := func() {
 			if cmd == "!ban-server" {
-				pe.sendNotice(ctx, "Usage: `!ban-server <list shortcode> <server name> <reason>`")
+				pe.sendNotice(ctx, "Usage: `!ban-server [list shortcode] <server name or glob> [--preview] [--silent-reason] [--code <code>] [--at <time>] <reason>`")
 			} else {
-				pe.sendNotice(ctx, "Usage: `!ban <list shortcode> <user ID> <reason>`")
+				pe.sendNotice(ctx, "Usage: `!ban [list shortcode] <user ID> [--silent-reason] [--with-rooms] [--code <code>] [--at <time>] <reason>`")
 			}
+		}
+		if len(args) < 1 || (len(args) < 2 && !looksLikeEntity(entityType, args[0])) {
+			sendUsage()
+			return
+		}
+		extra := make(map[string]any)
+		if silentReason {
+			extra[policylist.SilentReasonKey] = true
+		}
+		if reasonCode != "" {
+			extra[policylist.ReasonCodeKey] = reasonCode
+		}
+		sendBan := func(ctx context.Context, list *config.WatchedPolicyList, target, reason string) {
+			if hasAt {
+				pe.scheduleBanPolicy(ctx, evt, list, entityType, target, reason, extra, at)
+			} else {
+				sent := pe.sendBanPolicy(ctx, evt, list, entityType, target, reason, extra, preview)
+				if sent && withRooms {
+					pe.banCreatedRooms(ctx, evt.Sender, list, id.UserID(target), reason)
+				}
+			}
+		}
+		list := pe.FindListByShortcode(args[0])
+		if list == nil && pe.isShortcodeAmbiguous(args[0]) {
+			pe.sendListNotFound(ctx, args[0])
+			return
+		} else if list == nil && looksLikeEntity(entityType, args[0]) {
+			pe.pickWritableList(ctx, evt.Sender, entityType, func(ctx context.Context, list *config.WatchedPolicyList) {
+				sendBan(ctx, list, args[0], strings.Join(args[1:], " "))
+			})
+			return
+		} else if list == nil {
+			pe.sendListNotFound(ctx, args[0])
+			return
+		} else if len(args) < 2 {
+			// The shortcode can also look like a server name, e.g. `!ban-server my.list`
+			sendUsage()
+			return
+		} else if !pe.checkListWriteAccess(ctx, list, evt.Sender) {
+			return
+		}
+		sendBan(ctx, list, args[1], strings.Join(args[2:], " "))
+	case "!dump-state":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!dump-state <room ID or alias> [event type]`")
+			return
+		}
+		var eventType string
+		if len(args) > 1 {
+			eventType = args[1]
+		}
+		pe.dumpState(ctx, args[0], eventType)
+	case "!failures":
+		pe.sendFailures(ctx, len(args) > 0 && strings.ToLower(args[0]) == "clear")
+	case "!grant-power":
+		pe.grantPower(ctx, evt, args)
+	case "!failed-bans":
+		pe.sendFailedBans(ctx)
+	case "!retry-failed":
+		pe.retryFailedBans(ctx)
+	case "!scheduled":
+		pe.handleScheduledCommand(ctx, evt, args)
+	case "!held":
+		pe.handleHeldCommand(ctx, args)
+	case "!takedown-server":
+		if len(args) < 2 {
+			pe.sendNotice(ctx, "Usage: `!takedown-server <list shortcode> <server name> <reason>`")
 			return
 		}
 		list := pe.FindListByShortcode(args[0])
 		if list == nil {
-			pe.sendNotice(ctx, `List %q not found`, args[0])
+			pe.sendListNotFound(ctx, args[0])
+			return
+		} else if !pe.checkListWriteAccess(ctx, list, evt.Sender) {
 			return
 		}
-		target := args[1]
-		var match policylist.Match
-		var entityType policylist.EntityType
-		if cmd == "!ban-server" {
-			entityType = policylist.EntityTypeServer
-			match = pe.Store.MatchServer(pe.GetWatchedLists(), target)
+		pe.takedownServer(ctx, evt, list, args[1], strings.Join(args[2:], " "))
+	case "!admins":
+		if len(args) > 0 && strings.ToLower(args[0]) == "check" {
+			if len(args) < 2 {
+				pe.sendNotice(ctx, "Usage: `!admins check <user ID>`")
+				return
+			}
+			pe.checkAdmin(ctx, id.UserID(args[1]))
 		} else {
-			entityType = policylist.EntityTypeUser
-			match = pe.Store.MatchUser(pe.GetWatchedLists(), id.UserID(target))
+			pe.sendAdminList(ctx)
 		}
-		var existingStateKey string
-		if rec := match.Recommendations().BanOrUnban; rec != nil {
-			if rec.Recommendation == event.PolicyRecommendationUnban {
-				pe.sendNotice(ctx, "`%s` has an unban recommendation: %s", target, rec.Reason)
+	case "!watch":
+		var dontApply bool
+		args, dontApply = extractFlag(args, "--no-apply")
+		// Server ACLs aren't managed by Meowlnir yet, so the flag is accepted but has no effect.
+		args, _ = extractFlag(args, "--no-acl")
+		if len(args) < 2 {
+			pe.sendNotice(ctx, "Usage: `!watch <room ID or alias> <shortcode> [--no-apply]`")
+			return
+		}
+		pe.watchList(ctx, args[0], args[1], dontApply)
+	case "!unwatch":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!unwatch <shortcode>`")
+			return
+		}
+		pe.unwatchList(ctx, args[0])
+	case "!lookup-hash":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!lookup-hash <base64 hash>`")
+			return
+		}
+		pe.lookupRoomHash(ctx, args[0])
+	case "!check-event":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!check-event <event link>`")
+			return
+		}
+		pe.checkEvent(ctx, args[0])
+	case "!explain":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!explain <user ID>`")
+			return
+		}
+		pe.explainUser(ctx, id.UserID(args[0]))
+	case "!reeval-user":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!reeval-user <user ID>`")
+			return
+		}
+		userID := id.UserID(args[0])
+		match := pe.Store.MatchUser(pe.GetWatchedLists(), userID)
+		if match == nil {
+			pe.sendNotice(ctx, "[%s](%s) doesn't match any policies", userID, userID.URI().MatrixToURL())
+			return
+		}
+		pe.sendNotice(ctx, "Re-evaluating [%s](%s), which matches:\n\n%s", userID, userID.URI().MatrixToURL(), pe.formatMatch(match))
+		pe.EvaluateUser(ctx, userID, true)
+		pe.sendSuccessReaction(ctx, evt.ID)
+	case "!history":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!history <entity>`")
+			return
+		}
+		pe.sendHistory(ctx, args[0])
+	case "!glob":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!glob <pattern>`")
+			return
+		}
+		pe.testGlob(ctx, args[0])
+	case "!by-sender":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!by-sender <user ID> [list shortcode]`")
+			return
+		}
+		var shortcode string
+		if len(args) > 1 {
+			shortcode = args[1]
+		}
+		pe.sendPoliciesBySender(ctx, id.UserID(args[0]), shortcode)
+	case "!search", "!search-reason":
+		var isRegex, asJSON bool
+		var shortcode string
+		args, isRegex = extractFlag(args, "--regex")
+		args, asJSON = extractFlag(args, "--json")
+		args, shortcode, _ = extractValueFlag(args, "--list")
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `%s [--regex] [--json] [--list <shortcode>] <text>`", cmd)
+			return
+		}
+		pe.sendPoliciesByReason(ctx, strings.Join(args, " "), isRegex, shortcode, asJSON)
+	case "!sample":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!sample <user ID> [count]`")
+			return
+		}
+		count := defaultSampleCount
+		if len(args) > 1 {
+			var err error
+			count, err = strconv.Atoi(args[1])
+			if err != nil || count <= 0 || count > maxSampleCount {
+				pe.sendNotice(ctx, "Count must be a number between 1 and %d", maxSampleCount)
 				return
-			} else if rec.RoomID == list.RoomID {
-				existingStateKey = rec.StateKey
 			}
 		}
-		policy := &event.ModPolicyContent{
-			Entity:         target,
-			Reason:         strings.Join(args[2:], " "),
-			Recommendation: event.PolicyRecommendationBan,
+		pe.sampleMessages(ctx, id.UserID(args[0]), count)
+	case "!pause":
+		var duration time.Duration
+		if len(args) > 0 {
+			var err error
+			duration, err = time.ParseDuration(args[0])
+			if err != nil || duration <= 0 {
+				pe.sendNotice(ctx, "Invalid duration %q. Usage: `!pause [duration]`, e.g. `!pause 30m`", args[0])
+				return
+			}
 		}
-		resp, err := pe.SendPolicy(ctx, list.RoomID, entityType, existingStateKey, policy)
-		if err != nil {
-			pe.sendNotice(ctx, `Failed to send ban policy: %v`, err)
+		pe.Pause(ctx, duration)
+		if duration > 0 {
+			pe.sendNotice(ctx, "Automated moderation paused for %s. Matches will be reported but not enforced. Use `!resume` to resume early.", duration)
+		} else {
+			pe.sendNotice(ctx, "Automated moderation paused. Matches will be reported but not enforced until `!resume` is used.")
+		}
+	case "!resume":
+		if pe.Resume() {
+			pe.sendNotice(ctx, "Automated moderation resumed")
+			pe.ReportPausedMatches(ctx)
+		} else {
+			pe.sendNotice(ctx, "Automated moderation wasn't paused")
+		}
+	case "!dedup-policies":
+		args, dryRun := extractFlag(args, "--dry")
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!dedup-policies <shortcode> [--dry]`")
 			return
 		}
-		zerolog.Ctx(ctx).Info().
-			Stringer("policy_list", list.RoomID).
-			Any("policy", policy).
-			Stringer("policy_event_id", resp.EventID).
-			Msg("Sent ban policy from command")
-		pe.sendSuccessReaction(ctx, evt.ID)
+		pe.dedupPolicies(ctx, evt.Sender, args[0], dryRun)
+	case "!bot":
+		pe.handleBotCommand(ctx, evt, args)
+	case "!stats":
+		_, asJSON := extractFlag(args, "--json")
+		pe.sendStats(ctx, asJSON)
+	case "!knock-requests":
+		pe.sendKnockRequests(ctx)
+	case "!fedping":
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!fedping <server name>`")
+			return
+		}
+		pe.fedPing(ctx, args[0])
+	case "!rooms":
+		pe.handleRoomsCommand(ctx, evt, args)
+	case "!lists":
+		if len(args) > 0 && strings.ToLower(args[0]) == "refresh" {
+			if len(args) < 2 {
+				pe.sendNotice(ctx, "Usage: `!lists refresh <shortcode>`")
+				return
+			}
+			pe.refreshList(ctx, args[1])
+		} else {
+			pe.sendWatchedListInfo(ctx, slices.Contains(args, "--stats"))
+		}
 	case "!match":
+		var asJSON bool
+		args, asJSON = extractFlag(args, "--json")
+		if len(args) < 1 {
+			pe.sendNotice(ctx, "Usage: `!match <user ID> [--json]`")
+			return
+		}
 		start := time.Now()
 		match := pe.Store.MatchUser(nil, id.UserID(args[0]))
 		dur := time.Since(start)
 		if match != nil {
 			eventStrings := make([]string, len(match))
 			for i, policy := range match {
-				eventStrings[i] = fmt.Sprintf("* [%s](%s) set recommendation `%s` for `%s` at %s for %s",
-					policy.Sender, policy.Sender.URI().MatrixToURL(), policy.Recommendation, policy.Entity, time.UnixMilli(policy.Timestamp), policy.Reason)
+				eventStrings[i] = fmt.Sprintf("* [%s](%s) set recommendation `%s` for `%s` at %s for %s%s",
+					policy.Sender, policy.Sender.URI().MatrixToURL(), policy.Recommendation, policy.Entity, time.UnixMilli(policy.Timestamp), policy.Reason,
+					pe.listCategorySuffix(policy.RoomID))
 			}
 			pe.sendNotice(ctx, "Matched in %s with recommendations %+v\n\n%s", dur, match.Recommendations(), strings.Join(eventStrings, "\n"))
 		} else {
 			pe.sendNotice(ctx, "No match in %s", dur.String())
 		}
+		if asJSON {
+			pe.sendJSON(ctx, &matchJSON{Duration: dur.Seconds(), Policies: policiesToJSON(match)})
+		}
+	}
+}
+
+const untrustedCommandReaction = "🔒"
+
+func (pe *PolicyEvaluator) sendUntrustedCommandFeedback(ctx context.Context, evt *event.Event) {
+	mode := pe.Config.UntrustedCommandFeedback
+	if mode == "" || mode == "none" || !pe.shouldSendUntrustedFeedback(evt.Sender) {
+		return
+	}
+	if mode == "reaction" {
+		_, err := pe.Bot.SendReaction(ctx, pe.ManagementRoom, evt.ID, untrustedCommandReaction)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Msg("Failed to send reaction to ignored command")
+		}
+		return
 	}
+	pe.sendNotice(ctx,
+		"Ignored command from [%s](%s) because it was sent from a device that isn't cross-signed (trust state: %s). "+
+			"Verify the device and try again.",
+		evt.Sender, evt.Sender.URI().MatrixToURL(), evt.Mautrix.TrustState)
 }
 
-func (pe *PolicyEvaluator) SendPolicy(ctx context.Context, policyList id.RoomID, entityType policylist.EntityType, stateKey string, content *event.ModPolicyContent) (*mautrix.RespSendEvent, error) {
+// extractFlag removes the given flag from the argument list and returns whether it was present.
+func extractFlag(args []string, flag string) ([]string, bool) {
+	idx := slices.Index(args, flag)
+	if idx < 0 {
+		return args, false
+	}
+	return slices.Delete(slices.Clone(args), idx, idx+1), true
+}
+
+// extractValueFlag removes the given flag and the value after it from the argument list.
+// The returned bool is true if the flag was present, even if it didn't have a value.
+func extractValueFlag(args []string, flag string) ([]string, string, bool) {
+	idx := slices.Index(args, flag)
+	if idx < 0 {
+		return args, "", false
+	} else if idx+1 >= len(args) {
+		return slices.Delete(slices.Clone(args), idx, idx+1), "", true
+	}
+	return slices.Delete(slices.Clone(args), idx, idx+2), args[idx+1], true
+}
+
+// ErrReasonRequired is returned by SendPolicy when require_policy_reason is enabled and a policy doesn't have a reason.
+var ErrReasonRequired = errors.New("a reason is required for policies, please specify one")
+
+func (pe *PolicyEvaluator) SendPolicy(ctx context.Context, policyList id.RoomID, entityType policylist.EntityType, stateKey string, content *event.ModPolicyContent, extra map[string]any) (*mautrix.RespSendEvent, error) {
+	// Policies without a recommendation are removals, which don't need a reason
+	if pe.Config.RequirePolicyReason && content.Recommendation != "" && strings.TrimSpace(content.Reason) == "" {
+		return nil, ErrReasonRequired
+	}
 	if stateKey == "" {
-		stateKeyHash := sha256.Sum256(append([]byte(content.Entity), []byte(content.Recommendation)...))
-		stateKey = base64.StdEncoding.EncodeToString(stateKeyHash[:])
+		stateKey = policyStateKey(content)
+	}
+	var wrappedContent any = content
+	if len(extra) > 0 {
+		wrappedContent = &event.Content{Parsed: content, Raw: extra}
 	}
-	return pe.Bot.SendStateEvent(ctx, policyList, entityType.EventType(), stateKey, content)
+	return pe.Bot.SendStateEvent(ctx, policyList, entityType.EventType(), stateKey, wrappedContent)
+}
+
+// sendPolicyUndoable is like SendPolicy, but also lets !undo revert the change.
+// It should only be used for policies sent directly by admin commands.
+func (pe *PolicyEvaluator) sendPolicyUndoable(ctx context.Context, policyList id.RoomID, entityType policylist.EntityType, stateKey string, content *event.ModPolicyContent, extra map[string]any) (*mautrix.RespSendEvent, error) {
+	if stateKey == "" {
+		stateKey = policyStateKey(content)
+	}
+	undo := pe.preparePolicyUndo(ctx, policyList, entityType, stateKey, content)
+	resp, err := pe.SendPolicy(ctx, policyList, entityType, stateKey, content, extra)
+	if err == nil && undo != nil {
+		pe.pushUndo(undo)
+	}
+	return resp, err
+}
+
+func policyStateKey(content *event.ModPolicyContent) string {
+	stateKeyHash := sha256.Sum256(append([]byte(content.Entity), []byte(content.Recommendation)...))
+	return base64.StdEncoding.EncodeToString(stateKeyHash[:])
 }
 
 func (pe *PolicyEvaluator) HandleReport(ctx context.Context, sender id.UserID, roomID id.RoomID, eventID id.EventID, reason string) error {
@@ -173,6 +513,7 @@ func (pe *PolicyEvaluator) HandleReport(ctx context.Context, sender id.UserID, r
 			evt.Sender, evt.Sender.URI().MatrixToURL(),
 			reason,
 		)
+		pe.aggregateReport(ctx, sender, evt)
 		return nil
 	}
 	fields := strings.Fields(reason)
@@ -183,7 +524,7 @@ func (pe *PolicyEvaluator) HandleReport(ctx context.Context, sender id.UserID, r
 		if len(args) < 2 {
 			return mautrix.MInvalidParam.WithMessage("Not enough arguments for ban")
 		}
-		match := pe.Store.MatchUser(pe.GetWatchedLists(), evt.Sender)
+		match := pe.matchUser(pe.GetWatchedLists(), evt.Sender)
 		if rec := match.Recommendations().BanOrUnban; rec != nil {
 			if rec.Recommendation == event.PolicyRecommendationUnban {
 				return mautrix.RespError{
@@ -201,20 +542,25 @@ func (pe *PolicyEvaluator) HandleReport(ctx context.Context, sender id.UserID, r
 		}
 		list := pe.FindListByShortcode(args[0])
 		if list == nil {
-			pe.sendNotice(ctx, `Failed to handle [%s](%s)'s report of [%s](%s): list %q not found`,
-				sender, sender.URI().MatrixToURL(), evt.Sender, evt.Sender.URI().MatrixToURL(), args[0])
+			pe.sendNotice(ctx, `Failed to handle [%s](%s)'s report of [%s](%s): %s`,
+				sender, sender.URI().MatrixToURL(), evt.Sender, evt.Sender.URI().MatrixToURL(), pe.describeMissingList(args[0]))
 			return mautrix.MNotFound.WithMessage(fmt.Sprintf("List with shortcode %q not found", args[0]))
+		} else if !pe.checkListWriteAccess(ctx, list, sender) {
+			return mautrix.MForbidden.WithMessage(fmt.Sprintf("Not allowed to write to the %s list", list.Shortcode))
 		}
 		policy := &event.ModPolicyContent{
 			Entity:         string(evt.Sender),
 			Reason:         strings.Join(args[1:], " "),
 			Recommendation: event.PolicyRecommendationBan,
 		}
-		resp, err := pe.SendPolicy(ctx, list.RoomID, policylist.EntityTypeUser, "", policy)
+		resp, err := pe.SendPolicy(ctx, list.RoomID, policylist.EntityTypeUser, "", policy, nil)
 		if err != nil {
 			pe.sendNotice(ctx, `Failed to handle [%s](%s)'s report of [%s](%s) for %s ([%s](%s)): %v`,
 				sender, sender.URI().MatrixToURL(), evt.Sender, evt.Sender.URI().MatrixToURL(),
 				list.Name, list.RoomID, list.RoomID.URI().MatrixToURL(), err)
+			if errors.Is(err, ErrReasonRequired) {
+				return mautrix.MInvalidParam.WithMessage("A reason is required for ban policies")
+			}
 			return fmt.Errorf("failed to send policy: %w", err)
 		}
 		zerolog.Ctx(ctx).Info().
@@ -0,0 +1,135 @@
+package policyeval
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/util/variationselector"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/bot"
+	"go.mau.fi/meowlnir/config"
+)
+
+const confirmationReaction = "✅"
+const rejectionReaction = "❌"
+const confirmationTimeout = 1 * time.Hour
+
+var choiceReactions = []string{"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣", "🔟"}
+
+type pendingConfirmation struct {
+	choices   []string
+	fn        func(ctx context.Context, choice int)
+	expiresAt time.Time
+	// canConfirm limits which admins can react to the request. If nil, any admin can.
+	canConfirm func(userID id.UserID) bool
+}
+
+// requestConfirmation sends the given message to the management room and calls fn
+// once an admin reacts to the message with a checkmark.
+func (pe *PolicyEvaluator) requestConfirmation(ctx context.Context, message string, fn func(ctx context.Context)) {
+	pe.requestConfirmationOpts(ctx, message, nil, fn)
+}
+
+func (pe *PolicyEvaluator) requestConfirmationOpts(ctx context.Context, message string, opts *bot.SendNoticeOpts, fn func(ctx context.Context)) {
+	message += "\n\nReact with " + confirmationReaction + " to confirm."
+	pe.requestChoiceOpts(ctx, message, opts, []string{confirmationReaction}, func(ctx context.Context, _ int) {
+		fn(ctx)
+	})
+}
+
+// requestListWriteConfirmation is like requestConfirmation, but only accepts confirmations
+// from admins who are allowed to write to the given list.
+func (pe *PolicyEvaluator) requestListWriteConfirmation(ctx context.Context, message string, list *config.WatchedPolicyList, fn func(ctx context.Context)) {
+	message += "\n\nReact with " + confirmationReaction + " to confirm."
+	pe.sendChoiceRequest(ctx, message, nil, &pendingConfirmation{
+		choices: []string{confirmationReaction},
+		fn: func(ctx context.Context, _ int) {
+			fn(ctx)
+		},
+		canConfirm: list.CanWrite,
+	})
+}
+
+// requestChoice sends the given message to the management room and calls fn with the index
+// of the chosen option once an admin reacts to the message with one of the given reactions.
+func (pe *PolicyEvaluator) requestChoice(ctx context.Context, message string, choices []string, fn func(ctx context.Context, choice int)) {
+	pe.requestChoiceOpts(ctx, message, nil, choices, fn)
+}
+
+func (pe *PolicyEvaluator) requestChoiceOpts(ctx context.Context, message string, opts *bot.SendNoticeOpts, choices []string, fn func(ctx context.Context, choice int)) {
+	pe.sendChoiceRequest(ctx, message, opts, &pendingConfirmation{choices: choices, fn: fn})
+}
+
+func (pe *PolicyEvaluator) sendChoiceRequest(ctx context.Context, message string, opts *bot.SendNoticeOpts, pending *pendingConfirmation) {
+	eventID := pe.Bot.SendNoticeOpts(ctx, pe.ManagementRoom, message, opts)
+	if eventID == "" {
+		return
+	}
+	pe.pendingConfirmationsLock.Lock()
+	now := time.Now()
+	for evtID, pending := range pe.pendingConfirmations {
+		if now.After(pending.expiresAt) {
+			delete(pe.pendingConfirmations, evtID)
+		}
+	}
+	pending.expiresAt = now.Add(confirmationTimeout)
+	pe.pendingConfirmations[eventID] = pending
+	pe.pendingConfirmationsLock.Unlock()
+	for _, choice := range pending.choices {
+		_, err := pe.Bot.SendReaction(ctx, pe.ManagementRoom, eventID, choice)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Msg("Failed to send reaction to confirmation request")
+		}
+	}
+}
+
+func (pe *PolicyEvaluator) HandleReaction(ctx context.Context, evt *event.Event) {
+	content, ok := evt.Content.Parsed.(*event.ReactionEventContent)
+	if !ok {
+		return
+	}
+	key := variationselector.Remove(content.RelatesTo.Key)
+	switch key {
+	case appealGrantReaction:
+		pe.handleAppealReaction(ctx, evt, content.RelatesTo.EventID, true)
+	case appealDenyReaction:
+		pe.handleAppealReaction(ctx, evt, content.RelatesTo.EventID, false)
+	default:
+		pe.handleConfirmationReaction(ctx, evt, content.RelatesTo.EventID, key)
+	}
+}
+
+func (pe *PolicyEvaluator) handleConfirmationReaction(ctx context.Context, evt *event.Event, targetID id.EventID, key string) {
+	pe.pendingConfirmationsLock.Lock()
+	pending, ok := pe.pendingConfirmations[targetID]
+	choice := -1
+	if ok {
+		choice = slices.IndexFunc(pending.choices, func(choice string) bool {
+			return variationselector.Remove(choice) == key
+		})
+	}
+	authorized := choice >= 0 && (pending.canConfirm == nil || pending.canConfirm(evt.Sender))
+	if authorized {
+		delete(pe.pendingConfirmations, targetID)
+	}
+	pe.pendingConfirmationsLock.Unlock()
+	if choice < 0 {
+		return
+	} else if !authorized {
+		pe.sendNotice(ctx, "[%s](%s) is not authorized to confirm that action", evt.Sender, evt.Sender.URI().MatrixToURL())
+		return
+	} else if time.Now().After(pending.expiresAt) {
+		pe.sendNotice(ctx, "That confirmation request has expired, please re-run the command.")
+		return
+	}
+	zerolog.Ctx(ctx).Info().
+		Stringer("confirmed_by", evt.Sender).
+		Stringer("confirmation_event_id", targetID).
+		Int("choice", choice).
+		Msg("Pending action confirmed")
+	go pending.fn(context.WithoutCancel(ctx), choice)
+}
@@ -0,0 +1,174 @@
+package policyeval
+
+import (
+	"context"
+	"regexp/syntax"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// minContentPatternLiterals is the minimum number of literal characters that a content policy pattern must
+// require for it to be enforced. Patterns like `.` or `\w+` would otherwise redact and ban everyone who sends a message.
+const minContentPatternLiterals = 4
+
+// countRequiredLiterals returns the minimum number of literal characters that any match of the given regex contains.
+func countRequiredLiterals(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpCapture, syntax.OpPlus:
+		return countRequiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min * countRequiredLiterals(re.Sub[0])
+	case syntax.OpConcat:
+		var total int
+		for _, sub := range re.Sub {
+			total += countRequiredLiterals(sub)
+		}
+		return total
+	case syntax.OpAlternate:
+		minimum := -1
+		for _, sub := range re.Sub {
+			if count := countRequiredLiterals(sub); minimum == -1 || count < minimum {
+				minimum = count
+			}
+		}
+		return max(minimum, 0)
+	default:
+		return 0
+	}
+}
+
+func isContentPatternTooBroad(pattern string) bool {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	return err != nil || countRequiredLiterals(parsed.Simplify()) < minContentPatternLiterals
+}
+
+// filterContentPolicies removes content policies that shouldn't be enforced, either because their list
+// isn't applied or because the pattern is too broad. Admins are warned once about each broad pattern.
+func (pe *PolicyEvaluator) filterContentPolicies(ctx context.Context, rules []*policylist.ContentPolicy) []*policylist.ContentPolicy {
+	return slices.DeleteFunc(rules, func(rule *policylist.ContentPolicy) bool {
+		if meta := pe.GetWatchedListMeta(rule.RoomID); meta == nil || meta.DontApply {
+			return true
+		} else if !isContentPatternTooBroad(rule.Pattern) {
+			return false
+		}
+		if pe.warnedContentPolicies.Add(rule.ID) {
+			zerolog.Ctx(ctx).Warn().
+				Stringer("policy_list", rule.RoomID).
+				Str("content_pattern", rule.Pattern).
+				Msg("Ignoring content policy with too broad pattern")
+			pe.sendNotice(ctx, "⚠️ Not enforcing content policy `%s` in %s, as the pattern must contain at least %d literal characters",
+				rule.Pattern, pe.getListName(rule.RoomID), minContentPatternLiterals)
+		}
+		return true
+	})
+}
+
+// checkContentPolicies applies content policies from watched lists to a message in a protected room.
+func (pe *PolicyEvaluator) checkContentPolicies(ctx context.Context, evt *event.Event, body string) {
+	if !slices.Contains(pe.Config.ContentEventTypes, evt.Type.Type) || pe.isExemptFromProtections(ctx, evt.RoomID, evt.Sender) {
+		return
+	}
+	rules := pe.filterContentPolicies(ctx, pe.Store.MatchContent(pe.GetWatchedLists(), body))
+	if len(rules) == 0 {
+		return
+	}
+	rule := rules[0]
+	for _, r := range rules {
+		if r.Recommendation == event.PolicyRecommendationBan {
+			rule = r
+			break
+		}
+	}
+	if rule.Recommendation != event.PolicyRecommendationBan && rule.Recommendation != policylist.ContentRecommendationRedact {
+		return
+	}
+	log := zerolog.Ctx(ctx).With().
+		Stringer("sender", evt.Sender).
+		Stringer("room_id", evt.RoomID).
+		Stringer("event_id", evt.ID).
+		Str("content_pattern", rule.Pattern).
+		Str("recommendation", string(rule.Recommendation)).
+		Logger()
+	if pe.IsPaused() {
+		log.Info().Msg("Not applying content policy as automated moderation is paused")
+		pe.sendNotice(ctx, "[%s](%s) matched content policy `%s` in [%s](%s), but automated moderation is paused",
+			evt.Sender, evt.Sender.URI().MatrixToURL(), rule.Pattern, evt.RoomID, evt.RoomID.EventURI(evt.ID).MatrixToURL())
+		return
+	}
+	log.Info().Msg("Applying content policy")
+	reason := filterReason(rule.Reason)
+	successCount, _ := pe.redactEventsInRoom(ctx, evt.Sender, evt.RoomID, []id.EventID{evt.ID}, reason)
+	if successCount == 0 {
+		// Don't ban if the message couldn't be redacted, as something is probably wrong with the bot's permissions
+		pe.sendNotice(ctx, "Failed to redact [message](%s) from [%s](%s) matching content policy `%s`",
+			evt.RoomID.EventURI(evt.ID).MatrixToURL(), evt.Sender, evt.Sender.URI().MatrixToURL(), rule.Pattern)
+		return
+	}
+	redacted := "Redacted"
+	if pe.DryRun {
+		redacted = "Would have redacted"
+	}
+	pe.sendNotice(ctx, "%s [message](%s) from [%s](%s) matching content policy `%s` for %s",
+		redacted, evt.RoomID.EventURI(evt.ID).MatrixToURL(), evt.Sender, evt.Sender.URI().MatrixToURL(), rule.Pattern, rule.Reason)
+	if rule.Recommendation != event.PolicyRecommendationBan {
+		return
+	}
+	publicReason := reason
+	if pe.Config.HideBanReasons {
+		publicReason = pe.Config.PublicBanReason
+	}
+	for _, roomID := range pe.getRoomsUserIsIn(evt.Sender) {
+		pe.applyContentBan(ctx, evt.Sender, roomID, rule, publicReason)
+	}
+}
+
+// applyContentBan bans a user who sent a message matching the given content policy.
+// Unlike ApplyBan, the taken action is recorded with the content policy's pattern rather than a policy entity.
+func (pe *PolicyEvaluator) applyContentBan(ctx context.Context, userID id.UserID, roomID id.RoomID, rule *policylist.ContentPolicy, publicReason string) {
+	ta := &database.TakenAction{
+		TargetUser: userID,
+		InRoomID:   roomID,
+		ActionType: database.TakenActionTypeContentBan,
+		PolicyList: rule.RoomID,
+		RuleEntity: rule.Pattern,
+		Action:     event.PolicyRecommendationBan,
+		Categories: pe.getListCategories(rule.RoomID),
+		TakenAt:    time.Now(),
+	}
+	var err error
+	if !pe.DryRun {
+		_, err = pe.Bot.BanUser(ctx, roomID, &mautrix.ReqBanUser{
+			Reason: publicReason,
+			UserID: userID,
+		})
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Any("attempted_action", ta).Msg("Failed to ban user for content policy")
+		pe.recordFailure("ban", userID, roomID, err)
+		pe.sendNotice(ctx, "Failed to ban [%s](%s) in [%s](%s) for content policy `%s`: %v",
+			userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), rule.Pattern, err)
+		return
+	}
+	err = pe.DB.TakenAction.Put(ctx, ta)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Any("taken_action", ta).Msg("Failed to save taken action")
+	} else {
+		zerolog.Ctx(ctx).Info().Any("taken_action", ta).Str("public_reason", publicReason).Msg("Took action")
+	}
+	banned := "Banned"
+	if pe.DryRun {
+		banned = "Would have banned"
+	}
+	pe.sendNotice(ctx, "%s [%s](%s) in [%s](%s) for matching content policy `%s` in %s",
+		banned, userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), rule.Pattern, pe.getListName(rule.RoomID))
+}
@@ -0,0 +1,139 @@
+package policyeval
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/util/glob"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/policylist"
+)
+
+type redundantPolicy struct {
+	Policy    *policylist.Policy
+	CoveredBy *policylist.Policy
+	Duplicate bool
+}
+
+type dedupGroupKey struct {
+	EntityType     policylist.EntityType
+	Recommendation event.PolicyRecommendation
+	SilentReason   bool
+	ReasonCode     string
+}
+
+// findRedundantPolicies finds policies that are exact duplicates of another policy or literal
+// policies whose entity is already covered by a glob policy with the same recommendation.
+func findRedundantPolicies(policies []*policylist.Policy) []redundantPolicy {
+	groups := make(map[dedupGroupKey][]*policylist.Policy)
+	for _, policy := range policies {
+		if policy.Ignored {
+			continue
+		}
+		key := dedupGroupKey{
+			EntityType:     policy.EntityType,
+			Recommendation: policy.Recommendation,
+			SilentReason:   policy.SilentReason,
+			ReasonCode:     policy.ReasonCode,
+		}
+		groups[key] = append(groups[key], policy)
+	}
+	var output []redundantPolicy
+	for _, group := range groups {
+		// Keep the oldest policy when there are duplicates
+		slices.SortFunc(group, func(a, b *policylist.Policy) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+		var globs []*policylist.Policy
+		for _, policy := range group {
+			if _, isLiteral := policy.Pattern.(glob.ExactGlob); !isLiteral {
+				globs = append(globs, policy)
+			}
+		}
+		firstByEntity := make(map[string]*policylist.Policy)
+	Outer:
+		for _, policy := range group {
+			if first, ok := firstByEntity[policy.Entity]; ok {
+				output = append(output, redundantPolicy{Policy: policy, CoveredBy: first, Duplicate: true})
+				continue
+			}
+			firstByEntity[policy.Entity] = policy
+			if _, isLiteral := policy.Pattern.(glob.ExactGlob); !isLiteral {
+				continue
+			}
+			for _, globPolicy := range globs {
+				if globPolicy.Pattern.Match(policy.Entity) {
+					output = append(output, redundantPolicy{Policy: policy, CoveredBy: globPolicy})
+					continue Outer
+				}
+			}
+		}
+	}
+	slices.SortFunc(output, func(a, b redundantPolicy) int {
+		return cmp.Or(
+			strings.Compare(string(a.Policy.EntityType), string(b.Policy.EntityType)),
+			strings.Compare(a.Policy.Entity, b.Policy.Entity),
+		)
+	})
+	return output
+}
+
+func (pe *PolicyEvaluator) dedupPolicies(ctx context.Context, sender id.UserID, shortcode string, dryRun bool) {
+	list := pe.FindListByShortcode(shortcode)
+	if list == nil {
+		pe.sendListNotFound(ctx, shortcode)
+		return
+	} else if !dryRun && !pe.checkListWriteAccess(ctx, list, sender) {
+		return
+	}
+	room := pe.Store.GetRoom(list.RoomID)
+	if room == nil {
+		pe.sendNotice(ctx, "Policies of %s haven't been loaded", list.Name)
+		return
+	}
+	redundant := findRedundantPolicies(room.Policies())
+	if len(redundant) == 0 {
+		pe.sendNotice(ctx, "No redundant policies found in %s", list.Name)
+		return
+	}
+	lines := make([]string, len(redundant))
+	var failed int
+	for i, rp := range redundant {
+		if rp.Duplicate {
+			lines[i] = fmt.Sprintf("* `%s` %s `%s` is a duplicate", rp.Policy.Recommendation, rp.Policy.EntityType, rp.Policy.Entity)
+		} else {
+			lines[i] = fmt.Sprintf("* `%s` %s `%s` is covered by `%s`", rp.Policy.Recommendation, rp.Policy.EntityType, rp.Policy.Entity, rp.CoveredBy.Entity)
+		}
+		if dryRun {
+			continue
+		}
+		_, err := pe.Bot.SendStateEvent(ctx, list.RoomID, rp.Policy.Type, rp.Policy.StateKey, map[string]any{})
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).
+				Stringer("policy_list", list.RoomID).
+				Str("state_key", rp.Policy.StateKey).
+				Msg("Failed to remove redundant policy")
+			lines[i] += fmt.Sprintf(" (failed to remove: %v)", err)
+			failed++
+		}
+	}
+	noun := "redundant policies"
+	if len(redundant) == 1 {
+		noun = "redundant policy"
+	}
+	var summary string
+	if dryRun {
+		summary = fmt.Sprintf("Found %d %s", len(redundant), noun)
+	} else if failed > 0 {
+		summary = fmt.Sprintf("Removed %d/%d %s", len(redundant)-failed, len(redundant), noun)
+	} else {
+		summary = fmt.Sprintf("Removed %d %s", len(redundant), noun)
+	}
+	pe.sendNotice(ctx, "%s in %s:\n\n%s", summary, list.Name, strings.Join(lines, "\n"))
+}
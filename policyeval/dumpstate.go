@@ -0,0 +1,85 @@
+package policyeval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// dumpStateTypes are the state event types included in !dump-state output when no type is specified.
+var dumpStateTypes = []event.Type{
+	event.StateCreate,
+	event.StatePowerLevels,
+	event.StateServerACL,
+	event.StateJoinRules,
+	event.StateHistoryVisibility,
+	event.StateGuestAccess,
+	event.StateEncryption,
+	event.StateTombstone,
+	event.StateRoomName,
+	event.StateCanonicalAlias,
+}
+
+type stateDump struct {
+	RoomID       id.RoomID                 `json:"room_id"`
+	DumpedAt     time.Time                 `json:"dumped_at"`
+	MemberCounts map[event.Membership]int  `json:"member_counts"`
+	State        map[string][]*event.Event `json:"state"`
+}
+
+func (pe *PolicyEvaluator) dumpState(ctx context.Context, roomIDOrAlias, eventType string) {
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	}
+	joinedRooms, err := pe.Bot.JoinedRooms(ctx)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get joined rooms: %v", err)
+		return
+	} else if !slices.Contains(joinedRooms.JoinedRooms, roomID) {
+		pe.sendNotice(ctx, "Bot is not in [%s](%s)", roomID, roomID.URI().MatrixToURL())
+		return
+	}
+	state, err := pe.Bot.State(ctx, roomID)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get state of [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err)
+		return
+	}
+	dump := &stateDump{
+		RoomID:       roomID,
+		DumpedAt:     time.Now().UTC(),
+		MemberCounts: make(map[event.Membership]int),
+		State:        make(map[string][]*event.Event),
+	}
+	for _, evt := range state[event.StateMember] {
+		dump.MemberCounts[evt.Content.AsMember().Membership]++
+	}
+	types := dumpStateTypes
+	if eventType != "" {
+		types = []event.Type{{Type: eventType, Class: event.StateEventType}}
+	}
+	for _, evtType := range types {
+		for _, evt := range state[evtType] {
+			dump.State[evtType.Type] = append(dump.State[evtType.Type], evt)
+		}
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to marshal state: %v", err)
+		return
+	}
+	fileName := fmt.Sprintf("state-%s.json", strings.TrimPrefix(string(roomID), "!"))
+	_, err = pe.Bot.SendFile(ctx, pe.ManagementRoom, fileName, "application/json", data)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to send state dump")
+		pe.sendNotice(ctx, "Failed to send state dump: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package policyeval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// checkRoomEncryption checks whether the given room has encryption enabled. If it doesn't and enable is true,
+// the bot tries to enable it. The returned message is empty if the room is encrypted,
+// and the returned bool is true if the room is encrypted after the check.
+func (pe *PolicyEvaluator) checkRoomEncryption(ctx context.Context, roomID id.RoomID, enable bool) (string, bool) {
+	roomLink := fmt.Sprintf("[%s](%s)", roomID, roomID.URI().MatrixToURL())
+	var content event.EncryptionEventContent
+	err := pe.Bot.StateEvent(ctx, roomID, event.StateEncryption, "", &content)
+	if err == nil && content.Algorithm != "" {
+		return "", true
+	} else if err != nil && !errors.Is(err, mautrix.MNotFound) {
+		return fmt.Sprintf("Failed to check encryption state of %s: %v", roomLink, err), false
+	} else if !enable {
+		return fmt.Sprintf("%s is not encrypted", roomLink), false
+	} else if pe.DryRun {
+		return fmt.Sprintf("%s is not encrypted (not enabling encryption in dry run mode)", roomLink), false
+	}
+	var powerLevels event.PowerLevelsEventContent
+	err = pe.Bot.StateEvent(ctx, roomID, event.StatePowerLevels, "", &powerLevels)
+	if err != nil {
+		return fmt.Sprintf("%s is not encrypted, and getting power levels failed: %v", roomLink, err), false
+	} else if powerLevels.GetUserLevel(pe.Bot.UserID) < powerLevels.GetEventLevel(event.StateEncryption) {
+		return fmt.Sprintf("%s is not encrypted, and the bot doesn't have permission to enable encryption", roomLink), false
+	}
+	_, err = pe.Bot.SendStateEvent(ctx, roomID, event.StateEncryption, "", &event.EncryptionEventContent{
+		Algorithm: id.AlgorithmMegolmV1,
+	})
+	if err != nil {
+		return fmt.Sprintf("%s is not encrypted, and enabling encryption failed: %v", roomLink, err), false
+	}
+	zerolog.Ctx(ctx).Info().Stringer("room_id", roomID).Msg("Enabled encryption in protected room")
+	return fmt.Sprintf("Enabled encryption in %s", roomLink), true
+}
+
+// checkNewProtectedRoomEncryption is called when starting to protect a single room and alerts the management room
+// if the room is not encrypted and require_room_encryption is enabled. Rooms protected in bulk by
+// handleProtectedRooms are checked there instead, so that the results can be aggregated.
+func (pe *PolicyEvaluator) checkNewProtectedRoomEncryption(ctx context.Context, roomID id.RoomID) {
+	if !pe.Config.RequireRoomEncryption {
+		return
+	}
+	if msg, encrypted := pe.checkRoomEncryption(ctx, roomID, pe.Config.EnableRoomEncryption); !encrypted {
+		pe.sendNotice(ctx, "⚠️ %s", msg)
+	} else if msg != "" {
+		pe.sendNotice(ctx, "%s", msg)
+	}
+}
+
+func (pe *PolicyEvaluator) sendRoomEncryptionCheck(ctx context.Context, enable bool) {
+	rooms := pe.GetProtectedRooms()
+	slices.Sort(rooms)
+	var lines []string
+	for _, roomID := range rooms {
+		if msg, _ := pe.checkRoomEncryption(ctx, roomID, enable); msg != "" {
+			lines = append(lines, "* "+msg)
+		}
+	}
+	if len(lines) == 0 {
+		pe.sendNotice(ctx, "All %d protected rooms are encrypted", len(rooms))
+	} else {
+		pe.sendNotice(ctx, "Encryption check of %d protected rooms:\n\n%s", len(rooms), strings.Join(lines, "\n"))
+	}
+}
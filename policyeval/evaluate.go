@@ -27,7 +27,7 @@ func (pe *PolicyEvaluator) EvaluateAllMembers(ctx context.Context, members []id.
 }
 
 func (pe *PolicyEvaluator) EvaluateUser(ctx context.Context, userID id.UserID, isNewRule bool) {
-	match := pe.Store.MatchUser(pe.GetWatchedLists(), userID)
+	match := pe.matchUser(pe.GetWatchedLists(), userID)
 	if match == nil {
 		return
 	}
@@ -65,10 +65,25 @@ func (pe *PolicyEvaluator) HandleMember(ctx context.Context, evt *event.Event) {
 			}
 		}
 	} else {
-		checkRules := pe.updateUser(userID, evt.RoomID, content.Membership)
+		membership := content.Membership
+		if membership == event.MembershipLeave {
+			pe.handleManualUnban(ctx, evt)
+			pe.checkJoinPartFlood(ctx, evt)
+		} else if membership == event.MembershipJoin && pe.Config.BanOnJoin && pe.enforceBanOnJoin(ctx, evt, userID) {
+			// The user was already banned from this room, so don't track them as a member. This also skips
+			// evaluating the user below, as they were already banned from any other rooms when the policy was applied.
+			membership = event.MembershipBan
+		}
+		checkRules := pe.updateUser(userID, evt.RoomID, membership)
+		pe.cacheMembership(ctx, evt.RoomID, userID, membership)
 		if checkRules {
 			pe.EvaluateUser(ctx, userID, false)
 		}
+		if content.Membership == event.MembershipKnock && pe.Config.DenyBannedKnocks {
+			pe.handleKnock(ctx, evt)
+		} else if content.Membership == event.MembershipInvite && evt.Sender != userID {
+			pe.checkInviteRate(ctx, evt)
+		}
 	}
 }
 
@@ -116,6 +131,9 @@ func (pe *PolicyEvaluator) HandlePolicyListChange(ctx context.Context, policyRoo
 		Any("removed", removed).
 		Msg("Policy list change")
 	removedAndAddedAreEquivalent := removed != nil && added != nil && removed.Entity == added.Entity && removed.Recommendation == added.Recommendation
+	if removed != nil && !removedAndAddedAreEquivalent {
+		pe.releaseHeldPolicy(ctx, removed)
+	}
 	if removedAndAddedAreEquivalent {
 		if removed.Reason == added.Reason {
 			pe.sendNotice(ctx,
@@ -139,20 +157,39 @@ func (pe *PolicyEvaluator) HandlePolicyListChange(ctx context.Context, policyRoo
 				pe.EvaluateRemovedRule(ctx, removed)
 			}
 		}
+		var inBurst bool
+		if added != nil && !added.Ignored {
+			var held bool
+			inBurst, held = pe.checkPolicyBurst(ctx, policyRoomMeta, added)
+			if held {
+				return
+			}
+		}
+		if added != nil && !added.Ignored && !policyRoomMeta.DontApply {
+			if matched, total, tooBroad := pe.isPolicyTooBroad(added); tooBroad {
+				pe.holdBroadPolicy(ctx, policyRoomMeta.Name, added, matched, total)
+				return
+			}
+		}
 		if added != nil {
 			var suffix string
 			if added.Ignored {
 				suffix = " (rule was ignored)"
 			}
-			pe.sendNotice(ctx,
-				"[%s] [%s](%s) %s %ss matching `%s` for `%s`%s",
-				policyRoomMeta.Name, added.Sender, added.Sender.URI().MatrixToURL(),
-				addActionString(added.Recommendation), added.EntityType, added.Entity, added.Reason,
-				suffix,
-			)
+			if !inBurst {
+				pe.sendNotice(ctx,
+					"[%s] [%s](%s) %s %ss matching `%s` for `%s`%s",
+					policyRoomMeta.Name, added.Sender, added.Sender.URI().MatrixToURL(),
+					addActionString(added.Recommendation), added.EntityType, added.Entity, added.Reason,
+					suffix,
+				)
+			}
 			if !policyRoomMeta.DontApply {
 				pe.EvaluateAddedRule(ctx, added)
 			}
+			if !added.Ignored {
+				pe.publishUpstream(ctx, policyRoomMeta, added)
+			}
 		}
 	}
 }
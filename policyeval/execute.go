@@ -10,9 +10,9 @@ import (
 
 	"github.com/rs/zerolog"
 	"maunium.net/go/mautrix"
-	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 
+	"go.mau.fi/meowlnir/config"
 	"go.mau.fi/meowlnir/database"
 	"go.mau.fi/meowlnir/policylist"
 )
@@ -24,6 +24,11 @@ func (pe *PolicyEvaluator) getRoomsUserIsIn(userID id.UserID) []id.RoomID {
 	return rooms
 }
 
+// shouldAutoRedact returns true if all messages of users banned by the given policy should be redacted.
+func shouldAutoRedact(policy *policylist.Policy, listMeta *config.WatchedPolicyList) bool {
+	return policy.Reason == "spam" || (listMeta != nil && listMeta.AutoRedact)
+}
+
 func (pe *PolicyEvaluator) ApplyPolicy(ctx context.Context, userID id.UserID, policy policylist.Match, isNew bool) {
 	if userID == pe.Bot.UserID {
 		return
@@ -42,15 +47,30 @@ func (pe *PolicyEvaluator) ApplyPolicy(ctx context.Context, userID id.UserID, po
 		return
 	}
 	if recs.BanOrUnban != nil {
-		if recs.BanOrUnban.Recommendation == event.PolicyRecommendationBan {
+		if recs.IsBan() {
+			if pe.recordPausedMatch(userID, recs.BanOrUnban.Reason) {
+				// Matches are reported all at once after resuming to avoid flooding the management room
+				zerolog.Ctx(ctx).Info().
+					Stringer("user_id", userID).
+					Any("matches", policy).
+					Msg("Not applying ban recommendation as automated moderation is paused")
+				return
+			}
 			zerolog.Ctx(ctx).Info().
 				Stringer("user_id", userID).
 				Any("matches", policy).
 				Msg("Applying ban recommendation")
+			publicReason := pe.getPublicBanReason(recs.BanOrUnban)
+			listMeta := pe.GetWatchedListMeta(recs.BanOrUnban.RoomID)
+			var anySucceeded bool
 			for _, room := range rooms {
-				pe.ApplyBan(ctx, userID, room, recs.BanOrUnban)
+				_, ok := pe.applyBanInRoom(ctx, userID, room, recs.BanOrUnban, publicReason, listMeta)
+				anySucceeded = anySucceeded || ok
 			}
-			if recs.BanOrUnban.Reason == "spam" {
+			if anySucceeded {
+				pe.recordListHit(ctx, recs.BanOrUnban.RoomID)
+			}
+			if shouldAutoRedact(recs.BanOrUnban, listMeta) {
 				go pe.RedactUser(context.WithoutCancel(ctx), userID, recs.BanOrUnban.Reason, true)
 			}
 		} else {
@@ -65,6 +85,46 @@ func (pe *PolicyEvaluator) ApplyPolicy(ctx context.Context, userID id.UserID, po
 	}
 }
 
+// applyBanInRoom applies a ban recommendation to a single room, unless the user's manual unban is under review
+// or in its cooldown, in which case attempted is false. If the list overrides the action, the user is kicked instead.
+func (pe *PolicyEvaluator) applyBanInRoom(
+	ctx context.Context, userID id.UserID, roomID id.RoomID, policy *policylist.Policy, publicReason string, listMeta *config.WatchedPolicyList,
+) (attempted, ok bool) {
+	if pe.isUnbanUnderReview(userID, roomID) {
+		zerolog.Ctx(ctx).Debug().
+			Stringer("user_id", userID).
+			Stringer("room_id", roomID).
+			Msg("Not re-banning user whose manual unban is under review")
+		return false, false
+	} else if pe.checkManualUnbanCooldown(ctx, userID, roomID, policy) {
+		return false, false
+	}
+	if listMeta != nil && listMeta.ActionOverride == config.ActionOverrideKick {
+		return true, pe.ApplyKick(ctx, userID, roomID, policy, publicReason)
+	}
+	return true, pe.ApplyBan(ctx, userID, roomID, policy, publicReason)
+}
+
+// recordListHit increments the hit count of the given policy list. It should be called once per user
+// that a policy from the list was successfully applied to, regardless of how many rooms they were in.
+func (pe *PolicyEvaluator) recordListHit(ctx context.Context, policyList id.RoomID) {
+	if pe.DryRun {
+		return
+	}
+	err := pe.DB.ListStats.IncrementHitCount(ctx, pe.ManagementRoom, policyList)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("policy_list", policyList).Msg("Failed to increment policy list hit count")
+	}
+}
+
+// getPublicBanReason returns the reason that should be included in ban events caused by the given policy.
+func (pe *PolicyEvaluator) getPublicBanReason(policy *policylist.Policy) string {
+	if pe.Config.HideBanReasons || policy.SilentReason {
+		return pe.Config.PublicBanReason
+	}
+	return filterReason(policy.Reason)
+}
+
 func filterReason(reason string) string {
 	if reason == "<no reason supplied>" {
 		return ""
@@ -72,7 +132,7 @@ func filterReason(reason string) string {
 	return reason
 }
 
-func (pe *PolicyEvaluator) ApplyBan(ctx context.Context, userID id.UserID, roomID id.RoomID, policy *policylist.Policy) {
+func (pe *PolicyEvaluator) ApplyBan(ctx context.Context, userID id.UserID, roomID id.RoomID, policy *policylist.Policy, publicReason string) bool {
 	ta := &database.TakenAction{
 		TargetUser: userID,
 		InRoomID:   roomID,
@@ -80,12 +140,14 @@ func (pe *PolicyEvaluator) ApplyBan(ctx context.Context, userID id.UserID, roomI
 		PolicyList: policy.RoomID,
 		RuleEntity: policy.Entity,
 		Action:     policy.Recommendation,
+		Categories: pe.getListCategories(policy.RoomID),
+		ReasonCode: policy.ReasonCode,
 		TakenAt:    time.Now(),
 	}
 	var err error
 	if !pe.DryRun {
 		_, err = pe.Bot.BanUser(ctx, roomID, &mautrix.ReqBanUser{
-			Reason: filterReason(policy.Reason),
+			Reason: publicReason,
 			UserID: userID,
 		})
 	}
@@ -94,18 +156,81 @@ func (pe *PolicyEvaluator) ApplyBan(ctx context.Context, userID id.UserID, roomI
 		if errors.As(err, &respErr) {
 			err = respErr
 		}
-		zerolog.Ctx(ctx).Err(err).Any("attempted_action", ta).Msg("Failed to ban user")
+		zerolog.Ctx(ctx).Err(err).Any("attempted_action", ta).Str("public_reason", publicReason).Msg("Failed to ban user")
+		pe.recordFailure("ban", userID, roomID, err)
+		pe.saveFailedBan(ctx, userID, roomID, policy, err)
 		pe.sendNotice(ctx, "Failed to ban [%s](%s) in [%s](%s) for %s: %v", userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), policy.Reason, err)
-		return
+		return false
+	} else if !pe.DryRun {
+		pe.clearFailedBan(ctx, userID, roomID)
 	}
 	err = pe.DB.TakenAction.Put(ctx, ta)
 	if err != nil {
 		zerolog.Ctx(ctx).Err(err).Any("taken_action", ta).Msg("Failed to save taken action")
 		pe.sendNotice(ctx, "Banned [%s](%s) in [%s](%s) for %s, but failed to save to database: %v", userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), policy.Reason, err)
 	} else {
-		zerolog.Ctx(ctx).Info().Any("taken_action", ta).Msg("Took action")
-		pe.sendNotice(ctx, "Banned [%s](%s) in [%s](%s) for %s", userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), policy.Reason)
+		zerolog.Ctx(ctx).Info().Any("taken_action", ta).Str("public_reason", publicReason).Msg("Took action")
+		var publicReasonSuffix string
+		if publicReason != filterReason(policy.Reason) {
+			publicReasonSuffix = fmt.Sprintf(" (public reason: %s)", publicReason)
+		}
+		data := newNoticeTemplateData(userID, roomID)
+		data.Reason = policy.Reason
+		data.PublicReason = publicReason
+		data.ListCategory = strings.Join(pe.getListCategories(policy.RoomID), "/")
+		pe.sendNotice(ctx, pe.formatNotice(
+			ctx, noticeTemplateBan, data,
+			"Banned [%s](%s) in [%s](%s) for %s%s%s", userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), policy.Reason, pe.listCategorySuffix(policy.RoomID), publicReasonSuffix,
+		))
+	}
+	return true
+}
+
+func (pe *PolicyEvaluator) ApplyKick(ctx context.Context, userID id.UserID, roomID id.RoomID, policy *policylist.Policy, publicReason string) bool {
+	ta := &database.TakenAction{
+		TargetUser: userID,
+		InRoomID:   roomID,
+		ActionType: database.TakenActionTypeKick,
+		PolicyList: policy.RoomID,
+		RuleEntity: policy.Entity,
+		Action:     policy.Recommendation,
+		Categories: pe.getListCategories(policy.RoomID),
+		ReasonCode: policy.ReasonCode,
+		TakenAt:    time.Now(),
+	}
+	var err error
+	if !pe.DryRun {
+		_, err = pe.Bot.KickUser(ctx, roomID, &mautrix.ReqKickUser{
+			Reason: publicReason,
+			UserID: userID,
+		})
+	}
+	if err != nil {
+		var respErr mautrix.HTTPError
+		if errors.As(err, &respErr) {
+			err = respErr
+		}
+		zerolog.Ctx(ctx).Err(err).Any("attempted_action", ta).Str("public_reason", publicReason).Msg("Failed to kick user")
+		pe.recordFailure("kick", userID, roomID, err)
+		pe.sendNotice(ctx, "Failed to kick [%s](%s) from [%s](%s) for %s: %v", userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), policy.Reason, err)
+		return false
+	}
+	err = pe.DB.TakenAction.Put(ctx, ta)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Any("taken_action", ta).Msg("Failed to save taken action")
+		pe.sendNotice(ctx, "Kicked [%s](%s) from [%s](%s) for %s, but failed to save to database: %v", userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), policy.Reason, err)
+	} else {
+		zerolog.Ctx(ctx).Info().Any("taken_action", ta).Str("public_reason", publicReason).Msg("Took action")
+		data := newNoticeTemplateData(userID, roomID)
+		data.Reason = policy.Reason
+		data.PublicReason = publicReason
+		data.ListCategory = strings.Join(pe.getListCategories(policy.RoomID), "/")
+		pe.sendNotice(ctx, pe.formatNotice(
+			ctx, noticeTemplateKick, data,
+			"Kicked [%s](%s) from [%s](%s) for %s%s", userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), policy.Reason, pe.listCategorySuffix(policy.RoomID),
+		))
 	}
+	return true
 }
 
 func pluralize(value int, unit string) string {
@@ -115,10 +240,14 @@ func pluralize(value int, unit string) string {
 	return fmt.Sprintf("%d %ss", value, unit)
 }
 
-func (pe *PolicyEvaluator) redactUserMSC4194(ctx context.Context, userID id.UserID, reason string) {
+func (pe *PolicyEvaluator) redactUserMSC4194(ctx context.Context, userID id.UserID, reason string) int {
+	redactedCount, roomCount, errorMessages := pe.redactAllMSC4194(ctx, userID, reason)
+	pe.sendRedactResult(ctx, redactedCount, roomCount, userID, errorMessages)
+	return redactedCount
+}
+
+func (pe *PolicyEvaluator) redactAllMSC4194(ctx context.Context, userID id.UserID, reason string) (redactedCount, roomCount int, errorMessages []string) {
 	rooms := pe.GetProtectedRooms()
-	var errorMessages []string
-	var redactedCount, roomCount int
 Outer:
 	for _, roomID := range rooms {
 		hasMore := true
@@ -127,6 +256,7 @@ Outer:
 			resp, err := pe.Bot.UnstableRedactUserEvents(ctx, roomID, userID, &mautrix.ReqRedactUser{Reason: reason})
 			if err != nil {
 				zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to redact messages")
+				pe.recordFailure("redact events from", userID, roomID, err)
 				errorMessages = append(errorMessages, fmt.Sprintf(
 					"* Failed to redact events from [%s](%s) in [%s](%s): %v",
 					userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), err))
@@ -142,15 +272,30 @@ Outer:
 			}
 		}
 	}
-	pe.sendRedactResult(ctx, redactedCount, roomCount, userID, errorMessages)
+	return
+}
+
+func (pe *PolicyEvaluator) getEventsToRedactSynapse(ctx context.Context, userID id.UserID) (map[id.RoomID][]id.EventID, time.Time, error) {
+	rooms := pe.GetProtectedRooms()
+	if activeRooms, err := pe.SynapseDB.GetUserActiveRooms(ctx, userID, rooms); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).
+			Stringer("user_id", userID).
+			Msg("Failed to get user's active rooms, scanning all protected rooms for events to redact")
+	} else if len(activeRooms) == 0 {
+		return nil, time.Time{}, nil
+	} else {
+		rooms = activeRooms
+	}
+	return pe.SynapseDB.GetEventsToRedact(ctx, userID, rooms)
 }
 
-func (pe *PolicyEvaluator) redactUserSynapse(ctx context.Context, userID id.UserID, reason string, allowReredact bool) {
-	events, maxTS, err := pe.SynapseDB.GetEventsToRedact(ctx, userID, pe.GetProtectedRooms())
+func (pe *PolicyEvaluator) redactUserSynapse(ctx context.Context, userID id.UserID, reason string, allowReredact bool, onRedacted func(ctx context.Context)) {
+	events, maxTS, err := pe.getEventsToRedactSynapse(ctx, userID)
 	if err != nil {
 		zerolog.Ctx(ctx).Err(err).
 			Stringer("user_id", userID).
 			Msg("Failed to get events to redact")
+		pe.recordFailure("find events to redact from", userID, "", err)
 		pe.sendNotice(ctx,
 			"Failed to get events to redact for [%s](%s): %v",
 			userID, userID.URI().MatrixToURL(), err)
@@ -160,8 +305,54 @@ func (pe *PolicyEvaluator) redactUserSynapse(ctx context.Context, userID id.User
 	}
 	reason = filterReason(reason)
 	needsReredact := allowReredact && time.Since(maxTS) < 5*time.Minute
-	var errorMessages []string
-	var redactedCount int
+	var totalCount int
+	for _, roomEvents := range events {
+		totalCount += len(roomEvents)
+	}
+	if pe.Config.RedactionConfirmLimit > 0 && totalCount > pe.Config.RedactionConfirmLimit {
+		pe.requestConfirmation(ctx, fmt.Sprintf(
+			"Redacting all messages from [%s](%s) would redact %s across %s, which is above the safety limit of %d.",
+			userID, userID.URI().MatrixToURL(), pluralize(totalCount, "event"), pluralize(len(events), "room"),
+			pe.Config.RedactionConfirmLimit,
+		), func(ctx context.Context) {
+			if pe.redactEventsSynapse(ctx, userID, reason, events, totalCount, needsReredact) > 0 && onRedacted != nil {
+				onRedacted(ctx)
+			}
+		})
+		return
+	}
+	if pe.redactEventsSynapse(ctx, userID, reason, events, totalCount, needsReredact) > 0 && onRedacted != nil {
+		onRedacted(ctx)
+	}
+}
+
+const redactProgressInterval = 1000
+
+func (pe *PolicyEvaluator) redactEventsSynapse(
+	ctx context.Context,
+	userID id.UserID,
+	reason string,
+	events map[id.RoomID][]id.EventID,
+	totalCount int,
+	needsReredact bool,
+) int {
+	redactedCount, errorMessages := pe.redactEventMap(ctx, userID, reason, events, totalCount)
+	pe.sendRedactResult(ctx, redactedCount, len(events), userID, errorMessages)
+	if needsReredact {
+		time.Sleep(15 * time.Second)
+		pe.RedactUser(ctx, userID, reason, false)
+	}
+	return redactedCount
+}
+
+func (pe *PolicyEvaluator) redactEventMap(
+	ctx context.Context,
+	userID id.UserID,
+	reason string,
+	events map[id.RoomID][]id.EventID,
+	totalCount int,
+) (redactedCount int, errorMessages []string) {
+	var processedCount int
 	for roomID, roomEvents := range events {
 		successCount, failedCount := pe.redactEventsInRoom(ctx, userID, roomID, roomEvents, reason)
 		if failedCount > 0 {
@@ -170,16 +361,22 @@ func (pe *PolicyEvaluator) redactUserSynapse(ctx context.Context, userID id.User
 				failedCount, failedCount+successCount, userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL()))
 		}
 		redactedCount += successCount
+		prevProcessedCount := processedCount
+		processedCount += successCount + failedCount
+		if processedCount < totalCount && processedCount/redactProgressInterval > prevProcessedCount/redactProgressInterval {
+			pe.sendNotice(ctx, "Redacting messages from [%s](%s): %d/%d events processed",
+				userID, userID.URI().MatrixToURL(), processedCount, totalCount)
+		}
 	}
-	pe.sendRedactResult(ctx, redactedCount, len(events), userID, errorMessages)
-	if needsReredact {
-		time.Sleep(15 * time.Second)
-		pe.RedactUser(ctx, userID, reason, false)
-	}
+	return
 }
 
 func (pe *PolicyEvaluator) sendRedactResult(ctx context.Context, events, rooms int, userID id.UserID, errorMessages []string) {
-	output := fmt.Sprintf("Redacted %s across %s from [%s](%s)",
+	data := newNoticeTemplateData(userID, "")
+	data.EventCount = events
+	data.RoomCount = rooms
+	output := pe.formatNotice(ctx, noticeTemplateRedact, data,
+		"Redacted %s across %s from [%s](%s)",
 		pluralize(events, "event"), pluralize(rooms, "room"),
 		userID, userID.URI().MatrixToURL())
 	if len(errorMessages) > 0 {
@@ -189,10 +386,18 @@ func (pe *PolicyEvaluator) sendRedactResult(ctx context.Context, events, rooms i
 }
 
 func (pe *PolicyEvaluator) RedactUser(ctx context.Context, userID id.UserID, reason string, allowReredact bool) {
+	pe.redactUser(ctx, userID, reason, allowReredact, nil)
+}
+
+// redactUser is like RedactUser, but calls onRedacted if at least one event was redacted,
+// which may happen later if redacting requires confirmation.
+func (pe *PolicyEvaluator) redactUser(ctx context.Context, userID id.UserID, reason string, allowReredact bool, onRedacted func(ctx context.Context)) {
 	if pe.SynapseDB != nil {
-		pe.redactUserSynapse(ctx, userID, reason, allowReredact)
+		pe.redactUserSynapse(ctx, userID, reason, allowReredact, onRedacted)
 	} else if pe.Bot.Client.SpecVersions.Supports(mautrix.FeatureUserRedaction) {
-		pe.redactUserMSC4194(ctx, userID, reason)
+		if pe.redactUserMSC4194(ctx, userID, reason) > 0 && onRedacted != nil {
+			onRedacted(ctx)
+		}
 	} else {
 		zerolog.Ctx(ctx).Debug().
 			Stringer("user_id", userID).
@@ -200,6 +405,35 @@ func (pe *PolicyEvaluator) RedactUser(ctx context.Context, userID id.UserID, rea
 	}
 }
 
+// redactUserWithoutConfirmation redacts all messages from the given user like RedactUser, but doesn't ask for
+// confirmation above redaction_confirm_limit or send a notice, so that commands which already had an admin
+// confirm the impact can report the combined result themselves. If the server doesn't support redacting
+// all messages from a user, supported is false.
+func (pe *PolicyEvaluator) redactUserWithoutConfirmation(
+	ctx context.Context, userID id.UserID, reason string,
+) (redactedCount, roomCount int, errorMessages []string, supported bool) {
+	if pe.SynapseDB != nil {
+		events, _, err := pe.getEventsToRedactSynapse(ctx, userID)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Msg("Failed to get events to redact")
+			pe.recordFailure("find events to redact from", userID, "", err)
+			return 0, 0, []string{fmt.Sprintf(
+				"* Failed to get events to redact for [%s](%s): %v", userID, userID.URI().MatrixToURL(), err,
+			)}, true
+		}
+		var totalCount int
+		for _, roomEvents := range events {
+			totalCount += len(roomEvents)
+		}
+		redactedCount, errorMessages = pe.redactEventMap(ctx, userID, filterReason(reason), events, totalCount)
+		return redactedCount, len(events), errorMessages, true
+	} else if pe.Bot.Client.SpecVersions.Supports(mautrix.FeatureUserRedaction) {
+		redactedCount, roomCount, errorMessages = pe.redactAllMSC4194(ctx, userID, reason)
+		return redactedCount, roomCount, errorMessages, true
+	}
+	return 0, 0, nil, false
+}
+
 func (pe *PolicyEvaluator) redactEventsInRoom(ctx context.Context, userID id.UserID, roomID id.RoomID, events []id.EventID, reason string) (successCount, failedCount int) {
 	for _, evtID := range events {
 		var resp *mautrix.RespSendEvent
@@ -215,6 +449,7 @@ func (pe *PolicyEvaluator) redactEventsInRoom(ctx context.Context, userID id.Use
 				Stringer("room_id", roomID).
 				Stringer("event_id", evtID).
 				Msg("Failed to redact event")
+			pe.recordFailure(fmt.Sprintf("redact %s from", evtID), userID, roomID, err)
 			failedCount++
 		} else {
 			zerolog.Ctx(ctx).Debug().
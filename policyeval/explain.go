@@ -0,0 +1,87 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/policylist"
+)
+
+func (pe *PolicyEvaluator) explainUser(ctx context.Context, userID id.UserID) {
+	lists := pe.GetWatchedLists()
+	match := pe.Store.MatchUser(lists, userID)
+	recs := pe.withoutHeld(match).Recommendations()
+	var lines []string
+	if len(match) == 0 {
+		lines = append(lines, "* No policies in applied lists match the user")
+	} else {
+		lines = append(lines, "* Matching policies in priority order:\n"+pe.formatMatch(match))
+	}
+	if recs.BanOrUnban != nil {
+		lines = append(lines, fmt.Sprintf("* The decision comes from the `%s` rule for `%s` in %s (#%d in priority order)",
+			recs.BanOrUnban.Recommendation, recs.BanOrUnban.Entity, pe.getListName(recs.BanOrUnban.RoomID), recs.Priority+1))
+	}
+	for _, policy := range recs.OverriddenBans {
+		lines = append(lines, fmt.Sprintf(
+			"* The ban for `%s` is overridden by a higher priority unban rule for `%s`",
+			policy.Entity, recs.BanOrUnban.Entity))
+	}
+	var ignored policylist.Match
+	for _, listID := range lists {
+		room := pe.Store.GetRoom(listID)
+		if room == nil {
+			continue
+		}
+		for _, policy := range room.UserRules.Policies() {
+			if policy.Ignored && policy.Pattern.Match(string(userID)) {
+				ignored = append(ignored, policy)
+			}
+		}
+	}
+	var held policylist.Match
+	for _, policy := range match {
+		if pe.isPolicyHeld(policy) {
+			held = append(held, policy)
+		}
+	}
+	if len(held) > 0 {
+		lines = append(lines, "* Matching policies held for admin approval:\n"+pe.formatMatch(held))
+	}
+	if len(ignored) > 0 {
+		lines = append(lines, "* Matching policies ignored by `hacky_rule_filter`:\n"+pe.formatMatch(ignored))
+	}
+	var notApplied policylist.Match
+	for _, policy := range pe.Store.MatchUser(nil, userID) {
+		if meta := pe.GetWatchedListMeta(policy.RoomID); meta != nil && meta.DontApply {
+			notApplied = append(notApplied, policy)
+		}
+	}
+	if len(notApplied) > 0 {
+		lines = append(lines, "* Matching policies in watched lists that aren't applied:\n"+pe.formatMatch(notApplied))
+	}
+	if userID == pe.Bot.UserID {
+		lines = append(lines, "* The user is this bot, so policies are never applied")
+	} else if pe.Admins.Has(userID) {
+		lines = append(lines, "* The user is an admin in this management room")
+	}
+	rooms := pe.getRoomsUserIsIn(userID)
+	if len(rooms) == 0 {
+		lines = append(lines, "* The user is not in any protected rooms, so bans are only applied when new policies are added")
+	} else {
+		roomLinks := make([]string, len(rooms))
+		for i, roomID := range rooms {
+			roomLinks[i] = fmt.Sprintf("[%s](%s)", roomID, roomID.URI().MatrixToURL())
+		}
+		lines = append(lines, fmt.Sprintf("* The user is in %s: %s", pluralize(len(rooms), "protected room"), strings.Join(roomLinks, ", ")))
+	}
+	verdict := "no action"
+	if recs.IsBan() {
+		verdict = "banned"
+	} else if recs.BanOrUnban != nil {
+		verdict = "explicitly not banned"
+	}
+	pe.sendNotice(ctx, "Decision for [%s](%s): **%s**\n\n%s", userID, userID.URI().MatrixToURL(), verdict, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,106 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// saveFailedBan stores a ban that couldn't be applied, so that it can be listed with !failed-bans and retried later.
+func (pe *PolicyEvaluator) saveFailedBan(ctx context.Context, userID id.UserID, roomID id.RoomID, policy *policylist.Policy, banErr error) {
+	err := pe.DB.FailedBan.Put(ctx, &database.FailedBan{
+		ManagementRoom: pe.ManagementRoom,
+		UserID:         userID,
+		RoomID:         roomID,
+		PolicyList:     policy.RoomID,
+		RuleEntity:     policy.Entity,
+		Error:          banErr.Error(),
+		FailedAt:       time.Now(),
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Stringer("room_id", roomID).Msg("Failed to save failed ban")
+	}
+}
+
+func (pe *PolicyEvaluator) clearFailedBan(ctx context.Context, userID id.UserID, roomID id.RoomID) {
+	err := pe.DB.FailedBan.Delete(ctx, pe.ManagementRoom, userID, roomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Stringer("room_id", roomID).Msg("Failed to delete failed ban")
+	}
+}
+
+func (pe *PolicyEvaluator) sendFailedBans(ctx context.Context) {
+	failedBans, err := pe.DB.FailedBan.GetAll(ctx, pe.ManagementRoom)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get failed bans: %v", err)
+		return
+	} else if len(failedBans) == 0 {
+		pe.sendNotice(ctx, "No failed bans")
+		return
+	}
+	lines := make([]string, len(failedBans))
+	for i, fb := range failedBans {
+		lines[i] = fmt.Sprintf("* %s: [%s](%s) in [%s](%s) for `%s`: %s",
+			fb.FailedAt.UTC().Format(time.RFC3339), fb.UserID, fb.UserID.URI().MatrixToURL(),
+			fb.RoomID, fb.RoomID.URI().MatrixToURL(), fb.RuleEntity, fb.Error)
+	}
+	pe.sendNotice(ctx, "%s need manual intervention (use `!retry-failed` to try again):\n\n%s",
+		pluralize(len(failedBans), "failed ban"), strings.Join(lines, "\n"))
+}
+
+// retryFailedBans re-attempts all failed bans using the same checks as normal policy evaluation.
+// Bans in rooms that are no longer protected or for users who no longer match a ban policy are dropped from the queue.
+func (pe *PolicyEvaluator) retryFailedBans(ctx context.Context) {
+	if pe.IsPaused() {
+		pe.sendNotice(ctx, "Automated moderation is paused, not retrying failed bans")
+		return
+	} else if pe.DryRun {
+		pe.sendNotice(ctx, "Dry run mode is enabled, not retrying failed bans")
+		return
+	}
+	failedBans, err := pe.DB.FailedBan.GetAll(ctx, pe.ManagementRoom)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get failed bans: %v", err)
+		return
+	} else if len(failedBans) == 0 {
+		pe.sendNotice(ctx, "No failed bans to retry")
+		return
+	}
+	var succeeded, failed, dropped, unprotected, skipped int
+	for _, fb := range failedBans {
+		if !pe.IsProtectedRoom(fb.RoomID) {
+			pe.clearFailedBan(ctx, fb.UserID, fb.RoomID)
+			unprotected++
+			continue
+		}
+		recs := pe.matchUser(pe.GetWatchedLists(), fb.UserID).Recommendations()
+		if !recs.IsBan() {
+			pe.clearFailedBan(ctx, fb.UserID, fb.RoomID)
+			dropped++
+			continue
+		}
+		listMeta := pe.GetWatchedListMeta(recs.BanOrUnban.RoomID)
+		attempted, ok := pe.applyBanInRoom(ctx, fb.UserID, fb.RoomID, recs.BanOrUnban, pe.getPublicBanReason(recs.BanOrUnban), listMeta)
+		if !attempted {
+			// The user was manually unbanned after the ban failed, so the pending ban is obsolete
+			pe.clearFailedBan(ctx, fb.UserID, fb.RoomID)
+			skipped++
+		} else if ok {
+			// Kicks from action_override don't clear the failed ban themselves
+			pe.clearFailedBan(ctx, fb.UserID, fb.RoomID)
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+	pe.sendNotice(ctx, "Retried %s: %d succeeded, %d failed again, %d no longer match a ban policy, "+
+		"%d are in rooms that are no longer protected, %d were skipped due to manual unbans",
+		pluralize(len(failedBans), "failed ban"), succeeded, failed, dropped, unprotected, skipped)
+}
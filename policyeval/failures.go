@@ -0,0 +1,66 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"maunium.net/go/mautrix/id"
+)
+
+// maxRecordedFailures is the number of recent action failures kept in memory for the !failures command.
+const maxRecordedFailures = 50
+
+type actionFailure struct {
+	Time   time.Time
+	Action string
+	UserID id.UserID
+	RoomID id.RoomID
+	Error  string
+}
+
+// recordFailure stores a failed moderation action so that it can be inspected later with !failures.
+// Only the most recent failures are kept.
+func (pe *PolicyEvaluator) recordFailure(action string, userID id.UserID, roomID id.RoomID, err error) {
+	pe.failuresLock.Lock()
+	defer pe.failuresLock.Unlock()
+	if len(pe.failures) >= maxRecordedFailures {
+		pe.failures = slices.Delete(pe.failures, 0, len(pe.failures)-maxRecordedFailures+1)
+	}
+	pe.failures = append(pe.failures, actionFailure{
+		Time:   time.Now(),
+		Action: action,
+		UserID: userID,
+		RoomID: roomID,
+		Error:  err.Error(),
+	})
+}
+
+func (pe *PolicyEvaluator) sendFailures(ctx context.Context, clear bool) {
+	pe.failuresLock.Lock()
+	failures := slices.Clone(pe.failures)
+	if clear {
+		pe.failures = nil
+	}
+	pe.failuresLock.Unlock()
+	if len(failures) == 0 {
+		pe.sendNotice(ctx, "No failed actions recorded since startup")
+		return
+	}
+	slices.Reverse(failures)
+	lines := make([]string, len(failures))
+	for i, failure := range failures {
+		line := fmt.Sprintf("* %s: %s [%s](%s)", failure.Time.UTC().Format(time.RFC3339), failure.Action, failure.UserID, failure.UserID.URI().MatrixToURL())
+		if failure.RoomID != "" {
+			line += fmt.Sprintf(" in [%s](%s)", failure.RoomID, failure.RoomID.URI().MatrixToURL())
+		}
+		lines[i] = line + ": " + failure.Error
+	}
+	var suffix string
+	if clear {
+		suffix = " (cleared)"
+	}
+	pe.sendNotice(ctx, "Recent failed actions, newest first%s:\n\n%s", suffix, strings.Join(lines, "\n"))
+}
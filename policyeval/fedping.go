@@ -0,0 +1,50 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/federation"
+)
+
+const fedPingTimeout = 15 * time.Second
+
+// fedPing checks whether the given server is reachable over federation and reports its version and signing keys.
+func (pe *PolicyEvaluator) fedPing(ctx context.Context, serverName string) {
+	if _, _, ok := federation.ParseServerName(serverName); !ok {
+		pe.sendNotice(ctx, "`%s` is not a valid server name", serverName)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, fedPingTimeout)
+	defer cancel()
+	client := federation.NewClient("", nil)
+	client.HTTP.Timeout = fedPingTimeout
+	log := zerolog.Ctx(ctx).With().Str("server_name", serverName).Logger()
+
+	lines := []string{fmt.Sprintf("Federation check for `%s`:", serverName)}
+	start := time.Now()
+	version, err := client.Version(ctx, serverName)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to get server version")
+		lines = append(lines, fmt.Sprintf("* ❌ Version request failed: %v", err))
+	} else {
+		lines = append(lines, fmt.Sprintf("* ✅ Reachable in %s, running %s %s",
+			time.Since(start).Round(time.Millisecond), version.Server.Name, version.Server.Version))
+	}
+	keys, err := client.ServerKeys(ctx, serverName)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to get server keys")
+		lines = append(lines, fmt.Sprintf("* ❌ Key request failed: %v", err))
+	} else if keys.ServerName != serverName {
+		lines = append(lines, fmt.Sprintf("* ❌ Keys are for wrong server `%s`", keys.ServerName))
+	} else if validUntil := keys.ValidUntilTS.Time; validUntil.Before(time.Now()) {
+		lines = append(lines, fmt.Sprintf("* ⚠️ Keys expired at %s", validUntil.UTC().Format(time.RFC3339)))
+	} else {
+		lines = append(lines, fmt.Sprintf("* ✅ %s valid until %s",
+			pluralize(len(keys.VerifyKeys), "signing key"), validUntil.UTC().Format(time.RFC3339)))
+	}
+	pe.sendNotice(ctx, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,63 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mau.fi/util/glob"
+	"maunium.net/go/mautrix/id"
+)
+
+const maxGlobTestMatches = 25
+
+// testGlob compiles the given glob pattern and reports which tracked users and servers it matches.
+func (pe *PolicyEvaluator) testGlob(ctx context.Context, pattern string) {
+	compiled := glob.CompileSimple(pattern)
+	if compiled == nil {
+		regexGlob, err := glob.CompileRegex(glob.Simplify(pattern))
+		if err != nil {
+			pe.sendNotice(ctx, "Invalid glob pattern `%s`: %v", pattern, err)
+			return
+		}
+		compiled = regexGlob
+	}
+	pe.protectedRoomsLock.RLock()
+	var users []id.UserID
+	for userID := range pe.protectedRoomMembers {
+		if compiled.Match(string(userID)) {
+			users = append(users, userID)
+		}
+	}
+	pe.protectedRoomsLock.RUnlock()
+	slices.Sort(users)
+	servers := pe.findKnownServers(compiled)
+	userLines := make([]string, 0, min(len(users), maxGlobTestMatches))
+	for _, userID := range users[:min(len(users), maxGlobTestMatches)] {
+		userLines = append(userLines, fmt.Sprintf("* [%s](%s)", userID, userID.URI().MatrixToURL()))
+	}
+	serverLines := make([]string, 0, min(len(servers), maxGlobTestMatches))
+	for _, server := range servers[:min(len(servers), maxGlobTestMatches)] {
+		serverLines = append(serverLines, fmt.Sprintf("* `%s`", server))
+	}
+	sections := []string{fmt.Sprintf("Pattern `%s` matches %s and %s", pattern,
+		pluralize(len(users), "tracked user"), pluralize(len(servers), "known server"))}
+	if _, isLiteral := compiled.(glob.ExactGlob); isLiteral {
+		sections[0] += " (the pattern has no wildcards, so it only matches exactly)"
+	}
+	if len(userLines) > 0 {
+		sections = append(sections, fmt.Sprintf("**Users:**\n\n%s%s", strings.Join(userLines, "\n"), truncatedMatchesSuffix(len(users))))
+	}
+	if len(serverLines) > 0 {
+		sections = append(sections, fmt.Sprintf("**Servers:**\n\n%s%s", strings.Join(serverLines, "\n"), truncatedMatchesSuffix(len(servers))))
+	}
+	pe.sendNotice(ctx, strings.Join(sections, "\n\n"))
+}
+
+func truncatedMatchesSuffix(total int) string {
+	if total <= maxGlobTestMatches {
+		return ""
+	}
+	return fmt.Sprintf("\n* ...and %d more", total-maxGlobTestMatches)
+}
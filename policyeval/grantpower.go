@@ -0,0 +1,181 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/database"
+)
+
+func (pe *PolicyEvaluator) grantPower(ctx context.Context, evt *event.Event, args []string) {
+	args, durationStr, hasDuration := extractValueFlag(args, "--for")
+	if len(args) < 2 {
+		pe.sendNotice(ctx, "Usage: `!grant-power <user ID> <level> [--for <duration>]`")
+		return
+	}
+	userID := id.UserID(args[0])
+	if _, _, err := userID.ParseAndValidate(); err != nil {
+		pe.sendNotice(ctx, "Invalid user ID %q: %v", args[0], err)
+		return
+	}
+	level, err := strconv.Atoi(args[1])
+	if err != nil {
+		pe.sendNotice(ctx, "Invalid power level %q", args[1])
+		return
+	}
+	var duration time.Duration
+	if hasDuration {
+		duration, err = time.ParseDuration(durationStr)
+		if err != nil || duration <= 0 {
+			pe.sendNotice(ctx, "Invalid duration %q", durationStr)
+			return
+		}
+	}
+	rooms := pe.GetProtectedRooms()
+	if len(rooms) == 0 {
+		pe.sendNotice(ctx, "There are no protected rooms")
+		return
+	}
+	until := "permanently"
+	if duration > 0 {
+		until = fmt.Sprintf("for %s", duration)
+	}
+	pe.requestConfirmation(ctx, fmt.Sprintf(
+		"Set the power level of [%s](%s) to %d in %s %s?",
+		userID, userID.URI().MatrixToURL(), level, pluralize(len(rooms), "protected room"), until,
+	), func(ctx context.Context) {
+		pe.doGrantPower(ctx, evt, userID, level, duration, rooms)
+	})
+}
+
+func (pe *PolicyEvaluator) doGrantPower(ctx context.Context, evt *event.Event, userID id.UserID, level int, duration time.Duration, rooms []id.RoomID) {
+	var granted int
+	var skipped []string
+	for _, roomID := range rooms {
+		roomLink := fmt.Sprintf("[%s](%s)", roomID, roomID.URI().MatrixToURL())
+		var powerLevels event.PowerLevelsEventContent
+		err := pe.Bot.StateEvent(ctx, roomID, event.StatePowerLevels, "", &powerLevels)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("* %s: failed to get power levels: %v", roomLink, err))
+			continue
+		}
+		ownLevel := powerLevels.GetUserLevel(pe.Bot.UserID)
+		prevLevel := powerLevels.GetUserLevel(userID)
+		if prevLevel == level {
+			continue
+		} else if ownLevel < powerLevels.GetEventLevel(event.StatePowerLevels) || level > ownLevel || prevLevel >= ownLevel {
+			skipped = append(skipped, fmt.Sprintf("* %s: the bot doesn't have enough power (have %d)", roomLink, ownLevel))
+			continue
+		} else if pe.DryRun {
+			granted++
+			continue
+		}
+		powerLevels.SetUserLevel(userID, level)
+		_, err = pe.Bot.SendStateEvent(ctx, roomID, event.StatePowerLevels, "", &powerLevels)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("* %s: failed to send power levels: %v", roomLink, err))
+			continue
+		}
+		granted++
+		if duration > 0 {
+			err = pe.DB.PowerGrant.Put(ctx, &database.PowerGrant{
+				ManagementRoom: pe.ManagementRoom,
+				UserID:         userID,
+				RoomID:         roomID,
+				PrevLevel:      prevLevel,
+				GrantedLevel:   level,
+				CreatedBy:      evt.Sender,
+				ExpiresAt:      time.Now().Add(duration),
+			})
+			if err != nil {
+				zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to save power grant")
+				skipped = append(skipped, fmt.Sprintf("* %s: power level was changed, but saving it for reverting failed: %v", roomLink, err))
+			}
+		}
+	}
+	if duration > 0 {
+		pe.wakeScheduleLoop()
+	}
+	var message string
+	if pe.DryRun {
+		message = fmt.Sprintf("Would set the power level of [%s](%s) to %d in %s, but dry run mode is enabled",
+			userID, userID.URI().MatrixToURL(), level, pluralize(granted, "room"))
+	} else {
+		message = fmt.Sprintf("Set the power level of [%s](%s) to %d in %s", userID, userID.URI().MatrixToURL(), level, pluralize(granted, "room"))
+	}
+	if duration > 0 && !pe.DryRun {
+		message += fmt.Sprintf(", will revert at %s", time.Now().Add(duration).UTC().Format(time.RFC3339))
+	}
+	if len(skipped) > 0 {
+		message += fmt.Sprintf("\n\nSkipped %s:\n\n%s", pluralize(len(skipped), "room"), strings.Join(skipped, "\n"))
+	}
+	pe.sendNotice(ctx, message)
+}
+
+// revertExpiredPowerGrants restores the previous power levels of all grants whose duration has passed
+// and returns the time until the next one expires. Levels that were changed by someone else in the meantime are left alone.
+func (pe *PolicyEvaluator) revertExpiredPowerGrants(ctx context.Context) time.Duration {
+	sleep := maxScheduleSleep
+	grants, err := pe.DB.PowerGrant.GetAll(ctx, pe.ManagementRoom)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get power grants")
+	}
+	reverted := make(map[id.UserID]int)
+	var problems []string
+	for _, grant := range grants {
+		if untilDue := time.Until(grant.ExpiresAt); untilDue > 0 {
+			sleep = min(sleep, untilDue)
+			break
+		}
+		if msg := pe.revertPowerGrant(ctx, grant); msg != "" {
+			problems = append(problems, msg)
+		} else {
+			reverted[grant.UserID]++
+		}
+	}
+	for userID, count := range reverted {
+		pe.sendNotice(ctx, "Reverted temporary power level of [%s](%s) in %s", userID, userID.URI().MatrixToURL(), pluralize(count, "room"))
+	}
+	if len(problems) > 0 {
+		pe.sendNotice(ctx, "Some temporary power levels weren't reverted:\n\n%s", strings.Join(problems, "\n"))
+	}
+	return sleep
+}
+
+// revertPowerGrant restores the previous power level of a single grant. The grant is only removed from the database
+// once it's reverted or deliberately left alone, so that failed reverts are retried the next time the scheduler runs.
+func (pe *PolicyEvaluator) revertPowerGrant(ctx context.Context, grant *database.PowerGrant) string {
+	prefix := fmt.Sprintf("* [%s](%s) in [%s](%s)", grant.UserID, grant.UserID.URI().MatrixToURL(), grant.RoomID, grant.RoomID.URI().MatrixToURL())
+	var powerLevels event.PowerLevelsEventContent
+	err := pe.Bot.StateEvent(ctx, grant.RoomID, event.StatePowerLevels, "", &powerLevels)
+	if err != nil {
+		return fmt.Sprintf("%s: failed to get power levels, will retry later: %v", prefix, err)
+	} else if current := powerLevels.GetUserLevel(grant.UserID); current != grant.GrantedLevel {
+		pe.deletePowerGrant(ctx, grant)
+		return fmt.Sprintf("%s: power level was changed to %d after granting, not reverting", prefix, current)
+	}
+	powerLevels.SetUserLevel(grant.UserID, grant.PrevLevel)
+	_, err = pe.Bot.SendStateEvent(ctx, grant.RoomID, event.StatePowerLevels, "", &powerLevels)
+	if err != nil {
+		return fmt.Sprintf("%s: failed to send power levels, will retry later: %v", prefix, err)
+	}
+	pe.deletePowerGrant(ctx, grant)
+	return ""
+}
+
+func (pe *PolicyEvaluator) deletePowerGrant(ctx context.Context, grant *database.PowerGrant) {
+	_, err := pe.DB.PowerGrant.Delete(ctx, pe.ManagementRoom, grant.UserID, grant.RoomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).
+			Stringer("user_id", grant.UserID).
+			Stringer("room_id", grant.RoomID).
+			Msg("Failed to delete power grant")
+	}
+}
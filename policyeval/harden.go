@@ -0,0 +1,180 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+
+	"go.mau.fi/meowlnir/database"
+)
+
+// hardenPresets maps the presets accepted by `!rooms harden` to the join rule they apply.
+var hardenPresets = map[string]event.JoinRule{
+	"invite": event.JoinRuleInvite,
+	"knock":  event.JoinRuleKnock,
+}
+
+func (pe *PolicyEvaluator) hardenRoom(ctx context.Context, evt *event.Event, args []string) {
+	args, durationStr, _ := extractValueFlag(args, "--for")
+	if len(args) < 2 || durationStr == "" {
+		pe.sendNotice(ctx, "Usage: `!rooms harden <room ID or alias> <invite|knock> --for <duration>`")
+		return
+	}
+	joinRule, ok := hardenPresets[strings.ToLower(args[1])]
+	if !ok {
+		pe.sendNotice(ctx, "Unknown preset `%s`, must be `invite` or `knock`", args[1])
+		return
+	}
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil || duration <= 0 {
+		pe.sendNotice(ctx, "Invalid duration %q", durationStr)
+		return
+	}
+	roomID, err := pe.resolveRoom(ctx, args[0])
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", args[0], err)
+		return
+	} else if !pe.IsProtectedRoom(roomID) {
+		pe.sendNotice(ctx, "[%s](%s) is not a protected room", roomID, roomID.URI().MatrixToURL())
+		return
+	}
+	existing, err := pe.DB.HardenedRoom.Get(ctx, pe.ManagementRoom, roomID)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to check if room is already hardened: %v", err)
+		return
+	} else if existing != nil {
+		pe.sendNotice(ctx, "[%s](%s) is already hardened until %s, use `!rooms unharden` first",
+			roomID, roomID.URI().MatrixToURL(), existing.ExpiresAt.UTC().Format(time.RFC3339))
+		return
+	}
+	var prevJoinRules map[string]any
+	err = pe.Bot.StateEvent(ctx, roomID, event.StateJoinRules, "", &prevJoinRules)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get current join rules: %v", err)
+		return
+	}
+	hardened := &database.HardenedRoom{
+		ManagementRoom: pe.ManagementRoom,
+		RoomID:         roomID,
+		Preset:         strings.ToLower(args[1]),
+		PrevJoinRules:  prevJoinRules,
+		CreatedBy:      evt.Sender,
+		ExpiresAt:      time.Now().Add(duration),
+		CreatedAt:      time.Now(),
+	}
+	// Save the previous join rules before changing anything, so they can be restored even if Meowlnir restarts
+	err = pe.DB.HardenedRoom.Put(ctx, hardened)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to save hardened room")
+		pe.sendNotice(ctx, "Failed to save previous join rules: %v", err)
+		return
+	}
+	_, err = pe.Bot.SendStateEvent(ctx, roomID, event.StateJoinRules, "", &event.JoinRulesEventContent{JoinRule: joinRule})
+	if err != nil {
+		_, _ = pe.DB.HardenedRoom.Delete(ctx, pe.ManagementRoom, roomID)
+		pe.sendNotice(ctx, "Failed to change join rules of [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err)
+		return
+	}
+	pe.wakeScheduleLoop()
+	pe.sendNotice(ctx, "Hardened [%s](%s) with the `%s` preset until %s",
+		roomID, roomID.URI().MatrixToURL(), hardened.Preset, hardened.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+func (pe *PolicyEvaluator) unhardenRoom(ctx context.Context, evt *event.Event, roomIDOrAlias string) {
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	}
+	hardened, err := pe.DB.HardenedRoom.Get(ctx, pe.ManagementRoom, roomID)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get hardened room: %v", err)
+	} else if hardened == nil {
+		pe.sendNotice(ctx, "[%s](%s) is not hardened", roomID, roomID.URI().MatrixToURL())
+	} else if pe.revertHardening(ctx, hardened) {
+		pe.sendSuccessReaction(ctx, evt.ID)
+	}
+}
+
+// revertHardening restores the join rules that a room had before it was hardened.
+// If the join rules were changed by someone else in the meantime, they're left alone.
+// The hardened room is only removed from the database once the join rules are restored or deliberately
+// left alone, so that failed reverts are retried the next time the scheduler runs.
+func (pe *PolicyEvaluator) revertHardening(ctx context.Context, hardened *database.HardenedRoom) bool {
+	roomLink := fmt.Sprintf("[%s](%s)", hardened.RoomID, hardened.RoomID.URI().MatrixToURL())
+	var current event.JoinRulesEventContent
+	err := pe.Bot.StateEvent(ctx, hardened.RoomID, event.StateJoinRules, "", &current)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get current join rules of %s, will retry reverting hardening later: %v", roomLink, err)
+		return false
+	} else if current.JoinRule != hardenPresets[hardened.Preset] {
+		if !pe.deleteHardenedRoom(ctx, hardened) {
+			return false
+		}
+		pe.sendNotice(ctx, "Join rules of %s were changed after hardening, not reverting them", roomLink)
+		return true
+	}
+	_, err = pe.Bot.SendStateEvent(ctx, hardened.RoomID, event.StateJoinRules, "", hardened.PrevJoinRules)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to restore join rules of %s, will retry later: %v", roomLink, err)
+		return false
+	} else if !pe.deleteHardenedRoom(ctx, hardened) {
+		return false
+	}
+	pe.sendNotice(ctx, "Restored previous join rules of %s (hardened by [%s](%s))",
+		roomLink, hardened.CreatedBy, hardened.CreatedBy.URI().MatrixToURL())
+	return true
+}
+
+// deleteHardenedRoom removes a reverted hardening from the database. It returns false if the row was already
+// deleted by a concurrent revert, in which case the caller shouldn't report the revert again.
+func (pe *PolicyEvaluator) deleteHardenedRoom(ctx context.Context, hardened *database.HardenedRoom) bool {
+	deleted, err := pe.DB.HardenedRoom.Delete(ctx, pe.ManagementRoom, hardened.RoomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", hardened.RoomID).Msg("Failed to delete hardened room")
+		pe.sendNotice(ctx, "Reverted hardening of [%s](%s), but failed to delete it from the database: %v",
+			hardened.RoomID, hardened.RoomID.URI().MatrixToURL(), err)
+		return false
+	}
+	return deleted
+}
+
+// revertExpiredHardenings reverts all hardened rooms whose duration has passed
+// and returns the time until the next one expires.
+func (pe *PolicyEvaluator) revertExpiredHardenings(ctx context.Context) time.Duration {
+	sleep := maxScheduleSleep
+	hardenedRooms, err := pe.DB.HardenedRoom.GetAll(ctx, pe.ManagementRoom)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get hardened rooms")
+	}
+	for _, hardened := range hardenedRooms {
+		if untilDue := time.Until(hardened.ExpiresAt); untilDue > 0 {
+			sleep = min(sleep, untilDue)
+			break
+		}
+		pe.revertHardening(ctx, hardened)
+	}
+	return sleep
+}
+
+func (pe *PolicyEvaluator) sendHardenedRooms(ctx context.Context) {
+	hardenedRooms, err := pe.DB.HardenedRoom.GetAll(ctx, pe.ManagementRoom)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get hardened rooms: %v", err)
+		return
+	} else if len(hardenedRooms) == 0 {
+		pe.sendNotice(ctx, "No hardened rooms")
+		return
+	}
+	lines := make([]string, len(hardenedRooms))
+	for i, hardened := range hardenedRooms {
+		lines[i] = fmt.Sprintf("* [%s](%s): `%s` until %s by [%s](%s)",
+			hardened.RoomID, hardened.RoomID.URI().MatrixToURL(), hardened.Preset,
+			hardened.ExpiresAt.UTC().Format(time.RFC3339), hardened.CreatedBy, hardened.CreatedBy.URI().MatrixToURL())
+	}
+	pe.sendNotice(ctx, "Hardened rooms:\n\n%s", strings.Join(lines, "\n"))
+}
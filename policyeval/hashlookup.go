@@ -0,0 +1,69 @@
+package policyeval
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/id"
+)
+
+func decodeBase64Hash(input string) (hash [32]byte, ok bool) {
+	input = strings.TrimRight(input, "=")
+	decoded, err := base64.RawStdEncoding.DecodeString(input)
+	if err != nil {
+		decoded, err = base64.RawURLEncoding.DecodeString(input)
+	}
+	if err != nil || len(decoded) != len(hash) {
+		return
+	}
+	return [32]byte(decoded), true
+}
+
+func (pe *PolicyEvaluator) lookupRoomHash(ctx context.Context, input string) {
+	hash, ok := decodeBase64Hash(input)
+	if !ok {
+		pe.sendNotice(ctx, "`%s` is not a valid base64-encoded SHA-256 hash", input)
+		return
+	}
+	candidates := append(pe.GetProtectedRooms(), pe.ManagementRoom)
+	pe.watchedListsLock.RLock()
+	for roomID := range pe.watchedListsMap {
+		candidates = append(candidates, roomID)
+	}
+	pe.watchedListsLock.RUnlock()
+	joinedRooms, err := pe.Bot.JoinedRooms(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get joined rooms for hash lookup")
+	} else {
+		candidates = append(candidates, joinedRooms.JoinedRooms...)
+	}
+	for _, roomID := range candidates {
+		if sha256.Sum256([]byte(roomID)) == hash {
+			pe.sendNotice(ctx, "Hash `%s` matches [%s](%s)", input, roomID, roomID.URI().MatrixToURL())
+			return
+		}
+	}
+	if pe.SynapseDB == nil {
+		pe.sendNotice(ctx, "No known room matches hash `%s`", input)
+		return
+	}
+	var match id.RoomID
+	err = pe.SynapseDB.IterateRooms(ctx, func(roomID id.RoomID) (bool, error) {
+		if sha256.Sum256([]byte(roomID)) == hash {
+			match = roomID
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to iterate rooms in Synapse database")
+		pe.sendNotice(ctx, "Failed to search Synapse database for hash `%s`: %v", input, err)
+	} else if match != "" {
+		pe.sendNotice(ctx, "Hash `%s` matches [%s](%s) (found in Synapse database)", input, match, match.URI().MatrixToURL())
+	} else {
+		pe.sendNotice(ctx, "No room on this server matches hash `%s`", input)
+	}
+}
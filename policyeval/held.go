@@ -0,0 +1,225 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// heldPolicyKey identifies the policy state event that is being held. Holds are tracked per evaluator
+// rather than by flagging the policy in the store, because the store is shared by all management rooms.
+type heldPolicyKey struct {
+	RoomID   id.RoomID
+	Type     string
+	StateKey string
+}
+
+func heldKeyOf(policy *policylist.Policy) heldPolicyKey {
+	return heldPolicyKey{RoomID: policy.RoomID, Type: policy.Type.Type, StateKey: policy.StateKey}
+}
+
+// holdPolicy stops the given policy from being applied by this evaluator until releaseHeldPolicy is called.
+func (pe *PolicyEvaluator) holdPolicy(ctx context.Context, policy *policylist.Policy, reason database.HoldReason) {
+	key := heldKeyOf(policy)
+	pe.heldPoliciesLock.Lock()
+	pe.heldPolicies[key] = struct{}{}
+	pe.heldPoliciesLock.Unlock()
+	err := pe.DB.HeldPolicy.Put(ctx, &database.HeldPolicy{
+		ManagementRoom: pe.ManagementRoom,
+		PolicyList:     key.RoomID,
+		PolicyType:     key.Type,
+		StateKey:       key.StateKey,
+		Reason:         reason,
+		HeldAt:         time.Now(),
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Any("policy", policy).Msg("Failed to save held policy")
+	}
+}
+
+// releaseHeldPolicy removes the hold on the given policy and returns whether it was held.
+func (pe *PolicyEvaluator) releaseHeldPolicy(ctx context.Context, policy *policylist.Policy) bool {
+	key := heldKeyOf(policy)
+	pe.heldPoliciesLock.Lock()
+	_, wasHeld := pe.heldPolicies[key]
+	delete(pe.heldPolicies, key)
+	pe.heldPoliciesLock.Unlock()
+	if !wasHeld {
+		return false
+	}
+	err := pe.DB.HeldPolicy.Delete(ctx, pe.ManagementRoom, key.RoomID, key.Type, key.StateKey)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Any("policy", policy).Msg("Failed to delete held policy")
+	}
+	return true
+}
+
+func (pe *PolicyEvaluator) isPolicyHeld(policy *policylist.Policy) bool {
+	pe.heldPoliciesLock.Lock()
+	_, held := pe.heldPolicies[heldKeyOf(policy)]
+	pe.heldPoliciesLock.Unlock()
+	return held
+}
+
+// withoutHeld removes policies held by this evaluator from the given match.
+func (pe *PolicyEvaluator) withoutHeld(match policylist.Match) policylist.Match {
+	if len(match) == 0 {
+		return match
+	}
+	pe.heldPoliciesLock.Lock()
+	defer pe.heldPoliciesLock.Unlock()
+	if len(pe.heldPolicies) == 0 {
+		return match
+	}
+	return slices.DeleteFunc(slices.Clone(match), func(policy *policylist.Policy) bool {
+		_, held := pe.heldPolicies[heldKeyOf(policy)]
+		return held
+	})
+}
+
+// matchUser is like Store.MatchUser, but excludes policies that are held for approval.
+func (pe *PolicyEvaluator) matchUser(lists []id.RoomID, userID id.UserID) policylist.Match {
+	return pe.withoutHeld(pe.Store.MatchUser(lists, userID))
+}
+
+// matchServer is like Store.MatchServer, but excludes policies that are held for approval.
+func (pe *PolicyEvaluator) matchServer(lists []id.RoomID, server string) policylist.Match {
+	return pe.withoutHeld(pe.Store.MatchServer(lists, server))
+}
+
+// findPolicyByKey returns the policy that is currently in the state slot identified by the given key.
+func (pe *PolicyEvaluator) findPolicyByKey(key heldPolicyKey) *policylist.Policy {
+	room := pe.Store.GetRoom(key.RoomID)
+	if room == nil {
+		return nil
+	}
+	for _, policy := range room.Policies() {
+		if policy.Type.Type == key.Type && policy.StateKey == key.StateKey {
+			return policy
+		}
+	}
+	return nil
+}
+
+// applyReleasedPolicies evaluates policies whose hold was just released, unless their list is configured
+// to not apply policies, and publishes them upstream like policies that weren't held.
+func (pe *PolicyEvaluator) applyReleasedPolicies(ctx context.Context, policies []*policylist.Policy) {
+	for _, policy := range policies {
+		meta := pe.GetWatchedListMeta(policy.RoomID)
+		if meta == nil {
+			continue
+		}
+		if !meta.DontApply {
+			pe.EvaluateAddedRule(ctx, policy)
+		}
+		if !policy.Ignored {
+			pe.publishUpstream(ctx, meta, policy)
+		}
+	}
+}
+
+// loadHeldPolicies restores policy holds from the database after a restart
+// and asks admins to approve them again, as the previous confirmation requests are lost.
+func (pe *PolicyEvaluator) loadHeldPolicies(ctx context.Context) {
+	heldPolicies, err := pe.DB.HeldPolicy.GetAll(ctx, pe.ManagementRoom)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get held policies")
+		pe.sendNotice(ctx, "Failed to load policies held for approval: %v", err)
+		return
+	}
+	var policies []*policylist.Policy
+	var lines []string
+	pe.heldPoliciesLock.Lock()
+	for _, held := range heldPolicies {
+		key := heldPolicyKey{RoomID: held.PolicyList, Type: held.PolicyType, StateKey: held.StateKey}
+		policy := pe.findPolicyByKey(key)
+		if policy == nil {
+			// The policy was removed while Meowlnir was offline, so there's nothing to approve anymore.
+			err = pe.DB.HeldPolicy.Delete(ctx, pe.ManagementRoom, key.RoomID, key.Type, key.StateKey)
+			if err != nil {
+				zerolog.Ctx(ctx).Err(err).Any("held_policy", held).Msg("Failed to delete stale held policy")
+			}
+			continue
+		}
+		pe.heldPolicies[key] = struct{}{}
+		policies = append(policies, policy)
+		if len(lines) < 20 {
+			lines = append(lines, fmt.Sprintf("* `%s` in %s (%s)", policy.Entity, pe.getListName(policy.RoomID), held.Reason))
+		}
+	}
+	pe.heldPoliciesLock.Unlock()
+	if len(policies) == 0 {
+		return
+	}
+	if len(policies) > len(lines) {
+		lines = append(lines, fmt.Sprintf("* ...and %d more", len(policies)-len(lines)))
+	}
+	message := fmt.Sprintf("⚠️ %d policies were held for approval before restarting and won't be applied until approved:\n\n%s",
+		len(policies), strings.Join(lines, "\n"))
+	go pe.requestConfirmation(context.WithoutCancel(ctx), message, func(ctx context.Context) {
+		var released []*policylist.Policy
+		for _, policy := range policies {
+			if pe.releaseHeldPolicy(ctx, policy) {
+				released = append(released, policy)
+			}
+		}
+		pe.sendNotice(ctx, "Approved %d held policies, applying them now", len(released))
+		pe.applyReleasedPolicies(ctx, released)
+	})
+}
+
+func (pe *PolicyEvaluator) handleHeldCommand(ctx context.Context, args []string) {
+	const usage = "Usage: `!held [approve|drop <list shortcode>]`"
+	if len(args) == 0 {
+		pe.sendHeldPolicies(ctx)
+		return
+	} else if len(args) < 2 {
+		pe.sendNotice(ctx, usage)
+		return
+	}
+	list := pe.FindListByShortcode(args[1])
+	if list == nil {
+		pe.sendListNotFound(ctx, args[1])
+		return
+	}
+	switch strings.ToLower(args[0]) {
+	case "approve":
+		pe.releasePolicyBurst(ctx, list)
+	case "drop":
+		pe.dropPolicyBurst(ctx, list)
+	default:
+		pe.sendNotice(ctx, "Unknown subcommand `%s`. %s", args[0], usage)
+	}
+}
+
+func (pe *PolicyEvaluator) sendHeldPolicies(ctx context.Context) {
+	pe.heldPoliciesLock.Lock()
+	heldCount := len(pe.heldPolicies)
+	pe.heldPoliciesLock.Unlock()
+	var lines []string
+	pe.policyBurstsLock.Lock()
+	for roomID, burst := range pe.policyBursts {
+		if burst.active && len(burst.held) > 0 {
+			lines = append(lines, fmt.Sprintf("* %s: burst with %d held policies", pe.getListName(roomID), len(burst.held)))
+		}
+	}
+	pe.policyBurstsLock.Unlock()
+	if heldCount == 0 {
+		pe.sendNotice(ctx, "No policies are held for approval")
+		return
+	}
+	message := fmt.Sprintf("%d policies are held for approval", heldCount)
+	if len(lines) > 0 {
+		slices.Sort(lines)
+		message += "\n\n" + strings.Join(lines, "\n")
+	}
+	pe.sendNotice(ctx, message)
+}
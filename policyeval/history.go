@@ -0,0 +1,87 @@
+package policyeval
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/database"
+)
+
+type historyEntry struct {
+	At   time.Time
+	Line string
+}
+
+func (pe *PolicyEvaluator) getListName(roomID id.RoomID) string {
+	if meta := pe.GetWatchedListMeta(roomID); meta != nil {
+		return meta.Name
+	}
+	return fmt.Sprintf("[%s](%s)", roomID, roomID.URI().MatrixToURL())
+}
+
+// describeActionCause returns a human-readable description of the rule that caused the given action.
+func (pe *PolicyEvaluator) describeActionCause(ta *database.TakenAction) string {
+	if ta.ActionType == database.TakenActionTypeProtectionBan {
+		return fmt.Sprintf("%s protection", ta.RuleEntity)
+	}
+	return fmt.Sprintf("`%s` `%s` in %s", ta.Action, ta.RuleEntity, pe.getListName(ta.PolicyList))
+}
+
+// sendHistory sends a chronological list of policy changes and actions taken for the given entity.
+func (pe *PolicyEvaluator) sendHistory(ctx context.Context, entity string) {
+	policyChanges, err := pe.DB.PolicyHistory.GetAllByEntity(ctx, entity)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get policy history: %v", err)
+		return
+	}
+	var entries []historyEntry
+	for _, change := range policyChanges {
+		entries = append(entries, historyEntry{
+			At: change.ChangedAt,
+			Line: fmt.Sprintf("[%s](%s) %s a `%s` %s policy in %s: %s",
+				change.Sender, change.Sender.URI().MatrixToURL(), change.Change,
+				change.Recommendation, change.EntityType, pe.getListName(change.PolicyList), change.Reason),
+		})
+	}
+	if strings.HasPrefix(entity, "@") {
+		for _, actionType := range []database.TakenActionType{database.TakenActionTypeBanOrUnban, database.TakenActionTypeContentBan, database.TakenActionTypeProtectionBan, database.TakenActionTypeKick} {
+			actions, err := pe.DB.TakenAction.GetAllByTargetUser(ctx, id.UserID(entity), actionType)
+			if err != nil {
+				pe.sendNotice(ctx, "Failed to get taken actions: %v", err)
+				return
+			}
+			for _, ta := range actions {
+				verb := "Banned in"
+				if actionType == database.TakenActionTypeKick {
+					verb = "Kicked from"
+				} else if ta.Action == event.PolicyRecommendationUnban {
+					verb = "Unbanned in"
+				}
+				entries = append(entries, historyEntry{
+					At: ta.TakenAt,
+					Line: fmt.Sprintf("%s [%s](%s) due to %s",
+						verb, ta.InRoomID, ta.InRoomID.URI().MatrixToURL(), pe.describeActionCause(ta)),
+				})
+			}
+		}
+	}
+	if len(entries) == 0 {
+		pe.sendNotice(ctx, "No history found for `%s`", entity)
+		return
+	}
+	slices.SortStableFunc(entries, func(a, b historyEntry) int {
+		return cmp.Compare(a.At.UnixMilli(), b.At.UnixMilli())
+	})
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = fmt.Sprintf("* %s: %s", entry.At.UTC().Format(time.DateTime), entry.Line)
+	}
+	pe.sendNotice(ctx, "History of `%s`:\n\n%s", entity, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,88 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+type importableBan struct {
+	UserID id.UserID
+	Reason string
+}
+
+// importBans creates user ban policies in the given list for members who are banned in the given room,
+// so that bans made before the room was protected apply to all protected rooms.
+func (pe *PolicyEvaluator) importBans(ctx context.Context, evt *event.Event, roomIDOrAlias string, list *config.WatchedPolicyList) {
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	}
+	resp, err := pe.Bot.Members(ctx, roomID, mautrix.ReqMembers{Membership: event.MembershipBan})
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get banned members in [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err)
+		return
+	}
+	lists := pe.GetWatchedLists()
+	var bans []importableBan
+	var alreadyCovered int
+	for _, memberEvt := range resp.Chunk {
+		userID := id.UserID(memberEvt.GetStateKey())
+		// Bans sent by the bot itself come from policies already
+		if memberEvt.Sender == pe.Bot.UserID || pe.Store.MatchUser(lists, userID).Recommendations().BanOrUnban != nil {
+			alreadyCovered++
+			continue
+		}
+		bans = append(bans, importableBan{UserID: userID, Reason: memberEvt.Content.AsMember().Reason})
+	}
+	if len(bans) == 0 {
+		pe.sendNotice(ctx, "No bans to import from [%s](%s) (%d already covered by policies)",
+			roomID, roomID.URI().MatrixToURL(), alreadyCovered)
+		return
+	}
+	pe.requestListWriteConfirmation(ctx, fmt.Sprintf(
+		"Found %d bans in [%s](%s) that aren't covered by policies (%d already covered). Import them into `%s`?",
+		len(bans), roomID, roomID.URI().MatrixToURL(), alreadyCovered, list.Shortcode,
+	), list, func(ctx context.Context) {
+		pe.doImportBans(ctx, evt, roomID, list, bans)
+	})
+}
+
+func (pe *PolicyEvaluator) doImportBans(ctx context.Context, evt *event.Event, roomID id.RoomID, list *config.WatchedPolicyList, bans []importableBan) {
+	var failed int
+	for _, ban := range bans {
+		policy := &event.ModPolicyContent{
+			Entity:         string(ban.UserID),
+			Reason:         ban.Reason,
+			Recommendation: event.PolicyRecommendationBan,
+		}
+		_, err := pe.SendPolicy(ctx, list.RoomID, policylist.EntityTypeUser, "", policy, nil)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).
+				Stringer("user_id", ban.UserID).
+				Stringer("policy_list", list.RoomID).
+				Msg("Failed to send imported ban policy")
+			failed++
+		}
+	}
+	zerolog.Ctx(ctx).Info().
+		Stringer("room_id", roomID).
+		Stringer("policy_list", list.RoomID).
+		Int("ban_count", len(bans)).
+		Int("failed_count", failed).
+		Msg("Imported room bans into policy list")
+	if failed > 0 {
+		pe.sendNotice(ctx, "Imported %d bans from [%s](%s) into `%s`, %d failed (see logs for details)",
+			len(bans)-failed, roomID, roomID.URI().MatrixToURL(), list.Shortcode, failed)
+	} else {
+		pe.sendSuccessReaction(ctx, evt.ID)
+	}
+}
@@ -0,0 +1,91 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/bot"
+)
+
+type inviteRate struct {
+	timestamps []time.Time
+	notified   bool
+}
+
+// checkInviteRate counts invites sent by the sender of the given invite event across protected rooms
+// and notifies the management room (and optionally revokes the invite) if the sender is over the limit.
+func (pe *PolicyEvaluator) checkInviteRate(ctx context.Context, evt *event.Event) {
+	cfg := pe.Config.InviteRate
+	if cfg.Threshold <= 0 || !pe.IsProtectedRoom(evt.RoomID) || pe.isExemptFromProtections(ctx, evt.RoomID, evt.Sender) {
+		return
+	}
+	pe.inviteRatesLock.Lock()
+	now := time.Now()
+	cutoff := now.Add(-cfg.Window)
+	for inviter, rate := range pe.inviteRates {
+		if len(rate.timestamps) == 0 || rate.timestamps[len(rate.timestamps)-1].Before(cutoff) {
+			delete(pe.inviteRates, inviter)
+		}
+	}
+	rate, ok := pe.inviteRates[evt.Sender]
+	if !ok {
+		rate = &inviteRate{}
+		pe.inviteRates[evt.Sender] = rate
+	}
+	rate.timestamps = slices.DeleteFunc(rate.timestamps, func(ts time.Time) bool {
+		return ts.Before(cutoff)
+	})
+	rate.timestamps = append(rate.timestamps, now)
+	count := len(rate.timestamps)
+	overLimit := count > cfg.Threshold
+	notify := overLimit && !rate.notified
+	rate.notified = overLimit
+	pe.inviteRatesLock.Unlock()
+	if !overLimit {
+		return
+	}
+	invitee := id.UserID(evt.GetStateKey())
+	log := zerolog.Ctx(ctx).With().
+		Stringer("inviter", evt.Sender).
+		Stringer("invitee", invitee).
+		Stringer("room_id", evt.RoomID).
+		Int("invite_count", count).
+		Logger()
+	if notify {
+		log.Warn().Msg("User exceeded invite rate limit")
+		message := fmt.Sprintf("⚠️ @room [%s](%s) sent %d invites to protected rooms in the last %s.",
+			evt.Sender, evt.Sender.URI().MatrixToURL(), count, cfg.Window)
+		if cfg.Revoke {
+			message += " Invites over the limit will be revoked."
+		}
+		pe.Bot.SendNoticeOpts(ctx, pe.ManagementRoom, message, &bot.SendNoticeOpts{Mentions: &event.Mentions{Room: true}})
+	}
+	if !cfg.Revoke || pe.IsPaused() {
+		return
+	}
+	var err error
+	if !pe.DryRun {
+		_, err = pe.Bot.KickUser(ctx, evt.RoomID, &mautrix.ReqKickUser{
+			UserID: invitee,
+			Reason: "Invite rate limit exceeded",
+		})
+	}
+	if err != nil {
+		log.Err(err).Msg("Failed to revoke invite")
+		pe.sendNotice(ctx, "Failed to revoke invite of [%s](%s) to [%s](%s) from [%s](%s): %v",
+			invitee, invitee.URI().MatrixToURL(), evt.RoomID, evt.RoomID.URI().MatrixToURL(),
+			evt.Sender, evt.Sender.URI().MatrixToURL(), err)
+		return
+	}
+	log.Info().Msg("Revoked invite from rate limited user")
+	pe.sendNotice(ctx, "Revoked invite of [%s](%s) to [%s](%s) from [%s](%s)",
+		invitee, invitee.URI().MatrixToURL(), evt.RoomID, evt.RoomID.URI().MatrixToURL(),
+		evt.Sender, evt.Sender.URI().MatrixToURL())
+}
@@ -0,0 +1,48 @@
+package policyeval
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+)
+
+// enforceBanOnJoin immediately bans a user who joined a protected room while matching a ban policy.
+// The joined room is handled before anything else (including updating the member cache), so that
+// the user has as little time as possible to send messages. Returns true if the user was banned.
+func (pe *PolicyEvaluator) enforceBanOnJoin(ctx context.Context, evt *event.Event, userID id.UserID) bool {
+	if pe.DryRun || pe.IsPaused() || !pe.IsProtectedRoom(evt.RoomID) {
+		return false
+	}
+	recs := pe.matchUser(pe.GetWatchedLists(), userID).Recommendations()
+	if !recs.IsBan() {
+		return false
+	}
+	rec := recs.BanOrUnban
+	listMeta := pe.GetWatchedListMeta(rec.RoomID)
+	if listMeta != nil && listMeta.ActionOverride == config.ActionOverrideKick {
+		// Kick overrides go through the normal evaluation path
+		return false
+	} else if pe.isUnbanUnderReview(userID, evt.RoomID) || pe.checkManualUnbanCooldown(ctx, userID, evt.RoomID, rec) {
+		return false
+	}
+	start := time.Now()
+	if !pe.ApplyBan(ctx, userID, evt.RoomID, rec, pe.getPublicBanReason(rec)) {
+		return false
+	}
+	pe.recordListHit(ctx, rec.RoomID)
+	zerolog.Ctx(ctx).Info().
+		Stringer("user_id", userID).
+		Stringer("room_id", evt.RoomID).
+		Dur("ban_duration", time.Since(start)).
+		Dur("join_to_ban_latency", time.Since(time.UnixMilli(evt.Timestamp))).
+		Msg("Banned user on join")
+	if shouldAutoRedact(rec, listMeta) {
+		go pe.RedactUser(context.WithoutCancel(ctx), userID, rec.Reason, true)
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/bot"
+	"go.mau.fi/meowlnir/database"
+)
+
+type joinPartCycles struct {
+	timestamps []time.Time
+	notified   bool
+}
+
+// checkJoinPartFlood counts how many times the user of the given leave event has left a protected room right after
+// joining it, and notifies the management room (and optionally bans the user) if they're cycling too fast.
+func (pe *PolicyEvaluator) checkJoinPartFlood(ctx context.Context, evt *event.Event) {
+	cfg := pe.Config.JoinPartFlood
+	userID := id.UserID(evt.GetStateKey())
+	if cfg.Threshold <= 0 || evt.Sender != userID || evt.Unsigned.PrevContent == nil ||
+		!pe.IsProtectedRoom(evt.RoomID) || pe.isExemptFromProtections(ctx, evt.RoomID, userID) {
+		return
+	}
+	_ = evt.Unsigned.PrevContent.ParseRaw(evt.Type)
+	if prev := evt.Unsigned.PrevContent.AsMember(); prev.Membership != event.MembershipJoin {
+		return
+	}
+	pe.joinPartCyclesLock.Lock()
+	now := time.Now()
+	cutoff := now.Add(-cfg.Window)
+	for user, cycles := range pe.joinPartCycles {
+		if len(cycles.timestamps) == 0 || cycles.timestamps[len(cycles.timestamps)-1].Before(cutoff) {
+			delete(pe.joinPartCycles, user)
+		}
+	}
+	cycles, ok := pe.joinPartCycles[userID]
+	if !ok {
+		cycles = &joinPartCycles{}
+		pe.joinPartCycles[userID] = cycles
+	}
+	cycles.timestamps = slices.DeleteFunc(cycles.timestamps, func(ts time.Time) bool {
+		return ts.Before(cutoff)
+	})
+	cycles.timestamps = append(cycles.timestamps, now)
+	count := len(cycles.timestamps)
+	overLimit := count > cfg.Threshold
+	notify := overLimit && !cycles.notified
+	cycles.notified = overLimit
+	pe.joinPartCyclesLock.Unlock()
+	if !overLimit {
+		return
+	}
+	log := zerolog.Ctx(ctx).With().
+		Stringer("user_id", userID).
+		Stringer("room_id", evt.RoomID).
+		Int("cycle_count", count).
+		Logger()
+	if notify {
+		log.Warn().Msg("User exceeded join/leave cycle limit")
+		message := fmt.Sprintf("⚠️ @room [%s](%s) joined and left protected rooms %d times in the last %s.",
+			userID, userID.URI().MatrixToURL(), count, cfg.Window)
+		if cfg.Ban {
+			message += " They will be banned from rooms they leave."
+		}
+		pe.Bot.SendNoticeOpts(ctx, pe.ManagementRoom, message, &bot.SendNoticeOpts{Mentions: &event.Mentions{Room: true}})
+	}
+	if !cfg.Ban || pe.IsPaused() {
+		return
+	}
+	if pe.DryRun {
+		log.Info().Msg("Not banning join/leave flooder in dry run mode")
+		pe.sendNotice(ctx, "Would ban [%s](%s) in [%s](%s) for join/leave flooding (%d cycles), but dry run mode is enabled",
+			userID, userID.URI().MatrixToURL(), evt.RoomID, evt.RoomID.URI().MatrixToURL(), count)
+		return
+	}
+	_, err := pe.Bot.BanUser(ctx, evt.RoomID, &mautrix.ReqBanUser{
+		UserID: userID,
+		Reason: "Join/leave flooding",
+	})
+	if err != nil {
+		log.Err(err).Msg("Failed to ban join/leave flooder")
+		pe.recordFailure("ban", userID, evt.RoomID, err)
+		pe.sendNotice(ctx, "Failed to ban [%s](%s) in [%s](%s) for join/leave flooding: %v",
+			userID, userID.URI().MatrixToURL(), evt.RoomID, evt.RoomID.URI().MatrixToURL(), err)
+		return
+	}
+	log.Info().Msg("Banned join/leave flooder")
+	ta := &database.TakenAction{
+		TargetUser: userID,
+		InRoomID:   evt.RoomID,
+		ActionType: database.TakenActionTypeProtectionBan,
+		RuleEntity: "join/leave flooding",
+		Action:     event.PolicyRecommendationBan,
+		TakenAt:    time.Now(),
+	}
+	if err = pe.DB.TakenAction.Put(ctx, ta); err != nil {
+		log.Err(err).Msg("Failed to save taken action for join/leave flooder")
+	}
+	pe.sendNotice(ctx, "Banned [%s](%s) in [%s](%s) for join/leave flooding (%d cycles)",
+		userID, userID.URI().MatrixToURL(), evt.RoomID, evt.RoomID.URI().MatrixToURL(), count)
+}
@@ -0,0 +1,67 @@
+package policyeval
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// sendJSON sends the given data to the management room as a JSON code block for use in scripts.
+func (pe *PolicyEvaluator) sendJSON(ctx context.Context, data any) {
+	output, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to marshal command output")
+		pe.sendNotice(ctx, "Failed to marshal output: %v", err)
+		return
+	}
+	pe.sendNotice(ctx, "```json\n%s\n```", output)
+}
+
+type policyJSON struct {
+	PolicyList     id.RoomID                  `json:"policy_list"`
+	EntityType     policylist.EntityType      `json:"entity_type"`
+	Entity         string                     `json:"entity"`
+	Recommendation event.PolicyRecommendation `json:"recommendation"`
+	Reason         string                     `json:"reason"`
+	ReasonCode     string                     `json:"reason_code,omitempty"`
+	Sender         id.UserID                  `json:"sender"`
+	EventID        id.EventID                 `json:"event_id"`
+	Timestamp      int64                      `json:"timestamp"`
+	Ignored        bool                       `json:"ignored,omitempty"`
+}
+
+type matchJSON struct {
+	// Duration is the time it took to match the user in seconds.
+	Duration float64      `json:"duration"`
+	Policies []policyJSON `json:"policies"`
+}
+
+type searchJSON struct {
+	// Total is the number of matching policies, which may be higher than the number of policies included.
+	Total    int          `json:"total"`
+	Policies []policyJSON `json:"policies"`
+}
+
+func policiesToJSON(policies []*policylist.Policy) []policyJSON {
+	output := make([]policyJSON, len(policies))
+	for i, policy := range policies {
+		output[i] = policyJSON{
+			PolicyList:     policy.RoomID,
+			EntityType:     policy.EntityType,
+			Entity:         policy.Entity,
+			Recommendation: policy.Recommendation,
+			Reason:         policy.Reason,
+			ReasonCode:     policy.ReasonCode,
+			Sender:         policy.Sender,
+			EventID:        policy.ID,
+			Timestamp:      policy.Timestamp,
+			Ignored:        policy.Ignored,
+		}
+	}
+	return output
+}
@@ -0,0 +1,84 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// handleKnock denies knocks from users whose server is banned by a watched list.
+// Knocking users who match user ban policies are banned by the normal evaluation.
+func (pe *PolicyEvaluator) handleKnock(ctx context.Context, evt *event.Event) {
+	userID := id.UserID(evt.GetStateKey())
+	lists := pe.GetWatchedLists()
+	if pe.matchUser(lists, userID).Recommendations().BanOrUnban != nil {
+		// User policies take priority and are handled by the normal evaluation
+		return
+	}
+	recs := pe.matchServer(lists, userID.Homeserver()).Recommendations()
+	if !recs.IsBan() {
+		return
+	}
+	rec := recs.BanOrUnban
+	log := zerolog.Ctx(ctx).With().
+		Stringer("user_id", userID).
+		Stringer("room_id", evt.RoomID).
+		Str("policy_entity", rec.Entity).
+		Logger()
+	if pe.IsPaused() {
+		log.Info().Msg("Not denying knock from banned server as automated moderation is paused")
+		return
+	}
+	var err error
+	if !pe.DryRun {
+		_, err = pe.Bot.KickUser(ctx, evt.RoomID, &mautrix.ReqKickUser{
+			UserID: userID,
+			Reason: filterReason(rec.Reason),
+		})
+	}
+	if err != nil {
+		log.Err(err).Msg("Failed to deny knock from banned server")
+		pe.sendNotice(ctx, "Failed to deny knock from [%s](%s) in [%s](%s): %v",
+			userID, userID.URI().MatrixToURL(), evt.RoomID, evt.RoomID.URI().MatrixToURL(), err)
+		return
+	}
+	log.Info().Msg("Denied knock from banned server")
+	pe.sendNotice(ctx, "Denied knock from [%s](%s) in [%s](%s): server matches `%s` for %s",
+		userID, userID.URI().MatrixToURL(), evt.RoomID, evt.RoomID.URI().MatrixToURL(), rec.Entity, rec.Reason)
+}
+
+func (pe *PolicyEvaluator) sendKnockRequests(ctx context.Context) {
+	var lines []string
+	lists := pe.GetWatchedLists()
+	for _, roomID := range pe.GetProtectedRooms() {
+		resp, err := pe.Bot.Members(ctx, roomID, mautrix.ReqMembers{Membership: event.MembershipKnock})
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to get knocking members")
+			lines = append(lines, fmt.Sprintf("* Failed to get knocks in [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err))
+			continue
+		}
+		for _, evt := range resp.Chunk {
+			userID := id.UserID(evt.GetStateKey())
+			line := fmt.Sprintf("* [%s](%s) in [%s](%s)", userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL())
+			if reason := evt.Content.AsMember().Reason; reason != "" {
+				line += fmt.Sprintf(": %s", reason)
+			}
+			userMatch := pe.matchUser(lists, userID)
+			serverMatch := pe.matchServer(lists, userID.Homeserver())
+			if len(userMatch) > 0 || len(serverMatch) > 0 {
+				line += fmt.Sprintf(" (⚠️ matches %d user and %d server policies)", len(userMatch), len(serverMatch))
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		pe.sendNotice(ctx, "No pending knocks in protected rooms")
+		return
+	}
+	pe.sendNotice(ctx, "Pending knocks in protected rooms:\n\n%s", strings.Join(lines, "\n"))
+}
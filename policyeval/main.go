@@ -3,8 +3,11 @@ package policyeval
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -24,18 +27,74 @@ type PolicyEvaluator struct {
 	Store     *policylist.Store
 	SynapseDB *synapsedb.SynapseDB
 	DB        *database.Database
+	Config    *config.MeowlnirConfig
 	DryRun    bool
 
 	ManagementRoom id.RoomID
 	Admins         *exsync.Set[id.UserID]
 
-	watchedListsMap  map[id.RoomID]*config.WatchedPolicyList
-	watchedListsList []id.RoomID
-	watchedListsLock sync.RWMutex
+	watchedListsMap     map[id.RoomID]*config.WatchedPolicyList
+	watchedListsList    []id.RoomID
+	ambiguousShortcodes map[string]struct{}
+	watchedListsLock    sync.RWMutex
 
 	configLock sync.Mutex
 
+	resyncLoopStarted   atomic.Bool
+	scheduleLoopStarted atomic.Bool
+	scheduleWakeup      chan struct{}
+	lastLoad            atomic.Pointer[loadStats]
+
+	reports     map[id.EventID]*reportAggregate
+	reportsLock sync.Mutex
+
+	pendingConfirmations     map[id.EventID]*pendingConfirmation
+	pendingConfirmationsLock sync.Mutex
+
+	commandRateLimits     map[id.UserID]*commandRateLimit
+	untrustedFeedbackSent map[id.UserID]time.Time
+	commandRateLimitsLock sync.Mutex
+
+	inviteRates      map[id.UserID]*inviteRate
+	inviteRatesLock  sync.Mutex
+	policyBursts     map[id.RoomID]*policyBurst
+	policyBurstsLock sync.Mutex
+
+	joinPartCycles     map[id.UserID]*joinPartCycles
+	joinPartCyclesLock sync.Mutex
+
+	heldPolicies     map[heldPolicyKey]struct{}
+	heldPoliciesLock sync.Mutex
+
+	warnedContentPolicies *exsync.Set[id.EventID]
+
+	powerLevelCache     map[id.RoomID]*event.PowerLevelsEventContent
+	powerLevelCacheLock sync.Mutex
+
+	failures     []actionFailure
+	failuresLock sync.Mutex
+
+	unbanReviews     map[id.UserID]*unbanReview
+	manualUnbans     map[userRoomPair]*manualUnban
+	unbanReviewsLock sync.Mutex
+
+	undoStack     []*undoableAction
+	undoStackLock sync.Mutex
+
+	checkedSenders     map[userRoomPair]time.Time
+	checkedSendersLock sync.Mutex
+
+	noticeTemplates      map[string]*template.Template
+	noticeTemplateErrors []string
+
+	paused        bool
+	pausedMatches map[id.UserID]string
+	resumeTimer   *time.Timer
+	pauseLock     sync.Mutex
+
 	claimProtected       func(roomID id.RoomID, eval *PolicyEvaluator, claim bool) *PolicyEvaluator
+	lastTransaction      func() time.Time
+	version              VersionInfo
 	protectedRooms       map[id.RoomID]struct{}
 	wantToProtect        map[id.RoomID]struct{}
 	protectedRoomMembers map[id.UserID][]id.RoomID
@@ -49,23 +108,43 @@ func NewPolicyEvaluator(
 	db *database.Database,
 	synapseDB *synapsedb.SynapseDB,
 	claimProtected func(roomID id.RoomID, eval *PolicyEvaluator, claim bool) *PolicyEvaluator,
-	dryRun bool,
+	lastTransaction func() time.Time,
+	version VersionInfo,
+	cfg *config.MeowlnirConfig,
 ) *PolicyEvaluator {
 	pe := &PolicyEvaluator{
-		Bot:                  bot,
-		DB:                   db,
-		SynapseDB:            synapseDB,
-		Store:                store,
-		ManagementRoom:       managementRoom,
-		Admins:               exsync.NewSet[id.UserID](),
-		protectedRoomMembers: make(map[id.UserID][]id.RoomID),
-		watchedListsMap:      make(map[id.RoomID]*config.WatchedPolicyList),
-		protectedRooms:       make(map[id.RoomID]struct{}),
-		wantToProtect:        make(map[id.RoomID]struct{}),
-		claimProtected:       claimProtected,
-
-		DryRun: dryRun,
+		Bot:                   bot,
+		DB:                    db,
+		SynapseDB:             synapseDB,
+		Store:                 store,
+		ManagementRoom:        managementRoom,
+		Admins:                exsync.NewSet[id.UserID](),
+		protectedRoomMembers:  make(map[id.UserID][]id.RoomID),
+		watchedListsMap:       make(map[id.RoomID]*config.WatchedPolicyList),
+		protectedRooms:        make(map[id.RoomID]struct{}),
+		wantToProtect:         make(map[id.RoomID]struct{}),
+		claimProtected:        claimProtected,
+		lastTransaction:       lastTransaction,
+		version:               version,
+		reports:               make(map[id.EventID]*reportAggregate),
+		pendingConfirmations:  make(map[id.EventID]*pendingConfirmation),
+		commandRateLimits:     make(map[id.UserID]*commandRateLimit),
+		untrustedFeedbackSent: make(map[id.UserID]time.Time),
+		unbanReviews:          make(map[id.UserID]*unbanReview),
+		manualUnbans:          make(map[userRoomPair]*manualUnban),
+		checkedSenders:        make(map[userRoomPair]time.Time),
+		policyBursts:          make(map[id.RoomID]*policyBurst),
+		heldPolicies:          make(map[heldPolicyKey]struct{}),
+		warnedContentPolicies: exsync.NewSet[id.EventID](),
+		inviteRates:           make(map[id.UserID]*inviteRate),
+		joinPartCycles:        make(map[id.UserID]*joinPartCycles),
+		powerLevelCache:       make(map[id.RoomID]*event.PowerLevelsEventContent),
+		scheduleWakeup:        make(chan struct{}, 1),
+
+		Config: cfg,
+		DryRun: cfg.DryRun,
 	}
+	pe.noticeTemplates, pe.noticeTemplateErrors = parseNoticeTemplates(cfg.NoticeTemplates)
 	return pe
 }
 
@@ -87,11 +166,15 @@ func (pe *PolicyEvaluator) Load(ctx context.Context) {
 		pe.sendNotice(ctx, "Failed to load initial state: %v", err)
 	} else {
 		zerolog.Ctx(ctx).Info().Msg("Loaded initial state")
+		pe.startResyncLoop(ctx)
+		pe.startScheduleLoop(ctx)
 	}
 }
 
 func (pe *PolicyEvaluator) tryLoad(ctx context.Context) error {
-	pe.sendNotice(ctx, "Loading initial state...")
+	if !pe.Config.QuietStartup {
+		pe.sendNotice(ctx, "Loading initial state...")
+	}
 	pe.configLock.Lock()
 	defer pe.configLock.Unlock()
 	start := time.Now()
@@ -99,51 +182,64 @@ func (pe *PolicyEvaluator) tryLoad(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get management room state: %w", err)
 	}
-	var errors []string
+	errors := slices.Clone(pe.noticeTemplateErrors)
 	if evt, ok := state[event.StatePowerLevels][""]; !ok {
 		return fmt.Errorf("no power level event found in management room")
 	} else if errMsg := pe.handlePowerLevels(evt); errMsg != "" {
 		errors = append(errors, errMsg)
 	}
+	pe.DryRun = pe.Config.DryRun
+	if evt, ok := state[config.StateEvaluatorConfig][""]; ok {
+		pe.handleEvaluatorConfig(ctx, evt)
+	}
 	if evt, ok := state[config.StateWatchedLists][""]; !ok {
 		zerolog.Ctx(ctx).Info().Msg("No watched lists event found in management room")
 	} else {
 		_, errorMsgs := pe.handleWatchedLists(ctx, evt, true)
 		errors = append(errors, errorMsgs...)
 	}
+	pe.loadHeldPolicies(ctx)
 	if evt, ok := state[config.StateProtectedRooms][""]; !ok {
 		zerolog.Ctx(ctx).Info().Msg("No protected rooms event found in management room")
 	} else {
 		_, errorMsgs := pe.handleProtectedRooms(ctx, evt, true)
 		errors = append(errors, errorMsgs...)
 	}
+	pe.reviewManualUnbans(ctx)
 	initDuration := time.Since(start)
 	start = time.Now()
 	pe.EvaluateAll(ctx)
 	evalDuration := time.Since(start)
-	pe.protectedRoomsLock.Lock()
-	userCount := len(pe.protectedRoomMembers)
-	var joinedUserCount int
-	for _, rooms := range pe.protectedRoomMembers {
-		if len(rooms) > 0 {
-			joinedUserCount++
-		}
-	}
-	protectedRoomsCount := len(pe.protectedRooms)
-	pe.protectedRoomsLock.Unlock()
+	pe.lastLoad.Store(&loadStats{
+		LoadedAt:     time.Now(),
+		InitDuration: initDuration,
+		EvalDuration: evalDuration,
+		ErrorCount:   len(errors),
+	})
 	if len(errors) > 0 {
 		pe.sendNotice(ctx,
-			"Errors occurred during initialization:\n\n%s\n\nProtecting %d rooms with %d users (%d all time) using %d lists.",
-			strings.Join(errors, "\n"), protectedRoomsCount, joinedUserCount, userCount, len(pe.GetWatchedLists()))
-	} else {
+			"Errors occurred during initialization:\n\n%s\n\n%s",
+			strings.Join(errors, "\n"), pe.protectionSummary())
+	} else if !pe.Config.QuietStartup {
 		pe.sendNotice(ctx,
-			"Initialization completed successfully (took %s to load data and %s to evaluate rules). "+
-				"Protecting %d rooms with %d users (%d all time) using %d lists.",
-			initDuration, evalDuration, protectedRoomsCount, joinedUserCount, userCount, len(pe.GetWatchedLists()))
+			"Initialization completed successfully (took %s to load data and %s to evaluate rules). %s",
+			initDuration, evalDuration, pe.protectionSummary())
 	}
 	return nil
 }
 
+func (pe *PolicyEvaluator) handleEvaluatorConfig(ctx context.Context, evt *event.Event) {
+	content, ok := evt.Content.Parsed.(*config.EvaluatorConfigEventContent)
+	if !ok {
+		zerolog.Ctx(ctx).Warn().Msg("Failed to parse evaluator config event")
+		return
+	}
+	if content.DryRun != nil {
+		pe.DryRun = *content.DryRun
+		zerolog.Ctx(ctx).Info().Bool("dry_run", pe.DryRun).Msg("Overriding dry run mode for management room")
+	}
+}
+
 func (pe *PolicyEvaluator) handlePowerLevels(evt *event.Event) string {
 	content, ok := evt.Content.Parsed.(*event.PowerLevelsEventContent)
 	if !ok {
@@ -0,0 +1,109 @@
+package policyeval
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/database"
+)
+
+// getCachedMembers returns the members of the given room from the database in the same format as the
+// members endpoint, or nil if the member cache is disabled or doesn't have the room.
+func (pe *PolicyEvaluator) getCachedMembers(ctx context.Context, roomID id.RoomID) *mautrix.RespMembers {
+	if !pe.Config.MemberCache {
+		return nil
+	}
+	cached, err := pe.DB.MemberCache.GetRoom(ctx, pe.ManagementRoom, roomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to get cached members")
+		return nil
+	} else if len(cached) == 0 {
+		return nil
+	}
+	members := &mautrix.RespMembers{Chunk: make([]*event.Event, len(cached))}
+	for i, member := range cached {
+		stateKey := string(member.UserID)
+		members.Chunk[i] = &event.Event{
+			StateKey: &stateKey,
+			Type:     event.StateMember,
+			RoomID:   roomID,
+			Content:  event.Content{Parsed: &event.MemberEventContent{Membership: member.Membership}},
+		}
+	}
+	return members
+}
+
+func (pe *PolicyEvaluator) saveMemberCache(ctx context.Context, roomID id.RoomID, evts []*event.Event) {
+	if !pe.Config.MemberCache {
+		return
+	}
+	members := make([]database.CachedMember, len(evts))
+	for i, evt := range evts {
+		members[i] = database.CachedMember{
+			UserID:     id.UserID(evt.GetStateKey()),
+			Membership: evt.Content.AsMember().Membership,
+		}
+	}
+	err := pe.DB.MemberCache.ReplaceRoom(ctx, pe.ManagementRoom, roomID, members)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to save member cache")
+	}
+}
+
+func (pe *PolicyEvaluator) cacheMembership(ctx context.Context, roomID id.RoomID, userID id.UserID, membership event.Membership) {
+	if !pe.Config.MemberCache || !pe.IsProtectedRoom(roomID) {
+		return
+	}
+	err := pe.DB.MemberCache.Put(ctx, pe.ManagementRoom, roomID, userID, membership)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Stringer("user_id", userID).Msg("Failed to update member cache")
+	}
+}
+
+func (pe *PolicyEvaluator) deleteMemberCache(ctx context.Context, roomID id.RoomID) {
+	if !pe.Config.MemberCache {
+		return
+	}
+	err := pe.DB.MemberCache.DeleteRoom(ctx, pe.ManagementRoom, roomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to delete member cache")
+	}
+}
+
+// reconcileMemberCache fetches the live member list of a room that was protected using cached members,
+// applies any membership changes that were missed and evaluates users who weren't in the cache.
+func (pe *PolicyEvaluator) reconcileMemberCache(ctx context.Context, roomID id.RoomID, cached *mautrix.RespMembers) {
+	log := zerolog.Ctx(ctx).With().Stringer("room_id", roomID).Logger()
+	members, err := pe.Bot.Members(ctx, roomID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get room members to reconcile member cache")
+		return
+	}
+	live := make(map[id.UserID]struct{}, len(members.Chunk))
+	var newMembers []id.UserID
+	for _, evt := range members.Chunk {
+		userID := id.UserID(evt.GetStateKey())
+		live[userID] = struct{}{}
+		if pe.updateUser(userID, roomID, evt.Content.AsMember().Membership) {
+			newMembers = append(newMembers, userID)
+		}
+	}
+	for _, evt := range cached.Chunk {
+		userID := id.UserID(evt.GetStateKey())
+		if _, ok := live[userID]; !ok {
+			pe.updateUser(userID, roomID, event.MembershipLeave)
+		}
+	}
+	pe.saveMemberCache(ctx, roomID, members.Chunk)
+	log.Debug().
+		Int("member_count", len(members.Chunk)).
+		Int("new_member_count", len(newMembers)).
+		Msg("Reconciled member cache")
+	if len(newMembers) > 0 {
+		pe.EvaluateAllMembers(ctx, newMembers)
+	}
+}
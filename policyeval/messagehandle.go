@@ -18,11 +18,22 @@ func (pe *PolicyEvaluator) isMention(content *event.MessageEventContent) bool {
 		strings.Contains(content.FormattedBody, pe.Bot.UserID.String())
 }
 
+// HandleCustomContent checks events of custom types listed in content_event_types against content policies.
+func (pe *PolicyEvaluator) HandleCustomContent(ctx context.Context, evt *event.Event) {
+	if body, ok := evt.Content.Raw["body"].(string); ok {
+		pe.checkContentPolicies(ctx, evt, body)
+	}
+}
+
 func (pe *PolicyEvaluator) HandleMessage(ctx context.Context, evt *event.Event) {
 	content, ok := evt.Content.Parsed.(*event.MessageEventContent)
 	if !ok {
 		return
 	}
+	if pe.Config.EvaluateMessageSenders {
+		pe.evaluateMessageSender(ctx, evt)
+	}
+	pe.checkContentPolicies(ctx, evt, content.Body)
 	if pe.isMention(content) {
 		pe.Bot.SendNoticeOpts(
 			ctx, pe.ManagementRoom,
@@ -0,0 +1,100 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/id"
+)
+
+// Pause stops all automated actions until Resume is called or the given duration has passed.
+// A zero duration pauses indefinitely.
+func (pe *PolicyEvaluator) Pause(ctx context.Context, duration time.Duration) {
+	pe.pauseLock.Lock()
+	defer pe.pauseLock.Unlock()
+	if !pe.paused {
+		pe.pausedMatches = make(map[id.UserID]string)
+	}
+	pe.paused = true
+	if pe.resumeTimer != nil {
+		pe.resumeTimer.Stop()
+		pe.resumeTimer = nil
+	}
+	if duration > 0 {
+		ctx = context.WithoutCancel(ctx)
+		var timer *time.Timer
+		timer = time.AfterFunc(duration, func() {
+			pe.pauseLock.Lock()
+			if pe.resumeTimer != timer {
+				pe.pauseLock.Unlock()
+				return
+			}
+			pe.paused = false
+			pe.resumeTimer = nil
+			pe.pauseLock.Unlock()
+			zerolog.Ctx(ctx).Info().Msg("Automatically resuming automated moderation")
+			pe.sendNotice(ctx, "Pause expired, automated moderation has been resumed")
+			pe.ReportPausedMatches(ctx)
+		})
+		pe.resumeTimer = timer
+	}
+}
+
+// Resume cancels a previous Pause call. It returns false if automated actions weren't paused.
+func (pe *PolicyEvaluator) Resume() bool {
+	pe.pauseLock.Lock()
+	defer pe.pauseLock.Unlock()
+	if pe.resumeTimer != nil {
+		pe.resumeTimer.Stop()
+		pe.resumeTimer = nil
+	}
+	wasPaused := pe.paused
+	pe.paused = false
+	return wasPaused
+}
+
+// recordPausedMatch returns true if automated actions are paused, in which case the user
+// is remembered so that all skipped bans can be reported at once after resuming.
+func (pe *PolicyEvaluator) recordPausedMatch(userID id.UserID, reason string) bool {
+	pe.pauseLock.Lock()
+	defer pe.pauseLock.Unlock()
+	if pe.paused {
+		pe.pausedMatches[userID] = reason
+	}
+	return pe.paused
+}
+
+// ReportPausedMatches sends a summary of the bans that were skipped while automated actions were paused.
+func (pe *PolicyEvaluator) ReportPausedMatches(ctx context.Context) {
+	pe.pauseLock.Lock()
+	if pe.paused {
+		pe.pauseLock.Unlock()
+		return
+	}
+	matches := pe.pausedMatches
+	pe.pausedMatches = nil
+	pe.pauseLock.Unlock()
+	if len(matches) == 0 {
+		return
+	}
+	lines := make([]string, 0, min(len(matches), 20)+1)
+	for userID, reason := range matches {
+		if len(lines) >= 20 {
+			lines = append(lines, fmt.Sprintf("* ...and %d more", len(matches)-len(lines)))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("* [%s](%s) for %s", userID, userID.URI().MatrixToURL(), reason))
+	}
+	pe.sendNotice(ctx, "%s matched ban policies while automated moderation was paused and weren't banned:\n\n%s",
+		pluralize(len(matches), "user"), strings.Join(lines, "\n"))
+}
+
+// IsPaused returns whether automated actions are currently paused.
+func (pe *PolicyEvaluator) IsPaused() bool {
+	pe.pauseLock.Lock()
+	defer pe.pauseLock.Unlock()
+	return pe.paused
+}
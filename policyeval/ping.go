@@ -0,0 +1,26 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"maunium.net/go/mautrix/event"
+)
+
+func (pe *PolicyEvaluator) sendPong(ctx context.Context, evt *event.Event) {
+	receiveDelay := time.Since(time.UnixMilli(evt.Timestamp)).Round(time.Millisecond)
+	lastTxn := "no appservice transactions received since startup"
+	if ts := pe.lastTransaction(); !ts.IsZero() {
+		lastTxn = fmt.Sprintf("last appservice transaction received %s ago", time.Since(ts).Round(time.Millisecond))
+	}
+	state := "running"
+	if pe.IsPaused() {
+		state = "paused"
+	}
+	if pe.DryRun {
+		state += " in dry run mode"
+	}
+	pe.sendNotice(ctx, "Pong! Command arrived %s after it was sent. Automated moderation is %s, %s.",
+		receiveDelay, state, lastTxn)
+}
@@ -0,0 +1,79 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/util/glob"
+	"maunium.net/go/mautrix/event"
+
+	"go.mau.fi/meowlnir/bot"
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// isPolicyTooBroad checks whether the given ban policy matches a suspiciously large fraction
+// of the users or servers currently in protected rooms.
+func (pe *PolicyEvaluator) isPolicyTooBroad(policy *policylist.Policy) (matched, total int, tooBroad bool) {
+	threshold := pe.Config.PoisoningThreshold
+	if threshold <= 0 || policy.Recommendation != event.PolicyRecommendationBan {
+		return
+	} else if _, isLiteral := policy.Pattern.(glob.ExactGlob); isLiteral {
+		return
+	}
+	pe.protectedRoomsLock.RLock()
+	users := slices.Collect(maps.Keys(pe.protectedRoomMembers))
+	pe.protectedRoomsLock.RUnlock()
+	switch policy.EntityType {
+	case policylist.EntityTypeUser:
+		total = len(users)
+		for _, userID := range users {
+			if policy.Pattern.Match(string(userID)) {
+				matched++
+			}
+		}
+	case policylist.EntityTypeServer:
+		servers := make(map[string]struct{})
+		for _, userID := range users {
+			servers[userID.Homeserver()] = struct{}{}
+		}
+		total = len(servers)
+		for server := range servers {
+			if policy.Pattern.Match(server) {
+				matched++
+			}
+		}
+	default:
+		return
+	}
+	tooBroad = total > 0 && float64(matched)/float64(total) > threshold
+	return
+}
+
+// holdBroadPolicy holds the given policy and asks admins to approve it before it's applied.
+func (pe *PolicyEvaluator) holdBroadPolicy(ctx context.Context, listName string, policy *policylist.Policy, matched, total int) {
+	zerolog.Ctx(ctx).Warn().
+		Any("policy", policy).
+		Int("matched", matched).
+		Int("total", total).
+		Msg("Holding suspiciously broad policy for approval")
+	pe.holdPolicy(ctx, policy, database.HoldReasonTooBroad)
+	message := fmt.Sprintf(
+		"⚠️ @room [%s] [%s](%s) banned %ss matching `%s` for `%s`, which would affect %d out of %d tracked %ss. "+
+			"The rule has been ignored until an admin approves it.",
+		listName, policy.Sender, policy.Sender.URI().MatrixToURL(),
+		policy.EntityType, policy.Entity, policy.Reason, matched, total, policy.EntityType,
+	)
+	pe.requestConfirmationOpts(ctx, message, &bot.SendNoticeOpts{Mentions: &event.Mentions{Room: true}}, func(ctx context.Context) {
+		// Removing or replacing the policy also releases the hold, so this fails if the rule isn't present anymore.
+		if !pe.releaseHeldPolicy(ctx, policy) {
+			pe.sendNotice(ctx, "Rule for `%s` in %s is no longer held, not applying it", policy.Entity, listName)
+			return
+		}
+		pe.sendNotice(ctx, "Approved rule for `%s` in %s, applying it now", policy.Entity, listName)
+		pe.applyReleasedPolicies(ctx, []*policylist.Policy{policy})
+	})
+}
@@ -0,0 +1,67 @@
+package policyeval
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// getPowerLevels returns the power levels of the given room, using a cache that is updated
+// whenever a power level event is received in a protected room.
+func (pe *PolicyEvaluator) getPowerLevels(ctx context.Context, roomID id.RoomID) *event.PowerLevelsEventContent {
+	pe.powerLevelCacheLock.Lock()
+	cached, ok := pe.powerLevelCache[roomID]
+	pe.powerLevelCacheLock.Unlock()
+	if ok {
+		return cached
+	}
+	var powerLevels event.PowerLevelsEventContent
+	err := pe.Bot.StateEvent(ctx, roomID, event.StatePowerLevels, "", &powerLevels)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to get power levels")
+		return nil
+	} else if !pe.IsProtectedRoom(roomID) {
+		// Power level events are only received for protected rooms, so caching other rooms would leave stale data
+		return &powerLevels
+	}
+	pe.powerLevelCacheLock.Lock()
+	defer pe.powerLevelCacheLock.Unlock()
+	if cached, ok = pe.powerLevelCache[roomID]; ok {
+		// A power level event arrived while fetching, so it's newer than the fetched state
+		return cached
+	}
+	pe.powerLevelCache[roomID] = &powerLevels
+	return &powerLevels
+}
+
+func (pe *PolicyEvaluator) updatePowerLevelCache(roomID id.RoomID, powerLevels *event.PowerLevelsEventContent) {
+	pe.powerLevelCacheLock.Lock()
+	pe.powerLevelCache[roomID] = powerLevels
+	pe.powerLevelCacheLock.Unlock()
+}
+
+func (pe *PolicyEvaluator) deletePowerLevelCache(roomID id.RoomID) {
+	pe.powerLevelCacheLock.Lock()
+	delete(pe.powerLevelCache, roomID)
+	pe.powerLevelCacheLock.Unlock()
+}
+
+// isExemptFromProtections checks whether the given user should be skipped by protections in the given room,
+// i.e. whether they're an admin in the management room, or have at least protection_exempt_level in the room
+// (the power level required to kick users by default).
+func (pe *PolicyEvaluator) isExemptFromProtections(ctx context.Context, roomID id.RoomID, userID id.UserID) bool {
+	if userID == pe.Bot.UserID || pe.Admins.Has(userID) {
+		return true
+	}
+	powerLevels := pe.getPowerLevels(ctx, roomID)
+	if powerLevels == nil {
+		return false
+	}
+	threshold := powerLevels.Kick()
+	if pe.Config.ProtectionExemptLevel != nil {
+		threshold = *pe.Config.ProtectionExemptLevel
+	}
+	return powerLevels.GetUserLevel(userID) >= threshold
+}
@@ -0,0 +1,159 @@
+package policyeval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+)
+
+// getPreviewContent returns the JSON pasted after the subcommand, or the body of the message
+// that the command replies to if nothing was pasted.
+func (pe *PolicyEvaluator) getPreviewContent(ctx context.Context, evt *event.Event, subcommand string) (string, error) {
+	content := evt.Content.AsMessage()
+	_, pasted, _ := strings.Cut(content.Body, subcommand)
+	pasted = strings.TrimSpace(pasted)
+	if pasted == "" {
+		replyTo := content.RelatesTo.GetReplyTo()
+		if replyTo == "" {
+			return "", fmt.Errorf("no content pasted or replied to")
+		}
+		replyEvt, err := pe.fetchEvent(ctx, evt.RoomID, replyTo)
+		if err != nil {
+			return "", fmt.Errorf("failed to get replied-to event: %w", err)
+		}
+		if replyEvt.Type == event.EventEncrypted && pe.Bot.Mach != nil {
+			_ = replyEvt.Content.ParseRaw(replyEvt.Type)
+			replyEvt, err = pe.Bot.Mach.DecryptMegolmEvent(ctx, replyEvt)
+			if err != nil {
+				return "", fmt.Errorf("failed to decrypt replied-to event: %w", err)
+			}
+		}
+		_ = replyEvt.Content.ParseRaw(replyEvt.Type)
+		replyContent, ok := replyEvt.Content.Parsed.(*event.MessageEventContent)
+		if !ok {
+			return "", fmt.Errorf("replied-to event is not a message")
+		}
+		pasted = strings.TrimSpace(replyContent.Body)
+	}
+	pasted = strings.TrimPrefix(pasted, "```json")
+	pasted = strings.TrimPrefix(pasted, "```")
+	pasted = strings.TrimSuffix(pasted, "```")
+	return pasted, nil
+}
+
+func (pe *PolicyEvaluator) previewConfig(ctx context.Context, evt *event.Event, args []string) {
+	if len(args) < 1 || (args[0] != "watched-lists" && args[0] != "protected-rooms") {
+		pe.sendNotice(ctx, "Usage: `!preview-config <watched-lists|protected-rooms> [JSON]` (or reply to a message containing the JSON)")
+		return
+	}
+	rawContent, err := pe.getPreviewContent(ctx, evt, args[0])
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get proposed content: %v", err)
+		return
+	}
+	var output []string
+	if args[0] == "watched-lists" {
+		var content config.WatchedListsEventContent
+		if err = json.Unmarshal([]byte(rawContent), &content); err != nil {
+			pe.sendNotice(ctx, "Failed to parse proposed watched lists: %v", err)
+			return
+		}
+		output = pe.previewWatchedLists(&content)
+	} else {
+		var content config.ProtectedRoomsEventContent
+		if err = json.Unmarshal([]byte(rawContent), &content); err != nil {
+			pe.sendNotice(ctx, "Failed to parse proposed protected rooms: %v", err)
+			return
+		}
+		output = pe.previewProtectedRooms(ctx, &content)
+	}
+	if len(output) == 0 {
+		pe.sendNotice(ctx, "The proposed %s content wouldn't change anything", args[0])
+	} else {
+		pe.sendNotice(ctx, "Applying the proposed %s content would cause the following changes:\n\n%s", args[0], strings.Join(output, "\n"))
+	}
+}
+
+// previewWatchedLists computes the changes that handleWatchedLists would make for the given content without applying them.
+func (pe *PolicyEvaluator) previewWatchedLists(content *config.WatchedListsEventContent) (output []string) {
+	pe.watchedListsLock.RLock()
+	oldMap := pe.watchedListsMap
+	pe.watchedListsLock.RUnlock()
+	seen := make(map[id.RoomID]struct{}, len(content.Lists))
+	for _, list := range content.Lists {
+		link := fmt.Sprintf("[%s](%s)", list.Name, list.RoomID.URI().MatrixToURL())
+		if err := pe.checkWatchableRoom(list.RoomID); err != nil {
+			output = append(output, fmt.Sprintf("* Would not watch %s: %v", link, err))
+			continue
+		} else if _, duplicate := seen[list.RoomID]; duplicate {
+			output = append(output, fmt.Sprintf("* Duplicate watched list %s would be ignored", link))
+			continue
+		}
+		seen[list.RoomID] = struct{}{}
+		old, existed := oldMap[list.RoomID]
+		switch {
+		case !existed && list.DontApply:
+			output = append(output, fmt.Sprintf("* Would watch %s without applying it", link))
+		case !existed:
+			output = append(output, fmt.Sprintf("* Would subscribe to %s", link))
+		case old.DontApply && !list.DontApply:
+			output = append(output, fmt.Sprintf("* Would start applying %s", link))
+		case !old.DontApply && list.DontApply:
+			output = append(output, fmt.Sprintf("* Would stop applying %s", link))
+		case !reflect.DeepEqual(*old, list):
+			output = append(output, fmt.Sprintf("* Would change the settings of %s", link))
+		}
+		if !existed && !pe.Store.Contains(list.RoomID) {
+			output = append(output, fmt.Sprintf("  * The bot would need to fetch the state of %s, make sure it has joined the room", link))
+		}
+	}
+	for roomID, old := range oldMap {
+		if _, stillWatched := seen[roomID]; !stillWatched {
+			output = append(output, fmt.Sprintf("* Would unsubscribe from [%s](%s)", old.Name, roomID.URI().MatrixToURL()))
+		}
+	}
+	return
+}
+
+// previewProtectedRooms computes the changes that handleProtectedRooms would make for the given content without applying them.
+func (pe *PolicyEvaluator) previewProtectedRooms(ctx context.Context, content *config.ProtectedRoomsEventContent) (output []string) {
+	joinedRooms, err := pe.Bot.JoinedRooms(ctx)
+	if err != nil {
+		output = append(output, fmt.Sprintf("* Failed to get joined rooms: %v", err))
+	}
+	for _, roomID := range pe.GetProtectedRooms() {
+		if !slices.Contains(content.Rooms, roomID) {
+			output = append(output, fmt.Sprintf("* Would stop protecting [%s](%s)", roomID, roomID.URI().MatrixToURL()))
+		}
+	}
+	for _, roomID := range content.Rooms {
+		if pe.IsProtectedRoom(roomID) {
+			continue
+		}
+		link := fmt.Sprintf("[%s](%s)", roomID, roomID.URI().MatrixToURL())
+		if pe.IsRoomBlacklisted(ctx, roomID) {
+			output = append(output, fmt.Sprintf("* Would not protect %s: the room is blacklisted", link))
+			continue
+		} else if joinedRooms != nil && !slices.Contains(joinedRooms.JoinedRooms, roomID) {
+			output = append(output, fmt.Sprintf("* Would not protect %s: the bot is not in the room", link))
+			continue
+		}
+		output = append(output, fmt.Sprintf("* Would start protecting %s", link))
+		var powerLevels event.PowerLevelsEventContent
+		err = pe.Bot.StateEvent(ctx, roomID, event.StatePowerLevels, "", &powerLevels)
+		if err != nil {
+			output = append(output, fmt.Sprintf("  * Failed to get power levels: %v", err))
+		} else if ownLevel, minLevel := powerLevels.GetUserLevel(pe.Bot.UserID), max(powerLevels.Ban(), powerLevels.Redact()); ownLevel < minLevel {
+			output = append(output, fmt.Sprintf("  * The bot lacks sufficient power level (have %d, minimum %d)", ownLevel, minLevel))
+		}
+	}
+	return
+}
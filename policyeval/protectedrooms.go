@@ -2,12 +2,14 @@ package policyeval
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"maps"
 	"slices"
 	"strings"
 	"sync"
 
+	"github.com/rs/zerolog"
 	"maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
@@ -31,6 +33,7 @@ func (pe *PolicyEvaluator) IsProtectedRoom(roomID id.RoomID) bool {
 
 func (pe *PolicyEvaluator) HandleProtectedRoomPowerLevels(ctx context.Context, evt *event.Event) {
 	powerLevels := evt.Content.AsPowerLevels()
+	pe.updatePowerLevelCache(evt.RoomID, powerLevels)
 	ownLevel := powerLevels.GetUserLevel(pe.Bot.UserID)
 	minLevel := max(powerLevels.Ban(), powerLevels.Redact())
 	pe.protectedRoomsLock.RLock()
@@ -50,39 +53,73 @@ func (pe *PolicyEvaluator) HandleProtectedRoomPowerLevels(ctx context.Context, e
 }
 
 func (pe *PolicyEvaluator) tryProtectingRoom(ctx context.Context, joinedRooms *mautrix.RespJoinedRooms, roomID id.RoomID, doReeval bool) (*mautrix.RespMembers, string) {
+	members, errMsg, _ := pe.tryProtectingRoomCheckPower(ctx, joinedRooms, roomID, doReeval, nil)
+	if members != nil {
+		go pe.checkNewProtectedRoomEncryption(context.WithoutCancel(ctx), roomID)
+	}
+	return members, errMsg
+}
+
+// tryProtectingRoomCheckPower is like tryProtectingRoom, but if the failure was caused by the bot not having
+// a sufficient power level, it also returns a short description of the room, so that such failures can be
+// aggregated into one notice. If sem is set, reconciling the member cache counts against the same concurrency limit.
+func (pe *PolicyEvaluator) tryProtectingRoomCheckPower(
+	ctx context.Context, joinedRooms *mautrix.RespJoinedRooms, roomID id.RoomID, doReeval bool, sem chan struct{},
+) (*mautrix.RespMembers, string, string) {
+	if pe.IsRoomBlacklisted(ctx, roomID) {
+		return nil, fmt.Sprintf("* Room [%s](%s) is blacklisted", roomID, roomID.URI().MatrixToURL()), ""
+	} else if pe.IsWatchingList(roomID) {
+		// Watched lists are loaded before protected rooms, so this also catches rooms that are in both configs at startup
+		return nil, fmt.Sprintf("* Room [%s](%s) is a watched policy list, so it can't be protected", roomID, roomID.URI().MatrixToURL()), ""
+	}
 	if claimer := pe.claimProtected(roomID, pe, true); claimer != pe {
 		if claimer != nil && claimer.Bot.UserID == pe.Bot.UserID {
-			return nil, fmt.Sprintf("* Room [%s](%s) is already protected by [%s](%s)", roomID, roomID.URI().MatrixToURL(), claimer.ManagementRoom, claimer.ManagementRoom.URI().MatrixToURL())
+			return nil, fmt.Sprintf("* Room [%s](%s) is already protected by [%s](%s)", roomID, roomID.URI().MatrixToURL(), claimer.ManagementRoom, claimer.ManagementRoom.URI().MatrixToURL()), ""
 		} else {
-			return nil, fmt.Sprintf("* Room [%s](%s) is already protected by another bot", roomID, roomID.URI().MatrixToURL())
+			return nil, fmt.Sprintf("* Room [%s](%s) is already protected by another bot", roomID, roomID.URI().MatrixToURL()), ""
 		}
 	}
 	var err error
 	if joinedRooms == nil {
 		joinedRooms, err = pe.Bot.JoinedRooms(ctx)
 		if err != nil {
-			return nil, fmt.Sprintf("* Failed to get joined rooms: %v", err)
+			return nil, fmt.Sprintf("* Failed to get joined rooms: %v", err), ""
 		}
 	}
 	pe.markAsWantToProtect(roomID)
 	if !slices.Contains(joinedRooms.JoinedRooms, roomID) {
-		return nil, fmt.Sprintf("* Bot is not in protected room [%s](%s)", roomID, roomID.URI().MatrixToURL())
+		return nil, fmt.Sprintf("* Bot is not in protected room [%s](%s)", roomID, roomID.URI().MatrixToURL()), ""
 	}
 	var powerLevels event.PowerLevelsEventContent
 	err = pe.Bot.StateEvent(ctx, roomID, event.StatePowerLevels, "", &powerLevels)
 	if err != nil {
-		return nil, fmt.Sprintf("* Failed to get power levels for [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err)
+		return nil, fmt.Sprintf("* Failed to get power levels for [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err), ""
 	}
 	ownLevel := powerLevels.GetUserLevel(pe.Bot.UserID)
 	minLevel := max(powerLevels.Ban(), powerLevels.Redact())
 	if ownLevel < minLevel && !pe.DryRun {
-		return nil, fmt.Sprintf("* Bot does not have sufficient power level in [%s](%s) (have %d, minimum %d)", roomID, roomID.URI().MatrixToURL(), ownLevel, minLevel)
+		lowPower := fmt.Sprintf("[%s](%s) (have %d, minimum %d)", roomID, roomID.URI().MatrixToURL(), ownLevel, minLevel)
+		return nil, "* Bot does not have sufficient power level in " + lowPower, lowPower
 	}
-	members, err := pe.Bot.Members(ctx, roomID)
-	if err != nil {
-		return nil, fmt.Sprintf("* Failed to get room members for [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err)
+	members := pe.getCachedMembers(ctx, roomID)
+	fromCache := members != nil
+	if !fromCache {
+		if members, err = pe.Bot.Members(ctx, roomID); err != nil {
+			return nil, fmt.Sprintf("* Failed to get room members for [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err), ""
+		}
+		pe.saveMemberCache(ctx, roomID, members.Chunk)
 	}
 	pe.markAsProtectedRoom(roomID, members.Chunk)
+	if fromCache {
+		// The room must be marked as protected before reconciling, as membership updates for unprotected rooms are ignored
+		go func() {
+			if sem != nil {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+			pe.reconcileMemberCache(context.WithoutCancel(ctx), roomID, members)
+		}()
+	}
 	if doReeval {
 		memberIDs := make([]id.UserID, len(members.Chunk))
 		for i, member := range members.Chunk {
@@ -90,7 +127,7 @@ func (pe *PolicyEvaluator) tryProtectingRoom(ctx context.Context, joinedRooms *m
 		}
 		pe.EvaluateAllMembers(ctx, memberIDs)
 	}
-	return members, ""
+	return members, "", ""
 }
 
 func (pe *PolicyEvaluator) handleProtectedRooms(ctx context.Context, evt *event.Event, isInitial bool) (output, errors []string) {
@@ -98,36 +135,80 @@ func (pe *PolicyEvaluator) handleProtectedRooms(ctx context.Context, evt *event.
 	if !ok {
 		return nil, []string{"* Failed to parse protected rooms event"}
 	}
+	var removedRooms []id.RoomID
 	pe.protectedRoomsLock.Lock()
 	for roomID := range pe.protectedRooms {
 		if !slices.Contains(content.Rooms, roomID) {
 			delete(pe.protectedRooms, roomID)
 			pe.claimProtected(roomID, pe, false)
+			removedRooms = append(removedRooms, roomID)
 			output = append(output, fmt.Sprintf("* Stopped protecting room [%s](%s)", roomID, roomID.URI().MatrixToURL()))
 		}
 	}
 	pe.protectedRoomsLock.Unlock()
+	for _, roomID := range removedRooms {
+		pe.deleteMemberCache(ctx, roomID)
+		pe.deletePowerLevelCache(roomID)
+	}
 	joinedRooms, err := pe.Bot.JoinedRooms(ctx)
 	if err != nil {
 		return output, []string{"* Failed to get joined rooms: ", err.Error()}
 	}
+	newRooms := slices.DeleteFunc(slices.Clone(content.Rooms), pe.IsProtectedRoom)
+	var sem chan struct{}
+	reportProgress := false
+	if limit := pe.Config.MaxConcurrentProtects; limit > 0 && len(newRooms) > limit {
+		sem = make(chan struct{}, limit)
+		reportProgress = !isInitial || !pe.Config.QuietStartup
+		if reportProgress {
+			pe.sendNotice(ctx, "Protecting %d rooms, %d at a time", len(newRooms), limit)
+		}
+	}
 	var outLock sync.Mutex
+	var lowPowerRooms, unencryptedRooms []string
+	var completed int
 	reevalMembers := make(map[id.UserID]struct{})
 	var wg sync.WaitGroup
-	for _, roomID := range content.Rooms {
-		if pe.IsProtectedRoom(roomID) {
-			continue
-		}
-		wg.Add(1)
+	wg.Add(len(newRooms))
+	for _, roomID := range newRooms {
 		go func() {
 			defer wg.Done()
-			members, errMsg := pe.tryProtectingRoom(ctx, joinedRooms, roomID, false)
+			if sem != nil {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+			members, errMsg, lowPower := pe.tryProtectingRoomCheckPower(ctx, joinedRooms, roomID, false, sem)
+			var encryptionMsg string
+			var encrypted bool
+			if members != nil && pe.Config.RequireRoomEncryption {
+				encryptionMsg, encrypted = pe.checkRoomEncryption(ctx, roomID, pe.Config.EnableRoomEncryption)
+			}
 			outLock.Lock()
-			defer outLock.Unlock()
-			if errMsg != "" {
+			completed++
+			progress := completed
+			zerolog.Ctx(ctx).Debug().
+				Stringer("room_id", roomID).
+				Int("completed", completed).
+				Int("total", len(newRooms)).
+				Msg("Finished trying to protect room")
+			defer func() {
+				outLock.Unlock()
+				// Send the notice after unlocking to avoid blocking other rooms while it's being sent
+				if reportProgress && progress < len(newRooms) && progress%(len(newRooms)/4+1) == 0 {
+					pe.sendNotice(ctx, "Processed %d/%d rooms to protect", progress, len(newRooms))
+				}
+			}()
+			if lowPower != "" && isInitial {
+				lowPowerRooms = append(lowPowerRooms, "  * "+lowPower)
+			} else if errMsg != "" {
 				errors = append(errors, errMsg)
 			}
-			if !isInitial {
+			if encryptionMsg != "" && !encrypted {
+				unencryptedRooms = append(unencryptedRooms, "  * "+encryptionMsg)
+			} else if encryptionMsg != "" {
+				output = append(output, "* "+encryptionMsg)
+			}
+			if !isInitial && members != nil {
 				for _, member := range members.Chunk {
 					reevalMembers[id.UserID(member.GetStateKey())] = struct{}{}
 				}
@@ -136,12 +217,48 @@ func (pe *PolicyEvaluator) handleProtectedRooms(ctx context.Context, evt *event.
 		}()
 	}
 	wg.Wait()
+	if len(lowPowerRooms) > 0 {
+		errors = append(errors, fmt.Sprintf(
+			"* Bot lacks sufficient power level in %d protected rooms:\n%s",
+			len(lowPowerRooms), strings.Join(lowPowerRooms, "\n"),
+		))
+	}
+	if len(unencryptedRooms) > 0 {
+		slices.Sort(unencryptedRooms)
+		errors = append(errors, fmt.Sprintf(
+			"* %d protected rooms are not encrypted:\n%s",
+			len(unencryptedRooms), strings.Join(unencryptedRooms, "\n"),
+		))
+	}
 	if len(reevalMembers) > 0 {
 		pe.EvaluateAllMembers(ctx, slices.Collect(maps.Keys(reevalMembers)))
 	}
 	return
 }
 
+// retryProtectingUnwatchedRooms protects rooms that are in the protected rooms config, but were rejected
+// because they were watched policy lists at the time.
+func (pe *PolicyEvaluator) retryProtectingUnwatchedRooms(ctx context.Context, unwatched []id.RoomID) {
+	pe.configLock.Lock()
+	defer pe.configLock.Unlock()
+	content, err := pe.GetProtectedRoomsContent(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get protected rooms to retry protecting unwatched lists")
+		return
+	}
+	if !slices.ContainsFunc(unwatched, func(roomID id.RoomID) bool {
+		return slices.Contains(content.Rooms, roomID) && !pe.IsProtectedRoom(roomID)
+	}) {
+		return
+	}
+	output, errors := pe.handleProtectedRooms(ctx, &event.Event{Content: event.Content{Parsed: content}}, false)
+	message := "Retried protecting rooms that are no longer watched lists:\n\n" + strings.Join(output, "\n")
+	if len(errors) > 0 {
+		message += "\n" + strings.Join(errors, "\n")
+	}
+	pe.sendNotice(ctx, message)
+}
+
 func (pe *PolicyEvaluator) markAsWantToProtect(roomID id.RoomID) {
 	pe.protectedRoomsLock.Lock()
 	defer pe.protectedRoomsLock.Unlock()
@@ -192,3 +309,82 @@ func (pe *PolicyEvaluator) unlockedUpdateUser(userID id.UserID, roomID id.RoomID
 	}
 	return false
 }
+
+func (pe *PolicyEvaluator) GetProtectedRoomsContent(ctx context.Context) (*config.ProtectedRoomsEventContent, error) {
+	var content config.ProtectedRoomsEventContent
+	err := pe.Bot.StateEvent(ctx, pe.ManagementRoom, config.StateProtectedRooms, "", &content)
+	if err != nil && !errors.Is(err, mautrix.MNotFound) {
+		return nil, fmt.Errorf("failed to get protected rooms event: %w", err)
+	}
+	return &content, nil
+}
+
+// AddProtectedRoom adds the given room to the protected rooms state event of the management room.
+// The room will be protected once the state event is received back from the homeserver.
+func (pe *PolicyEvaluator) AddProtectedRoom(ctx context.Context, roomID id.RoomID, source config.ProtectionSource) (bool, error) {
+	content, err := pe.GetProtectedRoomsContent(ctx)
+	if err != nil {
+		return false, err
+	} else if slices.Contains(content.Rooms, roomID) {
+		return false, nil
+	}
+	content.Rooms = append(content.Rooms, roomID)
+	if source != config.ProtectionSourceConfig {
+		if content.Sources == nil {
+			content.Sources = make(map[id.RoomID]config.ProtectionSource)
+		}
+		content.Sources[roomID] = source
+	}
+	_, err = pe.Bot.SendStateEvent(ctx, pe.ManagementRoom, config.StateProtectedRooms, "", content)
+	if err != nil {
+		return false, fmt.Errorf("failed to update protected rooms event: %w", err)
+	}
+	return true, nil
+}
+
+func (pe *PolicyEvaluator) RemoveProtectedRoom(ctx context.Context, roomID id.RoomID) (bool, error) {
+	content, err := pe.GetProtectedRoomsContent(ctx)
+	if err != nil {
+		return false, err
+	}
+	idx := slices.Index(content.Rooms, roomID)
+	if idx < 0 {
+		return false, nil
+	}
+	content.Rooms = slices.Delete(content.Rooms, idx, idx+1)
+	delete(content.Sources, roomID)
+	_, err = pe.Bot.SendStateEvent(ctx, pe.ManagementRoom, config.StateProtectedRooms, "", content)
+	if err != nil {
+		return false, fmt.Errorf("failed to update protected rooms event: %w", err)
+	}
+	return true, nil
+}
+
+func (pe *PolicyEvaluator) AutoProtectInvitedRoom(ctx context.Context, roomID id.RoomID, inviter id.UserID) {
+	if pe.IsRoomBlacklisted(ctx, roomID) {
+		zerolog.Ctx(ctx).Info().Stringer("room_id", roomID).Msg("Rejecting invite to blacklisted room")
+		_, err := pe.Bot.LeaveRoom(ctx, roomID, nil)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to reject invite to blacklisted room")
+		}
+		pe.sendNotice(ctx, "[%s](%s) invited the bot to [%s](%s), but the room is blacklisted",
+			inviter, inviter.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL())
+		return
+	}
+	_, err := pe.Bot.JoinRoomByID(ctx, roomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to join room after invite from admin")
+		pe.sendNotice(ctx, "Failed to join [%s](%s) after invite from [%s](%s): %v",
+			roomID, roomID.URI().MatrixToURL(), inviter, inviter.URI().MatrixToURL(), err)
+		return
+	}
+	_, err = pe.AddProtectedRoom(ctx, roomID, config.ProtectionSourceInvite)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Msg("Failed to add room to protected rooms after invite from admin")
+		pe.sendNotice(ctx, "Joined [%s](%s) after invite from [%s](%s), but failed to add it to protected rooms: %v",
+			roomID, roomID.URI().MatrixToURL(), inviter, inviter.URI().MatrixToURL(), err)
+		return
+	}
+	pe.sendNotice(ctx, "[%s](%s) invited the bot to [%s](%s), adding it to protected rooms",
+		inviter, inviter.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL())
+}
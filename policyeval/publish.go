@@ -0,0 +1,78 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// publishUpstream copies a newly added ban policy to the upstream list configured in the source list's
+// publish_to field. If publish_review is enabled, an admin must confirm the publish first.
+func (pe *PolicyEvaluator) publishUpstream(ctx context.Context, source *config.WatchedPolicyList, policy *policylist.Policy) {
+	if source.PublishTo == "" || policy.Recommendation != event.PolicyRecommendationBan || policy.Entity == "" {
+		return
+	}
+	upstream := pe.FindListByShortcode(source.PublishTo)
+	if upstream == nil {
+		pe.sendNotice(ctx, "Can't publish `%s` from %s to upstream: %s", policy.Entity, source.Name, pe.describeMissingList(source.PublishTo))
+		return
+	} else if upstream.RoomID == source.RoomID || pe.upstreamHasPolicy(upstream, policy) {
+		// This also prevents loops when the upstream list publishes back to the source list.
+		return
+	}
+	publish := func(ctx context.Context) {
+		if pe.upstreamHasPolicy(upstream, policy) {
+			return
+		}
+		var extra map[string]any
+		if policy.ReasonCode != "" {
+			extra = map[string]any{policylist.ReasonCodeKey: policy.ReasonCode}
+		}
+		resp, err := pe.SendPolicy(ctx, upstream.RoomID, policy.EntityType, "", &event.ModPolicyContent{
+			Entity:         policy.Entity,
+			Reason:         policy.Reason,
+			Recommendation: event.PolicyRecommendationBan,
+		}, extra)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).
+				Stringer("upstream_list", upstream.RoomID).
+				Str("entity", policy.Entity).
+				Msg("Failed to publish policy to upstream list")
+			pe.sendNotice(ctx, "Failed to publish `%s` to %s: %v", policy.Entity, upstream.Name, err)
+			return
+		}
+		zerolog.Ctx(ctx).Info().
+			Stringer("source_list", source.RoomID).
+			Stringer("upstream_list", upstream.RoomID).
+			Str("entity", policy.Entity).
+			Stringer("policy_event_id", resp.EventID).
+			Msg("Published policy to upstream list")
+	}
+	if source.PublishReview {
+		pe.requestListWriteConfirmation(ctx, fmt.Sprintf(
+			"Publish the %s ban of `%s` from %s to %s?\n\nReason: %s",
+			policy.EntityType, policy.Entity, source.Name, upstream.Name, policy.Reason,
+		), upstream, publish)
+	} else {
+		publish(ctx)
+	}
+}
+
+func (pe *PolicyEvaluator) upstreamHasPolicy(upstream *config.WatchedPolicyList, policy *policylist.Policy) bool {
+	room := pe.Store.GetRoom(upstream.RoomID)
+	if room == nil {
+		return false
+	}
+	for _, existing := range room.Policies() {
+		if existing.EntityType == policy.EntityType && existing.Entity == policy.Entity &&
+			existing.Recommendation == event.PolicyRecommendationBan {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package policyeval
+
+import (
+	"time"
+
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	commandRateLimitWindow    = 1 * time.Minute
+	untrustedFeedbackCooldown = 10 * time.Minute
+)
+
+type commandRateLimit struct {
+	timestamps []time.Time
+	notified   bool
+}
+
+// checkCommandRateLimit records a command from the given sender and returns whether it's allowed.
+// The second return value is true if the sender should be notified about being rate limited,
+// which only happens for the first rejected command in a row.
+func (pe *PolicyEvaluator) checkCommandRateLimit(sender id.UserID) (allowed, notify bool) {
+	limit := pe.Config.CommandRateLimit
+	if limit <= 0 {
+		return true, false
+	}
+	pe.commandRateLimitsLock.Lock()
+	defer pe.commandRateLimitsLock.Unlock()
+	now := time.Now()
+	cutoff := now.Add(-commandRateLimitWindow)
+	for userID, rl := range pe.commandRateLimits {
+		if len(rl.timestamps) == 0 || rl.timestamps[len(rl.timestamps)-1].Before(cutoff) {
+			delete(pe.commandRateLimits, userID)
+		}
+	}
+	rl, ok := pe.commandRateLimits[sender]
+	if !ok {
+		rl = &commandRateLimit{}
+		pe.commandRateLimits[sender] = rl
+	}
+	firstValid := 0
+	for firstValid < len(rl.timestamps) && rl.timestamps[firstValid].Before(cutoff) {
+		firstValid++
+	}
+	rl.timestamps = rl.timestamps[firstValid:]
+	if len(rl.timestamps) >= limit {
+		notify = !rl.notified
+		rl.notified = true
+		return false, notify
+	}
+	rl.timestamps = append(rl.timestamps, now)
+	rl.notified = false
+	return true, false
+}
+
+// shouldSendUntrustedFeedback returns whether the given sender should be told about an ignored
+// command from an untrusted device. Feedback is sent at most once per cooldown period per user.
+func (pe *PolicyEvaluator) shouldSendUntrustedFeedback(sender id.UserID) bool {
+	pe.commandRateLimitsLock.Lock()
+	defer pe.commandRateLimitsLock.Unlock()
+	now := time.Now()
+	for userID, sentAt := range pe.untrustedFeedbackSent {
+		if now.Sub(sentAt) > untrustedFeedbackCooldown {
+			delete(pe.untrustedFeedbackSent, userID)
+		}
+	}
+	if _, recentlySent := pe.untrustedFeedbackSent[sender]; recentlySent {
+		return false
+	}
+	pe.untrustedFeedbackSent[sender] = now
+	return true
+}
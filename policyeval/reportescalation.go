@@ -0,0 +1,78 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/bot"
+)
+
+type reportAggregate struct {
+	firstReport time.Time
+	reporters   map[id.UserID]struct{}
+	escalated   bool
+}
+
+// aggregateReport tracks distinct reporters of an event and escalates it once enough users have reported it.
+func (pe *PolicyEvaluator) aggregateReport(ctx context.Context, reporter id.UserID, evt *event.Event) {
+	cfg := pe.Config.ReportEscalation
+	if cfg.Threshold <= 0 {
+		return
+	}
+	pe.reportsLock.Lock()
+	now := time.Now()
+	for eventID, agg := range pe.reports {
+		if now.Sub(agg.firstReport) > cfg.Window {
+			delete(pe.reports, eventID)
+		}
+	}
+	agg, ok := pe.reports[evt.ID]
+	if !ok {
+		agg = &reportAggregate{firstReport: now, reporters: make(map[id.UserID]struct{})}
+		pe.reports[evt.ID] = agg
+	}
+	agg.reporters[reporter] = struct{}{}
+	reporterCount := len(agg.reporters)
+	shouldEscalate := !agg.escalated && reporterCount >= cfg.Threshold
+	if shouldEscalate {
+		agg.escalated = true
+	}
+	pe.reportsLock.Unlock()
+	if !shouldEscalate {
+		return
+	}
+	zerolog.Ctx(ctx).Info().
+		Stringer("event_id", evt.ID).
+		Stringer("room_id", evt.RoomID).
+		Stringer("sender", evt.Sender).
+		Int("reporter_count", reporterCount).
+		Msg("Escalating event reported by multiple users")
+	eventLink := evt.RoomID.EventURI(evt.ID).MatrixToURL()
+	if !cfg.Redact || pe.IsPaused() {
+		pe.Bot.SendNoticeOpts(ctx, pe.ManagementRoom, fmt.Sprintf(
+			"@room [An event](%s) from [%s](%s) was reported by %s, please review",
+			eventLink, evt.Sender, evt.Sender.URI().MatrixToURL(), pluralize(reporterCount, "user"),
+		), &bot.SendNoticeOpts{Mentions: &event.Mentions{Room: true}})
+		return
+	}
+	var err error
+	if !pe.DryRun {
+		_, err = pe.Bot.RedactEvent(ctx, evt.RoomID, evt.ID, mautrix.ReqRedact{Reason: "Reported by multiple users"})
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("event_id", evt.ID).Msg("Failed to redact escalated event")
+		pe.Bot.SendNoticeOpts(ctx, pe.ManagementRoom, fmt.Sprintf(
+			"@room [An event](%s) from [%s](%s) was reported by %s, but redacting it failed: %v",
+			eventLink, evt.Sender, evt.Sender.URI().MatrixToURL(), pluralize(reporterCount, "user"), err,
+		), &bot.SendNoticeOpts{Mentions: &event.Mentions{Room: true}})
+	} else {
+		pe.sendNotice(ctx, "Redacted [an event](%s) from [%s](%s) after it was reported by %s, please review",
+			eventLink, evt.Sender, evt.Sender.URI().MatrixToURL(), pluralize(reporterCount, "user"))
+	}
+}
@@ -0,0 +1,45 @@
+package policyeval
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func (pe *PolicyEvaluator) startResyncLoop(ctx context.Context) {
+	if pe.Config.ResyncInterval <= 0 || !pe.resyncLoopStarted.CompareAndSwap(false, true) {
+		return
+	}
+	go pe.resyncLoop(context.WithoutCancel(ctx))
+}
+
+func (pe *PolicyEvaluator) resyncLoop(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("action", "periodic resync").
+		Stringer("management_room", pe.ManagementRoom).
+		Logger()
+	ctx = log.WithContext(ctx)
+	interval := pe.Config.ResyncInterval
+	for {
+		jitter := time.Duration(rand.Int64N(int64(interval/10) + 1))
+		select {
+		case <-time.After(interval + jitter):
+		case <-ctx.Done():
+			return
+		}
+		start := time.Now()
+		pe.protectedRoomsLock.RLock()
+		userCount := len(pe.protectedRoomMembers)
+		roomCount := len(pe.protectedRooms)
+		pe.protectedRoomsLock.RUnlock()
+		pe.EvaluateAll(ctx)
+		log.Info().
+			Int("user_count", userCount).
+			Int("room_count", roomCount).
+			Int("list_count", len(pe.GetWatchedLists())).
+			Dur("duration", time.Since(start)).
+			Msg("Periodic re-evaluation completed")
+	}
+}
@@ -0,0 +1,57 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+func (pe *PolicyEvaluator) setRoomName(ctx context.Context, evt *event.Event, roomIDOrAlias, name string) {
+	pe.setRoomProfileState(ctx, evt, roomIDOrAlias, event.StateRoomName, &event.RoomNameEventContent{Name: name}, "name")
+}
+
+func (pe *PolicyEvaluator) setRoomAvatar(ctx context.Context, evt *event.Event, roomIDOrAlias, rawURI string) {
+	avatarURL, err := id.ParseContentURI(rawURI)
+	if err != nil {
+		pe.sendNotice(ctx, "Invalid avatar URL: %v", err)
+		return
+	}
+	content := &event.RoomAvatarEventContent{URL: avatarURL.CUString()}
+	pe.setRoomProfileState(ctx, evt, roomIDOrAlias, event.StateRoomAvatar, content, "avatar")
+}
+
+// setRoomProfileState sends a name or avatar state event to a protected room,
+// checking that the bot has enough power first to give a clearer error.
+func (pe *PolicyEvaluator) setRoomProfileState(
+	ctx context.Context, evt *event.Event, roomIDOrAlias string, evtType event.Type, content any, what string,
+) {
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	}
+	roomLink := fmt.Sprintf("[%s](%s)", roomID, roomID.URI().MatrixToURL())
+	if !pe.IsProtectedRoom(roomID) {
+		pe.sendNotice(ctx, "%s is not a protected room", roomLink)
+		return
+	}
+	if powerLevels := pe.getPowerLevels(ctx, roomID); powerLevels != nil {
+		ownLevel := powerLevels.GetUserLevel(pe.Bot.UserID)
+		requiredLevel := powerLevels.GetEventLevel(evtType)
+		if ownLevel < requiredLevel {
+			pe.sendNotice(ctx, "Can't change the %s of %s: power level %d is required, but the bot only has %d",
+				what, roomLink, requiredLevel, ownLevel)
+			return
+		}
+	}
+	_, err = pe.Bot.SendStateEvent(ctx, roomID, evtType, "", content)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", roomID).Str("field", what).Msg("Failed to change room profile")
+		pe.sendNotice(ctx, "Failed to change the %s of %s: %v", what, roomLink, err)
+		return
+	}
+	pe.sendSuccessReaction(ctx, evt.ID)
+}
@@ -0,0 +1,305 @@
+package policyeval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+)
+
+const roomsCommandUsage = "Usage: `!rooms <all|list|info|protect|unprotect> [room ID or alias]`, `!rooms <all|list> --json`, " +
+	"`!rooms tombstone <old room> <new room> [--invite]`, " +
+	"`!rooms <blacklist|unblacklist> <room ID or alias> [--ban <list shortcode>] [reason]`, " +
+	"`!rooms import-bans <room ID or alias> <list shortcode>`, " +
+	"`!rooms harden <room ID or alias> <invite|knock> --for <duration>`, `!rooms <hardened|unharden> [room]`, " +
+	"`!rooms check-encryption [--enable]`, `!rooms rename <room ID or alias> <name>`, `!rooms avatar <room ID or alias> <mxc URI>`"
+
+func (pe *PolicyEvaluator) handleRoomsCommand(ctx context.Context, evt *event.Event, args []string) {
+	if len(args) == 0 {
+		pe.sendNotice(ctx, roomsCommandUsage)
+		return
+	}
+	switch strings.ToLower(args[0]) {
+	case "all", "list":
+		_, asJSON := extractFlag(args, "--json")
+		pe.sendAllRooms(ctx, asJSON)
+	case "info":
+		if len(args) < 2 {
+			pe.sendNotice(ctx, "Usage: `!rooms info <room ID or alias>`")
+			return
+		}
+		pe.sendRoomInfo(ctx, args[1])
+	case "tombstone":
+		var invite bool
+		args, invite = extractFlag(args, "--invite")
+		if len(args) < 3 {
+			pe.sendNotice(ctx, "Usage: `!rooms tombstone <old room> <new room> [--invite]`")
+			return
+		}
+		pe.tombstoneRoom(ctx, evt, args[1], args[2], invite)
+	case "blacklist":
+		pe.blacklistRoom(ctx, evt, args[1:])
+	case "unblacklist":
+		if len(args) < 2 {
+			pe.sendNotice(ctx, "Usage: `!rooms unblacklist <room ID or alias>`")
+			return
+		}
+		pe.unblacklistRoom(ctx, evt, args[1])
+	case "import-bans":
+		if len(args) < 3 {
+			pe.sendNotice(ctx, "Usage: `!rooms import-bans <room ID or alias> <list shortcode>`")
+			return
+		}
+		list := pe.FindListByShortcode(args[2])
+		if list == nil {
+			pe.sendListNotFound(ctx, args[2])
+			return
+		} else if !pe.checkListWriteAccess(ctx, list, evt.Sender) {
+			return
+		}
+		pe.importBans(ctx, evt, args[1], list)
+	case "harden":
+		pe.hardenRoom(ctx, evt, args[1:])
+	case "unharden":
+		if len(args) < 2 {
+			pe.sendNotice(ctx, "Usage: `!rooms unharden <room ID or alias>`")
+			return
+		}
+		pe.unhardenRoom(ctx, evt, args[1])
+	case "hardened":
+		pe.sendHardenedRooms(ctx)
+	case "check-encryption":
+		_, enable := extractFlag(args, "--enable")
+		pe.sendRoomEncryptionCheck(ctx, enable)
+	case "rename":
+		if len(args) < 3 {
+			pe.sendNotice(ctx, "Usage: `!rooms rename <room ID or alias> <name>`")
+			return
+		}
+		pe.setRoomName(ctx, evt, args[1], strings.Join(args[2:], " "))
+	case "avatar":
+		if len(args) < 3 {
+			pe.sendNotice(ctx, "Usage: `!rooms avatar <room ID or alias> <mxc URI>`")
+			return
+		}
+		pe.setRoomAvatar(ctx, evt, args[1], args[2])
+	case "protect":
+		if len(args) < 2 {
+			pe.sendNotice(ctx, "Usage: `!rooms protect <room ID or alias>`")
+			return
+		}
+		pe.protectRoom(ctx, evt, args[1])
+	case "unprotect":
+		if len(args) < 2 {
+			pe.sendNotice(ctx, "Usage: `!rooms unprotect <room ID or alias>`")
+			return
+		}
+		pe.unprotectRoom(ctx, evt, args[1])
+	default:
+		pe.sendNotice(ctx, "Unknown subcommand `%s`. %s", args[0], roomsCommandUsage)
+	}
+}
+
+func (pe *PolicyEvaluator) resolveRoom(ctx context.Context, roomIDOrAlias string) (id.RoomID, error) {
+	if !strings.HasPrefix(roomIDOrAlias, "#") {
+		return id.RoomID(roomIDOrAlias), nil
+	}
+	resp, err := pe.Bot.ResolveAlias(ctx, id.RoomAlias(roomIDOrAlias))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve alias: %w", err)
+	}
+	return resp.RoomID, nil
+}
+
+func (pe *PolicyEvaluator) protectRoom(ctx context.Context, evt *event.Event, roomIDOrAlias string) {
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	} else if pe.IsWatchingList(roomID) {
+		pe.sendNotice(ctx, "Can't protect [%s](%s): watched policy lists can't be protected rooms", roomID, roomID.URI().MatrixToURL())
+		return
+	}
+	resp, err := pe.Bot.JoinRoom(ctx, roomIDOrAlias, nil)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to join room %q: %v", roomIDOrAlias, err)
+		return
+	}
+	added, err := pe.AddProtectedRoom(ctx, resp.RoomID, config.ProtectionSourceCommand)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to add [%s](%s) to protected rooms: %v", resp.RoomID, resp.RoomID.URI().MatrixToURL(), err)
+	} else if !added {
+		pe.sendNotice(ctx, "Already protecting [%s](%s)", resp.RoomID, resp.RoomID.URI().MatrixToURL())
+	} else {
+		pe.sendSuccessReaction(ctx, evt.ID)
+	}
+}
+
+func (pe *PolicyEvaluator) unprotectRoom(ctx context.Context, evt *event.Event, roomIDOrAlias string) {
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	}
+	removed, err := pe.RemoveProtectedRoom(ctx, roomID)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to remove [%s](%s) from protected rooms: %v", roomID, roomID.URI().MatrixToURL(), err)
+	} else if !removed {
+		pe.sendNotice(ctx, "[%s](%s) is not a protected room", roomID, roomID.URI().MatrixToURL())
+	} else {
+		pe.sendSuccessReaction(ctx, evt.ID)
+	}
+}
+
+func (pe *PolicyEvaluator) sendRoomInfo(ctx context.Context, roomIDOrAlias string) {
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	}
+	protectedRooms, err := pe.GetProtectedRoomsContent(ctx)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get protected rooms: %v", err)
+		return
+	}
+	lines := []string{fmt.Sprintf("[%s](%s):", roomID, roomID.URI().MatrixToURL())}
+	if !slices.Contains(protectedRooms.Rooms, roomID) {
+		lines = append(lines, "* Not in the protected rooms list")
+	} else {
+		lines = append(lines, fmt.Sprintf("* Protection source: %s", protectedRooms.GetSource(roomID)))
+		if pe.IsProtectedRoom(roomID) {
+			lines = append(lines, "* Currently protected")
+		} else {
+			lines = append(lines, "* Not currently protected (check the bot's membership and power level)")
+		}
+	}
+	if pe.IsWatchingList(roomID) {
+		lines = append(lines, "* Watched as a policy list")
+	}
+	pe.sendNotice(ctx, strings.Join(lines, "\n"))
+}
+
+type roomRole string
+
+const (
+	roomRoleManagement      roomRole = "Management rooms"
+	roomRoleProtected       roomRole = "Protected rooms"
+	roomRoleWatchedList     roomRole = "Watched policy lists"
+	roomRoleOtherManagement roomRole = "Used by other management rooms"
+	roomRoleOrphan          roomRole = "Orphan rooms (not used for anything)"
+)
+
+var roomRoleOrder = []roomRole{roomRoleManagement, roomRoleProtected, roomRoleWatchedList, roomRoleOtherManagement, roomRoleOrphan}
+
+var roomRoleJSONKeys = map[roomRole]string{
+	roomRoleManagement:      "management",
+	roomRoleProtected:       "protected",
+	roomRoleWatchedList:     "watched_list",
+	roomRoleOtherManagement: "other_management",
+	roomRoleOrphan:          "orphan",
+}
+
+// getOtherManagementRoomUsage returns the rooms used by other management rooms of the same bot.
+func (pe *PolicyEvaluator) getOtherManagementRoomUsage(ctx context.Context) (managementRooms []id.RoomID, used map[id.RoomID]struct{}, err error) {
+	managementRooms, err = pe.DB.ManagementRoom.GetAll(ctx, pe.Bot.Meta.Username)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get management rooms: %w", err)
+	}
+	used = make(map[id.RoomID]struct{})
+	for _, roomID := range managementRooms {
+		if roomID == pe.ManagementRoom {
+			continue
+		}
+		var watchedLists config.WatchedListsEventContent
+		err = pe.Bot.StateEvent(ctx, roomID, config.StateWatchedLists, "", &watchedLists)
+		if err != nil && !errors.Is(err, mautrix.MNotFound) {
+			return nil, nil, fmt.Errorf("failed to get watched lists of %s: %w", roomID, err)
+		}
+		for _, list := range watchedLists.Lists {
+			used[list.RoomID] = struct{}{}
+		}
+		var protectedRooms config.ProtectedRoomsEventContent
+		err = pe.Bot.StateEvent(ctx, roomID, config.StateProtectedRooms, "", &protectedRooms)
+		if err != nil && !errors.Is(err, mautrix.MNotFound) {
+			return nil, nil, fmt.Errorf("failed to get protected rooms of %s: %w", roomID, err)
+		}
+		for _, protectedRoomID := range protectedRooms.Rooms {
+			used[protectedRoomID] = struct{}{}
+		}
+	}
+	return managementRooms, used, nil
+}
+
+func (pe *PolicyEvaluator) sendAllRooms(ctx context.Context, asJSON bool) {
+	joinedRooms, err := pe.Bot.JoinedRooms(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get joined rooms")
+		pe.sendNotice(ctx, "Failed to get joined rooms: %v", err)
+		return
+	}
+	managementRooms, usedByOthers, err := pe.getOtherManagementRoomUsage(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get rooms used by other management rooms")
+		pe.sendNotice(ctx, "Failed to get rooms used by other management rooms: %v", err)
+		return
+	}
+	protectedRooms, err := pe.GetProtectedRoomsContent(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get protected rooms")
+		pe.sendNotice(ctx, "Failed to get protected rooms: %v", err)
+		return
+	}
+	byRole := make(map[roomRole][]string)
+	byRoleJSON := make(map[string][]id.RoomID)
+	for _, roomID := range joinedRooms.JoinedRooms {
+		var roles []roomRole
+		if slices.Contains(managementRooms, roomID) || roomID == pe.ManagementRoom {
+			roles = append(roles, roomRoleManagement)
+		}
+		if pe.IsProtectedRoom(roomID) {
+			roles = append(roles, roomRoleProtected)
+		}
+		if pe.IsWatchingList(roomID) {
+			roles = append(roles, roomRoleWatchedList)
+		}
+		if _, ok := usedByOthers[roomID]; ok && len(roles) == 0 {
+			roles = append(roles, roomRoleOtherManagement)
+		}
+		if len(roles) == 0 {
+			roles = append(roles, roomRoleOrphan)
+		}
+		line := fmt.Sprintf("* [%s](%s)", roomID, roomID.URI().MatrixToURL())
+		if roomID == pe.ManagementRoom {
+			line += " (this room)"
+		}
+		for _, role := range roles {
+			byRoleJSON[roomRoleJSONKeys[role]] = append(byRoleJSON[roomRoleJSONKeys[role]], roomID)
+			if role == roomRoleProtected {
+				byRole[role] = append(byRole[role], fmt.Sprintf("%s (source: %s)", line, protectedRooms.GetSource(roomID)))
+			} else {
+				byRole[role] = append(byRole[role], line)
+			}
+		}
+	}
+	sections := []string{fmt.Sprintf("Bot is in %s", pluralize(len(joinedRooms.JoinedRooms), "room"))}
+	for _, role := range roomRoleOrder {
+		lines := byRole[role]
+		if len(lines) == 0 {
+			continue
+		}
+		slices.Sort(lines)
+		sections = append(sections, fmt.Sprintf("**%s** (%d):\n\n%s", role, len(lines), strings.Join(lines, "\n")))
+	}
+	pe.sendNotice(ctx, strings.Join(sections, "\n\n"))
+	if asJSON {
+		pe.sendJSON(ctx, byRoleJSON)
+	}
+}
@@ -0,0 +1,88 @@
+package policyeval
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+const (
+	defaultSampleCount = 10
+	maxSampleCount     = 50
+	maxSampleLength    = 300
+)
+
+func (pe *PolicyEvaluator) getRecentMessages(ctx context.Context, userID id.UserID, count int) ([]*event.Event, error) {
+	rooms := pe.GetProtectedRooms()
+	if pe.SynapseDB != nil {
+		return pe.SynapseDB.GetRecentMessages(ctx, userID, rooms, count)
+	}
+	filter := &mautrix.FilterPart{
+		Senders: []id.UserID{userID},
+		Types:   []event.Type{event.EventMessage, event.EventSticker},
+	}
+	var output []*event.Event
+	for _, roomID := range rooms {
+		resp, err := pe.Bot.Messages(ctx, roomID, "", "", mautrix.DirectionBackward, filter, count)
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Stringer("room_id", roomID).Msg("Failed to get recent messages in room")
+			continue
+		}
+		for _, evt := range resp.Chunk {
+			if evt.Sender == userID && evt.Unsigned.RedactedBecause == nil {
+				output = append(output, evt)
+			}
+		}
+	}
+	slices.SortFunc(output, func(a, b *event.Event) int {
+		return cmp.Compare(b.Timestamp, a.Timestamp)
+	})
+	if len(output) > count {
+		output = output[:count]
+	}
+	return output, nil
+}
+
+func formatSampleBody(evt *event.Event) string {
+	_ = evt.Content.ParseRaw(evt.Type)
+	content, ok := evt.Content.Parsed.(*event.MessageEventContent)
+	if !ok {
+		return "*unsupported event*"
+	}
+	body := strings.Join(strings.Fields(content.Body), " ")
+	if runes := []rune(body); len(runes) > maxSampleLength {
+		body = string(runes[:maxSampleLength]) + "…"
+	}
+	if body == "" {
+		return fmt.Sprintf("*empty `%s`*", content.MsgType)
+	}
+	return fmt.Sprintf("`%s` `%s`", content.MsgType, strings.ReplaceAll(body, "`", "'"))
+}
+
+func (pe *PolicyEvaluator) sampleMessages(ctx context.Context, userID id.UserID, count int) {
+	messages, err := pe.getRecentMessages(ctx, userID, count)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Msg("Failed to get recent messages")
+		pe.sendNotice(ctx, "Failed to get recent messages from [%s](%s): %v", userID, userID.URI().MatrixToURL(), err)
+		return
+	} else if len(messages) == 0 {
+		pe.sendNotice(ctx, "No unredacted messages from [%s](%s) found in protected rooms", userID, userID.URI().MatrixToURL())
+		return
+	}
+	lines := make([]string, len(messages))
+	for i, evt := range messages {
+		lines[i] = fmt.Sprintf("* [%s](%s): %s",
+			time.UnixMilli(evt.Timestamp).UTC().Format(time.DateTime),
+			evt.RoomID.EventURI(evt.ID).MatrixToURL(), formatSampleBody(evt))
+	}
+	pe.sendNotice(ctx, "Latest %s from [%s](%s) in protected rooms:\n\n%s",
+		pluralize(len(messages), "message"), userID, userID.URI().MatrixToURL(), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,196 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/util/random"
+	"maunium.net/go/mautrix/event"
+
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// maxScheduleSleep is the longest time the scheduler sleeps without checking the database,
+// which protects against missed wakeups and clock jumps.
+const maxScheduleSleep = 1 * time.Hour
+
+func (pe *PolicyEvaluator) startScheduleLoop(ctx context.Context) {
+	if !pe.scheduleLoopStarted.CompareAndSwap(false, true) {
+		return
+	}
+	go pe.scheduleLoop(context.WithoutCancel(ctx))
+}
+
+func (pe *PolicyEvaluator) wakeScheduleLoop() {
+	select {
+	case pe.scheduleWakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (pe *PolicyEvaluator) scheduleLoop(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().
+		Str("action", "scheduled actions").
+		Stringer("management_room", pe.ManagementRoom).
+		Logger()
+	ctx = log.WithContext(ctx)
+	for {
+		sleep := maxScheduleSleep
+		actions, err := pe.DB.ScheduledAction.GetAll(ctx, pe.ManagementRoom)
+		if err != nil {
+			log.Err(err).Msg("Failed to get scheduled actions")
+		}
+		for _, action := range actions {
+			// Actions whose time passed while Meowlnir was down are executed immediately
+			if untilDue := time.Until(action.ScheduledAt); untilDue > 0 {
+				sleep = min(sleep, untilDue)
+				break
+			}
+			pe.executeScheduledAction(ctx, action)
+		}
+		sleep = min(sleep, pe.revertExpiredHardenings(ctx))
+		sleep = min(sleep, pe.revertExpiredPowerGrants(ctx))
+		select {
+		case <-time.After(sleep):
+		case <-pe.scheduleWakeup:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (pe *PolicyEvaluator) executeScheduledAction(ctx context.Context, action *database.ScheduledAction) {
+	log := zerolog.Ctx(ctx).With().Str("scheduled_action_id", action.ID).Logger()
+	// Delete first to make sure a failing action isn't retried forever
+	deleted, err := pe.DB.ScheduledAction.Delete(ctx, pe.ManagementRoom, action.ID)
+	if err != nil {
+		log.Err(err).Msg("Failed to delete scheduled action")
+		return
+	} else if !deleted {
+		// Cancelled concurrently
+		return
+	}
+	list := pe.GetWatchedListMeta(action.PolicyList)
+	if list == nil {
+		pe.sendNotice(ctx, "Failed to execute scheduled ban `%s` of `%s`: policy list [%s](%s) is no longer watched",
+			action.ID, action.Entity, action.PolicyList, action.PolicyList.URI().MatrixToURL())
+		return
+	} else if !list.CanWrite(action.CreatedBy) {
+		pe.sendNotice(ctx, "Skipped scheduled ban `%s` of `%s`: [%s](%s) is no longer authorized to modify the `%s` list",
+			action.ID, action.Entity, action.CreatedBy, action.CreatedBy.URI().MatrixToURL(), list.Shortcode)
+		return
+	}
+	entityType := policylist.EntityType(action.EntityType)
+	existingStateKey, unban := pe.findExistingBan(list, entityType, action.Entity)
+	if unban != nil {
+		pe.sendNotice(ctx, "Skipped scheduled ban `%s`: `%s` has an unban recommendation: %s", action.ID, action.Entity, unban.Reason)
+		return
+	}
+	policy := &event.ModPolicyContent{
+		Entity:         action.Entity,
+		Reason:         action.Reason,
+		Recommendation: event.PolicyRecommendationBan,
+	}
+	resp, err := pe.SendPolicy(ctx, list.RoomID, entityType, existingStateKey, policy, action.Extra)
+	if err != nil {
+		log.Err(err).Msg("Failed to send scheduled ban policy")
+		pe.sendNotice(ctx, "Failed to send scheduled ban `%s` of `%s`: %v", action.ID, action.Entity, err)
+		return
+	}
+	log.Info().
+		Stringer("policy_list", list.RoomID).
+		Any("policy", policy).
+		Stringer("policy_event_id", resp.EventID).
+		Msg("Sent scheduled ban policy")
+	pe.sendNotice(ctx, "Sent scheduled ban `%s` of `%s` to `%s` (scheduled by [%s](%s))",
+		action.ID, action.Entity, list.Shortcode, action.CreatedBy, action.CreatedBy.URI().MatrixToURL())
+}
+
+func (pe *PolicyEvaluator) scheduleBanPolicy(
+	ctx context.Context,
+	evt *event.Event,
+	list *config.WatchedPolicyList,
+	entityType policylist.EntityType,
+	target, reason string,
+	extra map[string]any,
+	at time.Time,
+) {
+	if pe.Config.RequirePolicyReason && strings.TrimSpace(reason) == "" {
+		pe.sendNotice(ctx, "Failed to schedule ban policy: %v", ErrReasonRequired)
+		return
+	}
+	action := &database.ScheduledAction{
+		ID:             strings.ToLower(random.String(8)),
+		ManagementRoom: pe.ManagementRoom,
+		PolicyList:     list.RoomID,
+		EntityType:     string(entityType),
+		Entity:         target,
+		Reason:         reason,
+		Extra:          extra,
+		CreatedBy:      evt.Sender,
+		ScheduledAt:    at,
+		CreatedAt:      time.Now(),
+	}
+	err := pe.DB.ScheduledAction.Put(ctx, action)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to save scheduled action")
+		pe.sendNotice(ctx, "Failed to schedule ban policy: %v", err)
+		return
+	}
+	pe.wakeScheduleLoop()
+	pe.sendNotice(ctx, "Scheduled ban of `%s` in `%s` at %s (ID: `%s`)",
+		target, list.Shortcode, at.UTC().Format(time.RFC3339), action.ID)
+}
+
+func (pe *PolicyEvaluator) handleScheduledCommand(ctx context.Context, evt *event.Event, args []string) {
+	if len(args) == 0 {
+		pe.sendNotice(ctx, "Usage: `!scheduled <list|cancel> [ID]`")
+		return
+	}
+	switch strings.ToLower(args[0]) {
+	case "list":
+		pe.sendScheduledActions(ctx)
+	case "cancel":
+		if len(args) < 2 {
+			pe.sendNotice(ctx, "Usage: `!scheduled cancel <ID>`")
+			return
+		}
+		deleted, err := pe.DB.ScheduledAction.Delete(ctx, pe.ManagementRoom, args[1])
+		if err != nil {
+			pe.sendNotice(ctx, "Failed to cancel scheduled action: %v", err)
+		} else if !deleted {
+			pe.sendNotice(ctx, "Scheduled action `%s` not found", args[1])
+		} else {
+			pe.sendSuccessReaction(ctx, evt.ID)
+		}
+	default:
+		pe.sendNotice(ctx, "Unknown subcommand `%s`. Usage: `!scheduled <list|cancel> [ID]`", args[0])
+	}
+}
+
+func (pe *PolicyEvaluator) sendScheduledActions(ctx context.Context) {
+	actions, err := pe.DB.ScheduledAction.GetAll(ctx, pe.ManagementRoom)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get scheduled actions: %v", err)
+		return
+	} else if len(actions) == 0 {
+		pe.sendNotice(ctx, "No scheduled actions")
+		return
+	}
+	lines := make([]string, len(actions))
+	for i, action := range actions {
+		listName := string(action.PolicyList)
+		if list := pe.GetWatchedListMeta(action.PolicyList); list != nil {
+			listName = list.Shortcode
+		}
+		lines[i] = fmt.Sprintf("* `%s`: ban %s `%s` in `%s` at %s by [%s](%s) for %s",
+			action.ID, action.EntityType, action.Entity, listName, action.ScheduledAt.UTC().Format(time.RFC3339),
+			action.CreatedBy, action.CreatedBy.URI().MatrixToURL(), action.Reason)
+	}
+	pe.sendNotice(ctx, "Scheduled actions:\n\n%s", strings.Join(lines, "\n"))
+}
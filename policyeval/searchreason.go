@@ -0,0 +1,102 @@
+package policyeval
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// maxReasonSearchResults is the maximum number of policies listed by !search-reason.
+const maxReasonSearchResults = 100
+
+// sendPoliciesByReason lists policies whose reason contains the given text (case-insensitively) or matches
+// the given regex, in all watched lists or in the list with the given shortcode.
+func (pe *PolicyEvaluator) sendPoliciesByReason(ctx context.Context, query string, isRegex bool, shortcode string, asJSON bool) {
+	var match func(reason string) bool
+	if isRegex {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			pe.sendNotice(ctx, "Invalid regex: %v", err)
+			return
+		}
+		match = re.MatchString
+	} else {
+		lowerQuery := strings.ToLower(query)
+		match = func(reason string) bool {
+			return strings.Contains(strings.ToLower(reason), lowerQuery)
+		}
+	}
+	var lists []id.RoomID
+	if shortcode != "" {
+		list := pe.FindListByShortcode(shortcode)
+		if list == nil {
+			pe.sendListNotFound(ctx, shortcode)
+			return
+		}
+		lists = []id.RoomID{list.RoomID}
+	} else {
+		lists = pe.GetWatchedLists()
+	}
+	var sections []string
+	var shownPolicies []*policylist.Policy
+	total := 0
+	shown := 0
+	for _, listID := range lists {
+		room := pe.Store.GetRoom(listID)
+		if room == nil {
+			continue
+		}
+		var policies []*policylist.Policy
+		for _, policy := range room.Policies() {
+			if match(policy.Reason) {
+				policies = append(policies, policy)
+			}
+		}
+		if len(policies) == 0 {
+			continue
+		}
+		total += len(policies)
+		if shown >= maxReasonSearchResults {
+			continue
+		}
+		slices.SortFunc(policies, func(a, b *policylist.Policy) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+		listTotal := len(policies)
+		policies = policies[:min(len(policies), maxReasonSearchResults-shown)]
+		shown += len(policies)
+		shownPolicies = append(shownPolicies, policies...)
+		lines := make([]string, len(policies))
+		for i, policy := range policies {
+			lines[i] = fmt.Sprintf("* `%s` %s `%s`: %s", policy.Recommendation, policy.EntityType, policy.Entity, policy.Reason)
+			if policy.Ignored {
+				lines[i] += " (ignored)"
+			}
+		}
+		listName := string(listID)
+		if meta := pe.GetWatchedListMeta(listID); meta != nil {
+			listName = meta.Name
+		}
+		sections = append(sections, fmt.Sprintf("**%s** (%d):\n\n%s", listName, listTotal, strings.Join(lines, "\n")))
+	}
+	if total == 0 {
+		pe.sendNotice(ctx, "No policies with a reason matching `%s` found", query)
+	} else {
+		var suffix string
+		if shown < total {
+			suffix = fmt.Sprintf("\n\nOnly showing the first %d results", shown)
+		}
+		pe.sendNotice(ctx, "Policies with a reason matching `%s` (%d total):\n\n%s%s",
+			query, total, strings.Join(sections, "\n\n"), suffix)
+	}
+	if asJSON {
+		pe.sendJSON(ctx, &searchJSON{Total: total, Policies: policiesToJSON(shownPolicies)})
+	}
+}
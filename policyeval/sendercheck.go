@@ -0,0 +1,44 @@
+package policyeval
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+)
+
+// senderCheckInterval is how long a message sender is remembered after being evaluated,
+// so that active users aren't matched against all policies on every message.
+const senderCheckInterval = 10 * time.Minute
+
+// evaluateMessageSender evaluates the sender of a message in a protected room against policies.
+// This catches users whose membership event was never processed (e.g. due to federation lag),
+// as the sender is also added to the protected room member list if it was missing.
+func (pe *PolicyEvaluator) evaluateMessageSender(ctx context.Context, evt *event.Event) {
+	if evt.Sender == pe.Bot.UserID {
+		return
+	}
+	key := userRoomPair{UserID: evt.Sender, RoomID: evt.RoomID}
+	now := time.Now()
+	pe.checkedSendersLock.Lock()
+	if now.Sub(pe.checkedSenders[key]) < senderCheckInterval {
+		pe.checkedSendersLock.Unlock()
+		return
+	}
+	for pair, checkedAt := range pe.checkedSenders {
+		if now.Sub(checkedAt) >= senderCheckInterval {
+			delete(pe.checkedSenders, pair)
+		}
+	}
+	pe.checkedSenders[key] = now
+	pe.checkedSendersLock.Unlock()
+
+	if pe.updateUser(evt.Sender, evt.RoomID, event.MembershipJoin) {
+		zerolog.Ctx(ctx).Debug().
+			Stringer("user_id", evt.Sender).
+			Stringer("room_id", evt.RoomID).
+			Msg("Found untracked member from message, evaluating against policies")
+	}
+	pe.EvaluateUser(ctx, evt.Sender, false)
+}
@@ -0,0 +1,55 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.mau.fi/util/glob"
+)
+
+const maxServerBanPreview = 50
+
+// findKnownServers returns all servers seen among members of protected rooms and protected room IDs
+// that match the given glob.
+func (pe *PolicyEvaluator) findKnownServers(pattern glob.Glob) []string {
+	servers := make(map[string]struct{})
+	pe.protectedRoomsLock.RLock()
+	for userID := range pe.protectedRoomMembers {
+		servers[userID.Homeserver()] = struct{}{}
+	}
+	for roomID := range pe.protectedRooms {
+		if _, server, ok := strings.Cut(string(roomID), ":"); ok {
+			servers[server] = struct{}{}
+		}
+	}
+	pe.protectedRoomsLock.RUnlock()
+	matches := slices.Collect(maps.Keys(servers))
+	matches = slices.DeleteFunc(matches, func(server string) bool {
+		return !pattern.Match(server)
+	})
+	slices.Sort(matches)
+	return matches
+}
+
+// previewServerBan lists the known servers that a server ban would affect and only calls fn
+// after an admin confirms the ban.
+func (pe *PolicyEvaluator) previewServerBan(ctx context.Context, listName, pattern string, fn func(ctx context.Context)) {
+	matches := pe.findKnownServers(glob.Compile(pattern))
+	var message strings.Builder
+	if len(matches) == 0 {
+		_, _ = fmt.Fprintf(&message, "Banning `%s` in %s wouldn't affect any currently known servers.", pattern, listName)
+	} else {
+		_, _ = fmt.Fprintf(&message, "Banning `%s` in %s would affect %s:\n\n", pattern, listName, pluralize(len(matches), "known server"))
+		for i, server := range matches {
+			if i >= maxServerBanPreview {
+				_, _ = fmt.Fprintf(&message, "* ...and %d more\n", len(matches)-maxServerBanPreview)
+				break
+			}
+			_, _ = fmt.Fprintf(&message, "* `%s`\n", server)
+		}
+	}
+	pe.requestConfirmation(ctx, strings.TrimSpace(message.String()), fn)
+}
@@ -0,0 +1,105 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type loadStats struct {
+	LoadedAt     time.Time     `json:"loaded_at"`
+	InitDuration time.Duration `json:"init_duration"`
+	EvalDuration time.Duration `json:"eval_duration"`
+	ErrorCount   int           `json:"error_count"`
+}
+
+type statsJSON struct {
+	ProtectedRooms int        `json:"protected_rooms"`
+	JoinedUsers    int        `json:"joined_users"`
+	AllTimeUsers   int        `json:"all_time_users"`
+	WatchedLists   int        `json:"watched_lists"`
+	LastLoad       *loadStats `json:"last_load,omitempty"`
+	Paused         bool       `json:"paused"`
+	DryRun         bool       `json:"dry_run"`
+
+	ActionsByCode map[string]int `json:"actions_by_reason_code,omitempty"`
+}
+
+func (pe *PolicyEvaluator) getStats() *statsJSON {
+	pe.protectedRoomsLock.RLock()
+	stats := &statsJSON{
+		ProtectedRooms: len(pe.protectedRooms),
+		AllTimeUsers:   len(pe.protectedRoomMembers),
+	}
+	for _, rooms := range pe.protectedRoomMembers {
+		if len(rooms) > 0 {
+			stats.JoinedUsers++
+		}
+	}
+	pe.protectedRoomsLock.RUnlock()
+	stats.WatchedLists = len(pe.GetWatchedLists())
+	stats.LastLoad = pe.lastLoad.Load()
+	stats.Paused = pe.IsPaused()
+	stats.DryRun = pe.DryRun
+	return stats
+}
+
+// getActionCountsByCode counts the actions taken in this evaluator's protected rooms for each policy reason code.
+func (pe *PolicyEvaluator) getActionCountsByCode(ctx context.Context) map[string]int {
+	counts, err := pe.DB.TakenAction.CountByReasonCode(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to count taken actions by reason code")
+		return nil
+	}
+	output := make(map[string]int)
+	for _, count := range counts {
+		if pe.IsProtectedRoom(count.RoomID) {
+			output[count.Code] += count.Count
+		}
+	}
+	return output
+}
+
+func (pe *PolicyEvaluator) protectionSummary() string {
+	stats := pe.getStats()
+	return fmt.Sprintf("Protecting %d rooms with %d users (%d all time) using %d lists.",
+		stats.ProtectedRooms, stats.JoinedUsers, stats.AllTimeUsers, stats.WatchedLists)
+}
+
+func (pe *PolicyEvaluator) sendStats(ctx context.Context, asJSON bool) {
+	actionsByCode := pe.getActionCountsByCode(ctx)
+	lines := []string{pe.protectionSummary()}
+	if stats := pe.lastLoad.Load(); stats != nil {
+		lines = append(lines, fmt.Sprintf(
+			"Last loaded at %s (took %s to load data and %s to evaluate rules, %s)",
+			stats.LoadedAt.UTC().Format(time.RFC3339), stats.InitDuration, stats.EvalDuration, pluralize(stats.ErrorCount, "error"),
+		))
+	} else {
+		lines = append(lines, "Initial state hasn't been loaded successfully")
+	}
+	if len(actionsByCode) > 0 {
+		codes := slices.Sorted(maps.Keys(actionsByCode))
+		codeCounts := make([]string, len(codes))
+		for i, code := range codes {
+			codeCounts[i] = fmt.Sprintf("`%s`: %d", code, actionsByCode[code])
+		}
+		lines = append(lines, "Actions by reason code: "+strings.Join(codeCounts, ", "))
+	}
+	if pe.IsPaused() {
+		lines = append(lines, "Automated moderation is **paused**")
+	}
+	if pe.DryRun {
+		lines = append(lines, "Dry run mode is enabled")
+	}
+	pe.sendNotice(ctx, strings.Join(lines, "\n\n"))
+	if asJSON {
+		stats := pe.getStats()
+		stats.ActionsByCode = actionsByCode
+		pe.sendJSON(ctx, stats)
+	}
+}
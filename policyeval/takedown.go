@@ -0,0 +1,102 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/federation"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// getKnownUsersOnServer returns all users from the given server that have been seen in protected rooms,
+// including users who have already left. Server names are normalized, so the Unicode and punycode forms
+// of a name find the same users.
+func (pe *PolicyEvaluator) getKnownUsersOnServer(server string) []id.UserID {
+	var users []id.UserID
+	server = policylist.NormalizeServerName(server)
+	pe.protectedRoomsLock.RLock()
+	for userID := range pe.protectedRoomMembers {
+		if policylist.NormalizeServerName(userID.Homeserver()) == server {
+			users = append(users, userID)
+		}
+	}
+	pe.protectedRoomsLock.RUnlock()
+	slices.Sort(users)
+	return users
+}
+
+// takedownServer bans the given server in the given list and redacts all content sent by known users
+// from that server in protected rooms. Admins must confirm the takedown before anything is done.
+func (pe *PolicyEvaluator) takedownServer(ctx context.Context, evt *event.Event, list *config.WatchedPolicyList, server, reason string) {
+	if _, _, ok := federation.ParseServerName(server); !ok {
+		pe.sendNotice(ctx, "`%s` is not a valid server name. Takedowns don't support globs, use `!ban-server` for those.", server)
+		return
+	}
+	users := pe.getKnownUsersOnServer(server)
+	message := fmt.Sprintf(
+		"Taking down `%s` will ban it in %s and redact all messages from %s seen in protected rooms.",
+		server, list.Name, pluralize(len(users), "known user"),
+	)
+	pe.requestConfirmation(ctx, message, func(ctx context.Context) {
+		var existingStateKey string
+		match := pe.Store.MatchServer(pe.GetWatchedLists(), server)
+		if recs := match.Recommendations(); recs.IsBan() && recs.BanOrUnban.RoomID == list.RoomID {
+			existingStateKey = recs.BanOrUnban.StateKey
+		}
+		policy := &event.ModPolicyContent{
+			Entity:         server,
+			Reason:         reason,
+			Recommendation: event.PolicyRecommendationBan,
+		}
+		resp, err := pe.sendPolicyUndoable(ctx, list.RoomID, policylist.EntityTypeServer, existingStateKey, policy, nil)
+		if err != nil {
+			pe.sendNotice(ctx, "Failed to send ban policy for `%s`: %v", server, err)
+			return
+		}
+		zerolog.Ctx(ctx).Info().
+			Stringer("policy_list", list.RoomID).
+			Any("policy", policy).
+			Stringer("policy_event_id", resp.EventID).
+			Int("user_count", len(users)).
+			Msg("Sent takedown policy from command, redacting messages from server")
+		// The admin already confirmed the takedown, so don't ask again for each user above the redaction limit
+		var redactedEvents, redactedUsers int
+		var errorMessages []string
+		var supported bool
+		for _, userID := range users {
+			var userEvents int
+			var userErrors []string
+			userEvents, _, userErrors, supported = pe.redactUserWithoutConfirmation(ctx, userID, reason)
+			if !supported {
+				break
+			}
+			redactedEvents += userEvents
+			if userEvents > 0 {
+				redactedUsers++
+			}
+			errorMessages = append(errorMessages, userErrors...)
+		}
+		var output string
+		if len(users) > 0 && !supported {
+			output = fmt.Sprintf(
+				"Took down `%s`: banned in %s, but didn't redact messages, as redacting all messages from a user "+
+					"requires Synapse database access or server support for MSC4194",
+				server, list.Name)
+		} else {
+			output = fmt.Sprintf("Took down `%s`: banned in %s and redacted %s from %s",
+				server, list.Name, pluralize(redactedEvents, "event"), pluralize(redactedUsers, "user"))
+		}
+		if len(errorMessages) > 0 {
+			output += "\n\n" + strings.Join(errorMessages, "\n")
+		}
+		pe.sendNotice(ctx, output)
+		pe.sendSuccessReaction(ctx, evt.ID)
+	})
+}
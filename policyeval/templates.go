@@ -0,0 +1,90 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/id"
+)
+
+// Keys of notices that can be customized using the notice_templates config option.
+const (
+	noticeTemplateBan    = "ban"
+	noticeTemplateKick   = "kick"
+	noticeTemplateUnban  = "unban"
+	noticeTemplateRedact = "redact"
+)
+
+var noticeTemplateKeys = []string{noticeTemplateBan, noticeTemplateKick, noticeTemplateUnban, noticeTemplateRedact}
+
+// noticeTemplateData contains the placeholders available in notice templates.
+// Fields that aren't relevant for a given notice are left empty.
+type noticeTemplateData struct {
+	User         id.UserID
+	UserLink     string
+	Room         id.RoomID
+	RoomLink     string
+	Sender       id.UserID
+	SenderLink   string
+	Reason       string
+	PublicReason string
+	ListCategory string
+	EventCount   int
+	RoomCount    int
+}
+
+func newNoticeTemplateData(userID id.UserID, roomID id.RoomID) *noticeTemplateData {
+	data := &noticeTemplateData{
+		User:     userID,
+		UserLink: fmt.Sprintf("[%s](%s)", userID, userID.URI().MatrixToURL()),
+		Room:     roomID,
+	}
+	if roomID != "" {
+		data.RoomLink = fmt.Sprintf("[%s](%s)", roomID, roomID.URI().MatrixToURL())
+	}
+	return data
+}
+
+func (data *noticeTemplateData) withSender(sender id.UserID) *noticeTemplateData {
+	data.Sender = sender
+	data.SenderLink = fmt.Sprintf("[%s](%s)", sender, sender.URI().MatrixToURL())
+	return data
+}
+
+// parseNoticeTemplates parses the templates in the config. Invalid templates are skipped,
+// so the built-in notice is used for them, and the errors are returned for the load summary.
+func parseNoticeTemplates(templates map[string]string) (map[string]*template.Template, []string) {
+	parsed := make(map[string]*template.Template, len(templates))
+	var errors []string
+	for key, tpl := range templates {
+		if !slices.Contains(noticeTemplateKeys, key) {
+			errors = append(errors, fmt.Sprintf("* Unknown notice template `%s`", key))
+			continue
+		}
+		var err error
+		parsed[key], err = template.New(key).Parse(tpl)
+		if err != nil {
+			delete(parsed, key)
+			errors = append(errors, fmt.Sprintf("* Invalid notice template `%s`: %v", key, err))
+		}
+	}
+	return parsed, errors
+}
+
+// formatNotice renders the configured template for the given notice key, or the built-in format string
+// if there's no template or rendering it fails.
+func (pe *PolicyEvaluator) formatNotice(ctx context.Context, key string, data *noticeTemplateData, fallback string, args ...any) string {
+	if tpl, ok := pe.noticeTemplates[key]; ok {
+		var buf strings.Builder
+		err := tpl.Execute(&buf, data)
+		if err == nil {
+			return buf.String()
+		}
+		zerolog.Ctx(ctx).Err(err).Str("template_key", key).Msg("Failed to render notice template")
+	}
+	return fmt.Sprintf(fallback, args...)
+}
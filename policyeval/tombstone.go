@@ -0,0 +1,113 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/config"
+)
+
+// tombstoneRoom migrates protection from a protected room to a new room by tombstoning the old room,
+// protecting the new one and unprotecting the old one. Admins must confirm the migration first.
+func (pe *PolicyEvaluator) tombstoneRoom(ctx context.Context, evt *event.Event, oldRoomIDOrAlias, newRoomIDOrAlias string, invite bool) {
+	oldRoomID, err := pe.resolveRoom(ctx, oldRoomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", oldRoomIDOrAlias, err)
+		return
+	} else if !pe.IsProtectedRoom(oldRoomID) {
+		pe.sendNotice(ctx, "[%s](%s) is not a protected room", oldRoomID, oldRoomID.URI().MatrixToURL())
+		return
+	}
+	resp, err := pe.Bot.JoinRoom(ctx, newRoomIDOrAlias, nil)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to join new room %q: %v", newRoomIDOrAlias, err)
+		return
+	}
+	newRoomID := resp.RoomID
+	if newRoomID == oldRoomID {
+		pe.sendNotice(ctx, "The old and new rooms must be different")
+		return
+	}
+	message := fmt.Sprintf(
+		"This will send a tombstone in [%s](%s) pointing at [%s](%s), protect the new room and unprotect the old one.",
+		oldRoomID, oldRoomID.URI().MatrixToURL(), newRoomID, newRoomID.URI().MatrixToURL(),
+	)
+	if invite {
+		message += " Current members of the old room will be invited to the new room."
+	}
+	pe.requestConfirmation(ctx, message, func(ctx context.Context) {
+		pe.doTombstoneRoom(ctx, evt, oldRoomID, newRoomID, invite)
+	})
+}
+
+func (pe *PolicyEvaluator) doTombstoneRoom(ctx context.Context, evt *event.Event, oldRoomID, newRoomID id.RoomID, invite bool) {
+	log := zerolog.Ctx(ctx).With().
+		Stringer("old_room_id", oldRoomID).
+		Stringer("new_room_id", newRoomID).
+		Logger()
+	var lines []string
+	if !pe.DryRun {
+		_, err := pe.Bot.SendStateEvent(ctx, oldRoomID, event.StateTombstone, "", &event.TombstoneEventContent{
+			Body:            "This room has been replaced",
+			ReplacementRoom: newRoomID,
+		})
+		if err != nil {
+			log.Err(err).Msg("Failed to send tombstone")
+			pe.sendNotice(ctx, "Failed to send tombstone in [%s](%s): %v", oldRoomID, oldRoomID.URI().MatrixToURL(), err)
+			return
+		}
+	}
+	lines = append(lines, fmt.Sprintf("* Sent tombstone in [%s](%s)", oldRoomID, oldRoomID.URI().MatrixToURL()))
+	if _, err := pe.AddProtectedRoom(ctx, newRoomID, config.ProtectionSourceCommand); err != nil {
+		log.Err(err).Msg("Failed to protect new room")
+		lines = append(lines, fmt.Sprintf("* Failed to protect [%s](%s): %v", newRoomID, newRoomID.URI().MatrixToURL(), err))
+	} else {
+		lines = append(lines, fmt.Sprintf("* Added [%s](%s) to protected rooms", newRoomID, newRoomID.URI().MatrixToURL()))
+	}
+	if invite {
+		lines = append(lines, pe.inviteRoomMembers(ctx, oldRoomID, newRoomID))
+	}
+	if _, err := pe.RemoveProtectedRoom(ctx, oldRoomID); err != nil {
+		log.Err(err).Msg("Failed to unprotect old room")
+		lines = append(lines, fmt.Sprintf("* Failed to unprotect [%s](%s): %v", oldRoomID, oldRoomID.URI().MatrixToURL(), err))
+	} else {
+		lines = append(lines, fmt.Sprintf("* Removed [%s](%s) from protected rooms", oldRoomID, oldRoomID.URI().MatrixToURL()))
+	}
+	log.Info().Msg("Migrated protected room")
+	pe.sendNotice(ctx, "Room migration finished:\n\n%s", strings.Join(lines, "\n"))
+	pe.sendSuccessReaction(ctx, evt.ID)
+}
+
+func (pe *PolicyEvaluator) inviteRoomMembers(ctx context.Context, fromRoomID, toRoomID id.RoomID) string {
+	members, err := pe.Bot.JoinedMembers(ctx, fromRoomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("room_id", fromRoomID).Msg("Failed to get joined members to invite")
+		return fmt.Sprintf("* Failed to get members of [%s](%s) to invite: %v", fromRoomID, fromRoomID.URI().MatrixToURL(), err)
+	}
+	var invited, failed int
+	for userID := range members.Joined {
+		if userID == pe.Bot.UserID {
+			continue
+		}
+		if !pe.DryRun {
+			_, err = pe.Bot.InviteUser(ctx, toRoomID, &mautrix.ReqInviteUser{UserID: userID})
+		}
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Stringer("room_id", toRoomID).Msg("Failed to invite user")
+			failed++
+		} else {
+			invited++
+		}
+	}
+	line := fmt.Sprintf("* Invited %s to [%s](%s)", pluralize(invited, "member"), toRoomID, toRoomID.URI().MatrixToURL())
+	if failed > 0 {
+		line += fmt.Sprintf(" (failed to invite %d)", failed)
+	}
+	return line
+}
@@ -0,0 +1,184 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/database"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+type userRoomPair struct {
+	UserID id.UserID
+	RoomID id.RoomID
+}
+
+type manualUnban struct {
+	until    time.Time
+	notified bool
+}
+
+// unbanReview is a set of rooms where a user was unbanned while the bot was offline.
+// Automated bans are suppressed in those rooms until an admin decides or the review expires.
+type unbanReview struct {
+	rooms     []id.RoomID
+	expiresAt time.Time
+}
+
+// handleManualUnban starts the re-ban cooldown when someone other than the bot unbans a user.
+func (pe *PolicyEvaluator) handleManualUnban(ctx context.Context, evt *event.Event) {
+	if pe.Config.ManualUnbanCooldown <= 0 || evt.Sender == pe.Bot.UserID || evt.Unsigned.PrevContent == nil {
+		return
+	}
+	_ = evt.Unsigned.PrevContent.ParseRaw(evt.Type)
+	if prev := evt.Unsigned.PrevContent.AsMember(); prev.Membership != event.MembershipBan {
+		return
+	}
+	userID := id.UserID(evt.GetStateKey())
+	zerolog.Ctx(ctx).Debug().
+		Stringer("user_id", userID).
+		Stringer("unbanned_by", evt.Sender).
+		Msg("Detected manual unban, suppressing automated re-bans")
+	key := userRoomPair{UserID: userID, RoomID: evt.RoomID}
+	unban := &manualUnban{
+		until: time.Now().Add(pe.Config.ManualUnbanCooldown),
+	}
+	pe.unbanReviewsLock.Lock()
+	pe.manualUnbans[key] = unban
+	pe.unbanReviewsLock.Unlock()
+	ctx = context.WithoutCancel(ctx)
+	time.AfterFunc(pe.Config.ManualUnbanCooldown, func() {
+		pe.unbanReviewsLock.Lock()
+		if pe.manualUnbans[key] != unban {
+			// The user was unbanned again, so the newer cooldown will take care of re-evaluating
+			pe.unbanReviewsLock.Unlock()
+			return
+		}
+		delete(pe.manualUnbans, key)
+		pe.unbanReviewsLock.Unlock()
+		// Bans that were suppressed during the cooldown aren't retried otherwise
+		pe.EvaluateUser(ctx, userID, false)
+	})
+}
+
+// checkManualUnbanCooldown returns true if the user was recently unbanned manually in the given room,
+// in which case automated bans are suppressed. The first suppressed ban is reported to the management room.
+func (pe *PolicyEvaluator) checkManualUnbanCooldown(ctx context.Context, userID id.UserID, roomID id.RoomID, policy *policylist.Policy) bool {
+	key := userRoomPair{UserID: userID, RoomID: roomID}
+	pe.unbanReviewsLock.Lock()
+	unban, ok := pe.manualUnbans[key]
+	if ok && time.Now().After(unban.until) {
+		delete(pe.manualUnbans, key)
+		ok = false
+	}
+	var notify bool
+	if ok && !unban.notified {
+		unban.notified = true
+		notify = true
+	}
+	pe.unbanReviewsLock.Unlock()
+	if notify {
+		pe.sendNotice(ctx,
+			"[%s](%s) was manually unbanned in [%s](%s), but still matches a ban policy for %s. "+
+				"Not banning them again until %s.",
+			userID, userID.URI().MatrixToURL(), roomID, roomID.URI().MatrixToURL(), policy.Reason,
+			unban.until.UTC().Format(time.RFC3339))
+	}
+	return ok
+}
+
+func (pe *PolicyEvaluator) isUnbanUnderReview(userID id.UserID, roomID id.RoomID) bool {
+	pe.unbanReviewsLock.Lock()
+	defer pe.unbanReviewsLock.Unlock()
+	review, ok := pe.unbanReviews[userID]
+	if ok && time.Now().After(review.expiresAt) {
+		// Nobody answered the review, so stop suppressing bans
+		delete(pe.unbanReviews, userID)
+		return false
+	}
+	return ok && slices.Contains(review.rooms, roomID)
+}
+
+// reviewManualUnbans finds users who the bot has banned, but who are back in the room, and asks admins
+// whether they should be banned again instead of re-banning them automatically.
+func (pe *PolicyEvaluator) reviewManualUnbans(ctx context.Context) {
+	if !pe.Config.ReviewManualUnbans {
+		return
+	}
+	actions, err := pe.DB.TakenAction.GetAllByType(ctx, database.TakenActionTypeBanOrUnban)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get taken actions to review manual unbans")
+		return
+	}
+	found := make(map[id.UserID][]id.RoomID)
+	var foundActions []*database.TakenAction
+	for _, ta := range actions {
+		if ta.Action != event.PolicyRecommendationBan || !pe.IsProtectedRoom(ta.InRoomID) {
+			continue
+		}
+		if slices.Contains(pe.getRoomsUserIsIn(ta.TargetUser), ta.InRoomID) {
+			found[ta.TargetUser] = append(found[ta.TargetUser], ta.InRoomID)
+			foundActions = append(foundActions, ta)
+		}
+	}
+	if len(found) == 0 {
+		return
+	}
+	// The confirmation request expires after confirmationTimeout, after which the callback below will never run
+	expiresAt := time.Now().Add(confirmationTimeout)
+	pe.unbanReviewsLock.Lock()
+	for userID, rooms := range found {
+		pe.unbanReviews[userID] = &unbanReview{rooms: rooms, expiresAt: expiresAt}
+	}
+	pe.unbanReviewsLock.Unlock()
+	zerolog.Ctx(ctx).Info().Int("user_count", len(found)).Msg("Found previously banned users who are no longer banned")
+	var lines []string
+	for userID, rooms := range found {
+		if len(lines) >= 20 {
+			lines = append(lines, fmt.Sprintf("* ...and %d more", len(found)-len(lines)))
+			break
+		}
+		roomLinks := make([]string, len(rooms))
+		for i, roomID := range rooms {
+			roomLinks[i] = fmt.Sprintf("[%s](%s)", roomID, roomID.URI().MatrixToURL())
+		}
+		lines = append(lines, fmt.Sprintf("* [%s](%s) in %s", userID, userID.URI().MatrixToURL(), strings.Join(roomLinks, ", ")))
+	}
+	message := fmt.Sprintf(
+		"%s were banned by the bot, but are no longer banned. They won't be banned again unless an admin confirms it.\n\n%s\n\n"+
+			"React with %s to ban them again now, or %s to dismiss the review and forget the old bans. "+
+			"If nobody reacts by %s, automated bans will resume for them.",
+		pluralize(len(found), "user"), strings.Join(lines, "\n"), confirmationReaction, rejectionReaction,
+		expiresAt.UTC().Format(time.RFC3339),
+	)
+	go pe.requestChoice(context.WithoutCancel(ctx), message, []string{confirmationReaction, rejectionReaction}, func(ctx context.Context, choice int) {
+		pe.unbanReviewsLock.Lock()
+		for userID := range found {
+			delete(pe.unbanReviews, userID)
+		}
+		pe.unbanReviewsLock.Unlock()
+		if choice == 0 {
+			pe.sendNotice(ctx, "Re-evaluating %s", pluralize(len(found), "user"))
+			for userID := range found {
+				pe.EvaluateUser(ctx, userID, false)
+			}
+			return
+		}
+		// Forget the old bans so that the users aren't brought up for review again after the next restart.
+		for _, ta := range foundActions {
+			err := pe.DB.TakenAction.Delete(ctx, ta)
+			if err != nil {
+				zerolog.Ctx(ctx).Err(err).Any("action", ta).Msg("Failed to delete taken action of accepted unban")
+			}
+		}
+		pe.sendNotice(ctx, "Dismissed review of %s. They'll only be banned again if they're re-evaluated against a matching policy.",
+			pluralize(len(found), "user"))
+	})
+}
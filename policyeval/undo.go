@@ -0,0 +1,110 @@
+package policyeval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// maxUndoStack is the number of recent actions remembered for !undo.
+const maxUndoStack = 20
+
+// undoableAction is an action taken by the bot that !undo can reverse.
+// Actions with a nil undo function (e.g. kicks and redactions) are remembered only
+// so that !undo can report that they can't be reversed.
+type undoableAction struct {
+	description string
+	timestamp   time.Time
+	undo        func(ctx context.Context) error
+}
+
+func (pe *PolicyEvaluator) pushUndo(action *undoableAction) {
+	pe.undoStackLock.Lock()
+	defer pe.undoStackLock.Unlock()
+	action.timestamp = time.Now()
+	pe.undoStack = append(pe.undoStack, action)
+	if len(pe.undoStack) > maxUndoStack {
+		pe.undoStack = pe.undoStack[len(pe.undoStack)-maxUndoStack:]
+	}
+}
+
+func (pe *PolicyEvaluator) popUndo(expected *undoableAction) bool {
+	pe.undoStackLock.Lock()
+	defer pe.undoStackLock.Unlock()
+	if len(pe.undoStack) == 0 || pe.undoStack[len(pe.undoStack)-1] != expected {
+		return false
+	}
+	pe.undoStack = pe.undoStack[:len(pe.undoStack)-1]
+	return true
+}
+
+// preparePolicyUndo fetches the previous content of a policy state event before it's replaced,
+// so that !undo can restore it (or remove the policy if there was no previous content).
+func (pe *PolicyEvaluator) preparePolicyUndo(ctx context.Context, policyList id.RoomID, entityType policylist.EntityType, stateKey string, content *event.ModPolicyContent) *undoableAction {
+	var prevContent map[string]any
+	err := pe.Bot.StateEvent(ctx, policyList, entityType.EventType(), stateKey, &prevContent)
+	if err != nil && !errors.Is(err, mautrix.MNotFound) {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to get previous policy content for undo")
+		return nil
+	}
+	if prevContent == nil {
+		prevContent = map[string]any{}
+	}
+	listName := string(policyList)
+	if meta := pe.GetWatchedListMeta(policyList); meta != nil {
+		listName = meta.Name
+	}
+	var description string
+	if content.Recommendation == "" {
+		description = fmt.Sprintf("removal of %s policy for `%s` from %s", entityType, prevContent["entity"], listName)
+	} else {
+		description = fmt.Sprintf("`%s` %s policy for `%s` in %s", content.Recommendation, entityType, content.Entity, listName)
+	}
+	return &undoableAction{
+		description: description,
+		undo: func(ctx context.Context) error {
+			// Use the bot directly to avoid recording the undo itself as an undoable action
+			_, err := pe.Bot.SendStateEvent(ctx, policyList, entityType.EventType(), stateKey, prevContent)
+			return err
+		},
+	}
+}
+
+func (pe *PolicyEvaluator) undoLastAction(ctx context.Context, evt *event.Event) {
+	pe.undoStackLock.Lock()
+	if len(pe.undoStack) == 0 {
+		pe.undoStackLock.Unlock()
+		pe.sendNotice(ctx, "There are no recent actions to undo")
+		return
+	}
+	last := pe.undoStack[len(pe.undoStack)-1]
+	pe.undoStackLock.Unlock()
+	if last.undo == nil {
+		pe.popUndo(last)
+		pe.sendNotice(ctx, "The last action (%s, %s ago) can't be undone. Run `!undo` again to undo the action before it.",
+			last.description, time.Since(last.timestamp).Truncate(time.Second))
+		return
+	}
+	pe.requestConfirmation(ctx, fmt.Sprintf("Undo the last action (%s, %s ago)?",
+		last.description, time.Since(last.timestamp).Truncate(time.Second)), func(ctx context.Context) {
+		if !pe.popUndo(last) {
+			pe.sendNotice(ctx, "The last action changed while waiting for confirmation, not undoing anything")
+			return
+		}
+		err := last.undo(ctx)
+		if err != nil {
+			pe.sendNotice(ctx, "Failed to undo %s: %v", last.description, err)
+			return
+		}
+		zerolog.Ctx(ctx).Info().Str("action", last.description).Msg("Undid action")
+		pe.sendSuccessReaction(ctx, evt.ID)
+	})
+}
@@ -0,0 +1,122 @@
+package policyeval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"maunium.net/go/mautrix"
+)
+
+// VersionInfo describes the running Meowlnir version.
+type VersionInfo struct {
+	// Version is the plain version number, possibly with a +dev suffix and commit hash.
+	Version string
+	// Linkified is the version formatted as a markdown link to the release or commit.
+	Linkified string
+}
+
+const updateCheckCacheTime = 1 * time.Hour
+
+var updateCheckCache struct {
+	sync.Mutex
+	url        string
+	latest     string
+	releaseURL string
+	checkedAt  time.Time
+}
+
+type latestRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// fetchLatestRelease returns the latest release tag from the configured release endpoint,
+// which is expected to respond in the same format as GitHub's latest release API.
+// Results are cached for an hour to avoid hammering the API.
+func fetchLatestRelease(ctx context.Context, url string) (tag, releaseURL string, err error) {
+	updateCheckCache.Lock()
+	defer updateCheckCache.Unlock()
+	if updateCheckCache.url == url && time.Since(updateCheckCache.checkedAt) < updateCheckCacheTime {
+		return updateCheckCache.latest, updateCheckCache.releaseURL, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to prepare request: %w", err)
+	}
+	req.Header.Set("User-Agent", mautrix.DefaultUserAgent)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	var release latestRelease
+	if err = json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", "", fmt.Errorf("failed to parse response: %w", err)
+	} else if release.TagName == "" {
+		return "", "", fmt.Errorf("response didn't contain a tag name")
+	}
+	updateCheckCache.url = url
+	updateCheckCache.latest = release.TagName
+	updateCheckCache.releaseURL = release.HTMLURL
+	updateCheckCache.checkedAt = time.Now()
+	return release.TagName, release.HTMLURL, nil
+}
+
+// compareVersions compares the numeric parts of two dotted version strings like 0.2.0,
+// ignoring any v prefix and suffixes like +dev.
+func compareVersions(a, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(strings.TrimRightFunc(partsA[i], isNotDigit))
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(strings.TrimRightFunc(partsB[i], isNotDigit))
+		}
+		if numA != numB {
+			return numA - numB
+		}
+	}
+	return 0
+}
+
+func isNotDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
+
+func (pe *PolicyEvaluator) sendVersion(ctx context.Context, check bool) {
+	if !check {
+		pe.sendNotice(ctx, "Running Meowlnir %s", pe.version.Linkified)
+		return
+	} else if !pe.Config.UpdateCheck.Enabled {
+		pe.sendNotice(ctx, "Running Meowlnir %s. Update checks are disabled in the config.", pe.version.Linkified)
+		return
+	}
+	latest, releaseURL, err := fetchLatestRelease(ctx, pe.Config.UpdateCheck.URL)
+	if err != nil {
+		pe.sendNotice(ctx, "Running Meowlnir %s. Failed to check for updates: %v", pe.version.Linkified, err)
+		return
+	}
+	// Development builds are based on the previous release, so the latest release is an update
+	// only if it's strictly newer than the version number the build was made from.
+	current, _, _ := strings.Cut(pe.version.Version, "+")
+	if compareVersions(latest, current) > 0 {
+		pe.sendNotice(ctx, "Running Meowlnir %s. An update is available: [%s](%s)", pe.version.Linkified, latest, releaseURL)
+	} else {
+		pe.sendNotice(ctx, "Running Meowlnir %s, which is up to date (latest release is %s)", pe.version.Linkified, latest)
+	}
+}
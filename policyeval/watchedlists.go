@@ -2,15 +2,22 @@ package policyeval
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/rs/zerolog"
 	"go.mau.fi/util/exslices"
+	"maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 
 	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/database"
 )
 
 func (pe *PolicyEvaluator) IsWatchingList(roomID id.RoomID) bool {
@@ -27,10 +34,40 @@ func (pe *PolicyEvaluator) GetWatchedListMeta(roomID id.RoomID) *config.WatchedP
 	return meta
 }
 
+// checkListWriteAccess returns true if the user is allowed to send policies to the given list using commands,
+// and sends an error notice otherwise.
+func (pe *PolicyEvaluator) checkListWriteAccess(ctx context.Context, list *config.WatchedPolicyList, userID id.UserID) bool {
+	if list.CanWrite(userID) {
+		return true
+	}
+	pe.sendNotice(ctx, "[%s](%s) is not authorized to modify the `%s` list", userID, userID.URI().MatrixToURL(), list.Shortcode)
+	return false
+}
+
+func (pe *PolicyEvaluator) getListCategories(roomID id.RoomID) []string {
+	meta := pe.GetWatchedListMeta(roomID)
+	if meta == nil {
+		return nil
+	}
+	return meta.Categories
+}
+
+// listCategorySuffix returns a suffix for notices that describes which category of list caused an action.
+func (pe *PolicyEvaluator) listCategorySuffix(roomID id.RoomID) string {
+	categories := pe.getListCategories(roomID)
+	if len(categories) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (by the %s list)", strings.Join(categories, "/"))
+}
+
 func (pe *PolicyEvaluator) FindListByShortcode(shortcode string) *config.WatchedPolicyList {
 	shortcode = strings.ToLower(shortcode)
 	pe.watchedListsLock.RLock()
 	defer pe.watchedListsLock.RUnlock()
+	if _, ambiguous := pe.ambiguousShortcodes[shortcode]; ambiguous {
+		return nil
+	}
 	for _, meta := range pe.watchedListsMap {
 		if strings.ToLower(meta.Shortcode) == shortcode {
 			return meta
@@ -39,6 +76,30 @@ func (pe *PolicyEvaluator) FindListByShortcode(shortcode string) *config.Watched
 	return nil
 }
 
+func (pe *PolicyEvaluator) isShortcodeAmbiguous(shortcode string) bool {
+	pe.watchedListsLock.RLock()
+	_, ambiguous := pe.ambiguousShortcodes[strings.ToLower(shortcode)]
+	pe.watchedListsLock.RUnlock()
+	return ambiguous
+}
+
+// describeMissingList explains why FindListByShortcode didn't return a list for the given shortcode.
+func (pe *PolicyEvaluator) describeMissingList(shortcode string) string {
+	if pe.isShortcodeAmbiguous(shortcode) {
+		return fmt.Sprintf("shortcode %q is ambiguous, as it's used by multiple watched lists", shortcode)
+	}
+	return fmt.Sprintf("list %q not found", shortcode)
+}
+
+func (pe *PolicyEvaluator) sendListNotFound(ctx context.Context, shortcode string) {
+	if pe.isShortcodeAmbiguous(shortcode) {
+		pe.sendNotice(ctx, "Refusing to use shortcode %q, as it's used by multiple watched lists. "+
+			"Change the shortcode of one of them in the watched lists config.", shortcode)
+	} else {
+		pe.sendNotice(ctx, "List %q not found", shortcode)
+	}
+}
+
 func (pe *PolicyEvaluator) GetWatchedLists() []id.RoomID {
 	pe.watchedListsLock.RLock()
 	defer pe.watchedListsLock.RUnlock()
@@ -50,10 +111,39 @@ func (pe *PolicyEvaluator) handleWatchedLists(ctx context.Context, evt *event.Ev
 	if !ok {
 		return nil, []string{"* Failed to parse watched lists event"}
 	}
+	lists := make([]config.WatchedPolicyList, 0, len(content.Lists))
+	shortcodes := make(map[string]*config.WatchedPolicyList, len(content.Lists))
+	ambiguousShortcodes := make(map[string]struct{})
+	for _, listInfo := range content.Lists {
+		if err := pe.checkWatchableRoom(listInfo.RoomID); err != nil {
+			errors = append(errors, fmt.Sprintf("* Not watching [%s](%s): %v", listInfo.Name, listInfo.RoomID.URI().MatrixToURL(), err))
+			continue
+		}
+		shortcode := strings.ToLower(listInfo.Shortcode)
+		// Duplicate room IDs are reported separately below
+		if existing, ok := shortcodes[shortcode]; ok && existing.RoomID != listInfo.RoomID {
+			if pe.Config.DuplicateShortcodes == config.DuplicateShortcodesFirst {
+				errors = append(errors, fmt.Sprintf(
+					"* Not watching [%s](%s): shortcode `%s` is already used by [%s](%s)",
+					listInfo.Name, listInfo.RoomID.URI().MatrixToURL(), listInfo.Shortcode, existing.Name, existing.RoomID.URI().MatrixToURL(),
+				))
+				continue
+			}
+			ambiguousShortcodes[shortcode] = struct{}{}
+			errors = append(errors, fmt.Sprintf(
+				"* Shortcode `%s` is used by both [%s](%s) and [%s](%s), commands won't accept it until one of them is changed",
+				listInfo.Shortcode, existing.Name, existing.RoomID.URI().MatrixToURL(), listInfo.Name, listInfo.RoomID.URI().MatrixToURL(),
+			))
+		} else if !ok && shortcode != "" {
+			// Lists without a shortcode can't be referenced in commands, so they can't conflict
+			shortcodes[shortcode] = &listInfo
+		}
+		lists = append(lists, listInfo)
+	}
 	var wg sync.WaitGroup
 	var outLock sync.Mutex
-	wg.Add(len(content.Lists))
-	for _, listInfo := range content.Lists {
+	wg.Add(len(lists))
+	for _, listInfo := range lists {
 		go func() {
 			defer wg.Done()
 			if !pe.Store.Contains(listInfo.RoomID) {
@@ -69,9 +159,9 @@ func (pe *PolicyEvaluator) handleWatchedLists(ctx context.Context, evt *event.Ev
 		}()
 	}
 	wg.Wait()
-	watchedList := make([]id.RoomID, 0, len(content.Lists))
-	watchedMap := make(map[id.RoomID]*config.WatchedPolicyList, len(content.Lists))
-	for _, listInfo := range content.Lists {
+	watchedList := make([]id.RoomID, 0, len(lists))
+	watchedMap := make(map[id.RoomID]*config.WatchedPolicyList, len(lists))
+	for _, listInfo := range lists {
 		if _, alreadyWatched := watchedMap[listInfo.RoomID]; alreadyWatched {
 			errors = append(errors, fmt.Sprintf("* Duplicate watched list [%s](%s)", listInfo.Name, listInfo.RoomID.URI().MatrixToURL()))
 		} else {
@@ -83,8 +173,10 @@ func (pe *PolicyEvaluator) handleWatchedLists(ctx context.Context, evt *event.Ev
 	}
 	pe.watchedListsLock.Lock()
 	oldWatchedList := pe.watchedListsList
+	oldWatchedMap := pe.watchedListsMap
 	pe.watchedListsMap = watchedMap
 	pe.watchedListsList = watchedList
+	pe.ambiguousShortcodes = ambiguousShortcodes
 	pe.watchedListsLock.Unlock()
 	if !isInitial {
 		unsubscribed, subscribed := exslices.Diff(oldWatchedList, watchedList)
@@ -94,6 +186,12 @@ func (pe *PolicyEvaluator) handleWatchedLists(ctx context.Context, evt *event.Ev
 		for _, roomID := range unsubscribed {
 			output = append(output, fmt.Sprintf("* Unsubscribed from [%s](%s)", roomID, roomID.URI().MatrixToURL()))
 		}
+		var unwatched []id.RoomID
+		for roomID := range oldWatchedMap {
+			if _, stillWatched := watchedMap[roomID]; !stillWatched {
+				unwatched = append(unwatched, roomID)
+			}
+		}
 		go func(ctx context.Context) {
 			if len(unsubscribed) > 0 {
 				pe.ReevaluateAffectedByLists(ctx, unsubscribed)
@@ -101,7 +199,195 @@ func (pe *PolicyEvaluator) handleWatchedLists(ctx context.Context, evt *event.Ev
 			if len(subscribed) > 0 || len(unsubscribed) > 0 {
 				pe.EvaluateAll(ctx)
 			}
+			if len(unwatched) > 0 {
+				pe.retryProtectingUnwatchedRooms(ctx, unwatched)
+			}
 		}(context.WithoutCancel(ctx))
 	}
 	return
 }
+
+func (pe *PolicyEvaluator) sendWatchedListInfo(ctx context.Context, withStats bool) {
+	pe.watchedListsLock.RLock()
+	lists := slices.Collect(maps.Values(pe.watchedListsMap))
+	pe.watchedListsLock.RUnlock()
+	if len(lists) == 0 {
+		pe.sendNotice(ctx, "Not watching any policy lists")
+		return
+	}
+	slices.SortFunc(lists, func(a, b *config.WatchedPolicyList) int {
+		return strings.Compare(a.Shortcode, b.Shortcode)
+	})
+	var stats map[id.RoomID]*database.ListStats
+	if withStats {
+		allStats, err := pe.DB.ListStats.GetAll(ctx, pe.ManagementRoom)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Msg("Failed to get policy list stats")
+			pe.sendNotice(ctx, "Failed to get policy list stats: %v", err)
+			return
+		}
+		stats = make(map[id.RoomID]*database.ListStats, len(allStats))
+		for _, stat := range allStats {
+			stats[stat.PolicyList] = stat
+		}
+	}
+	lines := make([]string, len(lists))
+	for i, list := range lists {
+		var flags []string
+		if list.DontApply {
+			flags = append(flags, "not applied")
+		}
+		if list.AutoUnban {
+			flags = append(flags, "auto-unban")
+		}
+		if list.ActionOverride != "" {
+			flags = append(flags, fmt.Sprintf("%s instead of ban", list.ActionOverride))
+		}
+		if len(list.Categories) > 0 {
+			flags = append(flags, fmt.Sprintf("categories: %s", strings.Join(list.Categories, ", ")))
+		}
+		var flagStr string
+		if len(flags) > 0 {
+			flagStr = fmt.Sprintf(" (%s)", strings.Join(flags, ", "))
+		}
+		lines[i] = fmt.Sprintf("* `%s`: %s [%s](%s)%s", list.Shortcode, list.Name, list.RoomID, list.RoomID.URI().MatrixToURL(), flagStr)
+		if withStats {
+			if stat, ok := stats[list.RoomID]; ok {
+				lines[i] += fmt.Sprintf(" - %s, last at %s", pluralize(int(stat.HitCount), "action"), stat.LastHitAt.Format(time.RFC3339))
+			} else {
+				lines[i] += " - never resulted in an action"
+			}
+		}
+	}
+	pe.sendNotice(ctx, "Watching %s:\n\n%s", pluralize(len(lists), "list"), strings.Join(lines, "\n"))
+}
+
+func (pe *PolicyEvaluator) GetWatchedListsContent(ctx context.Context) (*config.WatchedListsEventContent, error) {
+	var content config.WatchedListsEventContent
+	err := pe.Bot.StateEvent(ctx, pe.ManagementRoom, config.StateWatchedLists, "", &content)
+	if err != nil && !errors.Is(err, mautrix.MNotFound) {
+		return nil, fmt.Errorf("failed to get watched lists event: %w", err)
+	}
+	return &content, nil
+}
+
+// checkWatchableRoom returns an error if the given room can't be used as a watched policy list,
+// i.e. if it's the management room or a protected room.
+func (pe *PolicyEvaluator) checkWatchableRoom(roomID id.RoomID) error {
+	if roomID == pe.ManagementRoom {
+		return fmt.Errorf("the management room can't be a watched list")
+	} else if pe.IsProtectedRoom(roomID) {
+		return fmt.Errorf("protected rooms can't be watched lists")
+	}
+	return nil
+}
+
+func (pe *PolicyEvaluator) watchList(ctx context.Context, roomIDOrAlias, shortcode string, dontApply bool) {
+	content, err := pe.GetWatchedListsContent(ctx)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get current watched lists: %v", err)
+		return
+	}
+	for _, list := range content.Lists {
+		if strings.EqualFold(list.Shortcode, shortcode) {
+			pe.sendNotice(ctx, "Shortcode `%s` is already used by [%s](%s)", shortcode, list.Name, list.RoomID.URI().MatrixToURL())
+			return
+		}
+	}
+	// Validate the room before joining it, so that the bot doesn't end up in rooms it can't watch
+	roomID, err := pe.resolveRoom(ctx, roomIDOrAlias)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to resolve %q: %v", roomIDOrAlias, err)
+		return
+	} else if err = pe.checkWatchableRoom(roomID); err != nil {
+		pe.sendNotice(ctx, "Can't watch [%s](%s): %v", roomID, roomID.URI().MatrixToURL(), err)
+		return
+	}
+	_, err = pe.Bot.JoinRoom(ctx, roomIDOrAlias, nil)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to join policy list %q: %v", roomIDOrAlias, err)
+		return
+	}
+	if slices.ContainsFunc(content.Lists, func(list config.WatchedPolicyList) bool {
+		return list.RoomID == roomID
+	}) {
+		pe.sendNotice(ctx, "Already watching [%s](%s)", roomID, roomID.URI().MatrixToURL())
+		return
+	}
+	name := shortcode
+	var nameContent event.RoomNameEventContent
+	err = pe.Bot.StateEvent(ctx, roomID, event.StateRoomName, "", &nameContent)
+	if err != nil {
+		zerolog.Ctx(ctx).Debug().Err(err).Stringer("room_id", roomID).Msg("Failed to get policy list name")
+	} else if nameContent.Name != "" {
+		name = nameContent.Name
+	}
+	content.Lists = append(content.Lists, config.WatchedPolicyList{
+		RoomID:    roomID,
+		Name:      name,
+		Shortcode: shortcode,
+		DontApply: dontApply,
+	})
+	_, err = pe.Bot.SendStateEvent(ctx, pe.ManagementRoom, config.StateWatchedLists, "", content)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to update watched lists: %v", err)
+		return
+	}
+	var applyStr string
+	if dontApply {
+		applyStr = " (policies won't be applied)"
+	}
+	pe.sendNotice(ctx, "Added %s [%s](%s) to watched lists as `%s`%s", name, roomID, roomID.URI().MatrixToURL(), shortcode, applyStr)
+}
+
+func (pe *PolicyEvaluator) unwatchList(ctx context.Context, shortcode string) {
+	content, err := pe.GetWatchedListsContent(ctx)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to get current watched lists: %v", err)
+		return
+	}
+	idx := slices.IndexFunc(content.Lists, func(list config.WatchedPolicyList) bool {
+		return strings.EqualFold(list.Shortcode, shortcode)
+	})
+	if idx < 0 {
+		pe.sendNotice(ctx, "List %q not found", shortcode)
+		return
+	} else if pe.isShortcodeAmbiguous(shortcode) {
+		pe.sendListNotFound(ctx, shortcode)
+		return
+	}
+	removed := content.Lists[idx]
+	content.Lists = slices.Delete(content.Lists, idx, idx+1)
+	_, err = pe.Bot.SendStateEvent(ctx, pe.ManagementRoom, config.StateWatchedLists, "", content)
+	if err != nil {
+		pe.sendNotice(ctx, "Failed to update watched lists: %v", err)
+		return
+	}
+	pe.sendNotice(ctx, "Removed %s [%s](%s) from watched lists, %s remaining",
+		removed.Name, removed.RoomID, removed.RoomID.URI().MatrixToURL(), pluralize(len(content.Lists), "list"))
+}
+
+func (pe *PolicyEvaluator) refreshList(ctx context.Context, shortcode string) {
+	list := pe.FindListByShortcode(shortcode)
+	if list == nil {
+		pe.sendListNotFound(ctx, shortcode)
+		return
+	}
+	start := time.Now()
+	state, err := pe.Bot.State(ctx, list.RoomID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("policy_list", list.RoomID).Msg("Failed to get policy list state for refresh")
+		pe.sendNotice(ctx, "Failed to get room state for %s [%s](%s): %v", list.Name, list.RoomID, list.RoomID.URI().MatrixToURL(), err)
+		return
+	}
+	pe.Store.Add(list.RoomID, state)
+	policyCount := len(pe.Store.GetRoom(list.RoomID).Policies())
+	fetchDuration := time.Since(start)
+	pe.sendNotice(ctx, "Reloaded %d policies from %s [%s](%s) in %s, re-evaluating users",
+		policyCount, list.Name, list.RoomID, list.RoomID.URI().MatrixToURL(), fetchDuration)
+	if !list.DontApply {
+		pe.ReevaluateAffectedByLists(ctx, []id.RoomID{list.RoomID})
+		pe.EvaluateAll(ctx)
+	}
+	pe.sendNotice(ctx, "Finished re-evaluating users after refreshing %s", list.Name)
+}
@@ -0,0 +1,51 @@
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"maunium.net/go/mautrix/event"
+
+	"go.mau.fi/meowlnir/config"
+	"go.mau.fi/meowlnir/policylist"
+)
+
+// sendWhoami tells the sender what they're allowed to do in this management room and the watched policy lists.
+func (pe *PolicyEvaluator) sendWhoami(ctx context.Context, evt *event.Event) {
+	lines := []string{fmt.Sprintf("[%s](%s):", evt.Sender, evt.Sender.URI().MatrixToURL())}
+	var powerLevels event.PowerLevelsEventContent
+	err := pe.Bot.StateEvent(ctx, pe.ManagementRoom, event.StatePowerLevels, "", &powerLevels)
+	if err != nil {
+		lines = append(lines, fmt.Sprintf("* Failed to get management room power levels: %v", err))
+	} else {
+		lines = append(lines, fmt.Sprintf(
+			"* Power level %d in the management room (admins need more than %d)",
+			powerLevels.GetUserLevel(evt.Sender), powerLevels.GetEventLevel(config.StateWatchedLists),
+		))
+	}
+	// Commands (including this one) are only handled for admins on trusted devices
+	lines = append(lines, "* Admin in this management room")
+	if !evt.Mautrix.WasEncrypted {
+		lines = append(lines, "* Command was not encrypted, so device trust was not checked")
+	} else {
+		lines = append(lines, fmt.Sprintf("* Device trust state is `%s`, which is sufficient for all commands", evt.Mautrix.TrustState))
+	}
+	botWritable := pe.getWritableLists(ctx, policylist.EntityTypeUser)
+	var writable []string
+	for _, list := range pe.getListsWritableBy(ctx, evt.Sender, policylist.EntityTypeUser) {
+		var botSuffix string
+		if !slices.Contains(botWritable, list) {
+			botSuffix = " (but the bot can't, so `!ban` won't work)"
+		}
+		writable = append(writable, fmt.Sprintf("  * `%s` - %s%s", list.Shortcode, list.Name, botSuffix))
+	}
+	if len(writable) == 0 {
+		lines = append(lines, "* Can't write policies to any watched list")
+	} else {
+		lines = append(lines, "* Can write policies to:")
+		lines = append(lines, writable...)
+	}
+	pe.sendNotice(ctx, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,91 @@
+package policylist
+
+import (
+	"reflect"
+	"regexp"
+
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+)
+
+// StatePolicyContent is a custom policy event type for rules that match message content instead of entities.
+var StatePolicyContent = event.Type{Type: "fi.mau.meowlnir.policy.content", Class: event.StateEventType}
+
+// ContentRecommendationRedact is a content policy recommendation that only redacts matching messages
+// instead of also banning the sender.
+const ContentRecommendationRedact event.PolicyRecommendation = "fi.mau.meowlnir.redact"
+
+// ContentPolicyEventContent represents the content of a content policy event.
+type ContentPolicyEventContent struct {
+	// Pattern is a regular expression that is matched against the plaintext body of messages.
+	Pattern        string                     `json:"pattern"`
+	Recommendation event.PolicyRecommendation `json:"recommendation"`
+	Reason         string                     `json:"reason"`
+}
+
+func init() {
+	event.TypeMap[StatePolicyContent] = reflect.TypeOf(ContentPolicyEventContent{})
+}
+
+// ContentPolicy represents a single content policy event with the relevant data parsed out.
+type ContentPolicy struct {
+	*ContentPolicyEventContent
+	Regex *regexp.Regexp
+
+	RoomID    id.RoomID
+	StateKey  string
+	Sender    id.UserID
+	Timestamp int64
+	ID        id.EventID
+}
+
+// updateContentPolicy updates the content policy with the state key of the given event.
+// Invalid patterns are treated the same way as removing the policy.
+func (r *Room) updateContentPolicy(evt *event.Event) {
+	content, ok := evt.Content.Parsed.(*ContentPolicyEventContent)
+	if !ok || evt.StateKey == nil {
+		return
+	}
+	r.byEventID[evt.ID] = typeStateKeyTuple{Type: evt.Type, StateKey: *evt.StateKey}
+	var compiled *regexp.Regexp
+	if content.Pattern != "" && content.Recommendation != "" {
+		compiled, _ = regexp.Compile(content.Pattern)
+	}
+	r.contentRulesLock.Lock()
+	defer r.contentRulesLock.Unlock()
+	if compiled == nil {
+		delete(r.contentRules, *evt.StateKey)
+		return
+	}
+	if content.Recommendation == event.PolicyRecommendationUnstableBan {
+		content.Recommendation = event.PolicyRecommendationBan
+	}
+	r.contentRules[*evt.StateKey] = &ContentPolicy{
+		ContentPolicyEventContent: content,
+		Regex:                     compiled,
+
+		RoomID:    evt.RoomID,
+		StateKey:  *evt.StateKey,
+		Sender:    evt.Sender,
+		Timestamp: evt.Timestamp,
+		ID:        evt.ID,
+	}
+}
+
+func (r *Room) removeContentPolicy(stateKey string) {
+	r.contentRulesLock.Lock()
+	delete(r.contentRules, stateKey)
+	r.contentRulesLock.Unlock()
+}
+
+// MatchContent finds all content policies in the room that match the given message body.
+func (r *Room) MatchContent(body string) (output []*ContentPolicy) {
+	r.contentRulesLock.RLock()
+	defer r.contentRulesLock.RUnlock()
+	for _, rule := range r.contentRules {
+		if rule.Regex.MatchString(body) {
+			output = append(output, rule)
+		}
+	}
+	return
+}
@@ -112,6 +112,17 @@ func (l *List) Remove(eventType event.Type, stateKey string) *Policy {
 	return nil
 }
 
+// Policies returns all policies in the list, including ignored ones.
+func (l *List) Policies() []*Policy {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	output := make([]*Policy, 0, len(l.byStateKey))
+	for _, node := range l.byStateKey {
+		output = append(output, node.Policy)
+	}
+	return output
+}
+
 var matchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 	Name: "meowlnir_policylist_match_duration_nanoseconds",
 	Help: "Time taken to evaluate an entity against all policies",
@@ -1,6 +1,8 @@
 package policylist
 
 import (
+	"regexp"
+
 	"go.mau.fi/util/glob"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
@@ -19,22 +21,56 @@ type Policy struct {
 	Timestamp  int64
 	ID         id.EventID
 	Ignored    bool
+
+	SilentReason bool
+	ReasonCode   string
+}
+
+// SilentReasonKey is a custom field in policy events that tells Meowlnir
+// to not include the reason when applying the policy.
+const SilentReasonKey = "fi.mau.meowlnir.silent_reason"
+
+// ReasonCodeKey is a custom field in policy events that contains a machine-readable reason category
+// (e.g. `spam` or `tos`) in addition to the free-text reason.
+const ReasonCodeKey = "fi.mau.meowlnir.reason_code"
+
+var reasonCodeRegex = regexp.MustCompile(`^[a-z0-9_.-]{1,64}$`)
+
+// IsValidReasonCode checks whether the given string is an acceptable reason code.
+func IsValidReasonCode(code string) bool {
+	return reasonCodeRegex.MatchString(code)
 }
 
 // Match represent a list of policies that matched a specific entity.
 type Match []*Policy
 
+// Recommendations is the result of aggregating a match, including details about why the winning policy won.
 type Recommendations struct {
+	// BanOrUnban is the highest priority ban or unban policy, which decides whether the entity is banned.
 	BanOrUnban *Policy
+	// Priority is the index of BanOrUnban in the match. Lower is higher priority.
+	Priority int
+	// OverriddenBans contains the lower priority ban policies that didn't apply because BanOrUnban is an unban.
+	OverriddenBans []*Policy
+}
+
+// IsBan returns true if the winning recommendation is a ban.
+func (r Recommendations) IsBan() bool {
+	return r.BanOrUnban != nil && r.BanOrUnban.Recommendation == event.PolicyRecommendationBan
 }
 
 // Recommendations aggregates the recommendations in the match.
 func (m Match) Recommendations() (output Recommendations) {
-	for _, policy := range m {
+	output.Priority = -1
+	for i, policy := range m {
 		switch policy.Recommendation {
 		case event.PolicyRecommendationBan, event.PolicyRecommendationUnban:
 			if output.BanOrUnban == nil {
 				output.BanOrUnban = policy
+				output.Priority = i
+			} else if output.BanOrUnban.Recommendation == event.PolicyRecommendationUnban &&
+				policy.Recommendation == event.PolicyRecommendationBan {
+				output.OverriddenBans = append(output.OverriddenBans, policy)
 			}
 		}
 	}
@@ -1,6 +1,8 @@
 package policylist
 
 import (
+	"sync"
+
 	"go.mau.fi/util/glob"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
@@ -18,6 +20,9 @@ type Room struct {
 	RoomRules   *List
 	ServerRules *List
 	byEventID   map[id.EventID]typeStateKeyTuple
+
+	contentRules     map[string]*ContentPolicy
+	contentRulesLock sync.RWMutex
 }
 
 // NewRoom creates a new store for a single policy room.
@@ -28,6 +33,8 @@ func NewRoom(roomID id.RoomID) *Room {
 		RoomRules:   NewList(roomID, "room"),
 		ServerRules: NewList(roomID, "server"),
 		byEventID:   make(map[id.EventID]typeStateKeyTuple),
+
+		contentRules: make(map[string]*ContentPolicy),
 	}
 }
 
@@ -43,6 +50,11 @@ func (r *Room) GetServerRules() *List {
 	return r.ServerRules
 }
 
+// Policies returns all user, room and server policies in the room.
+func (r *Room) Policies() []*Policy {
+	return append(append(r.UserRules.Policies(), r.RoomRules.Policies()...), r.ServerRules.Policies()...)
+}
+
 type EntityType string
 
 func (et EntityType) EventType() event.Type {
@@ -77,6 +89,8 @@ func (r *Room) Update(evt *event.Event) (added, removed *Policy) {
 		added, removed = r.updatePolicyList(evt, EntityTypeRoom, r.RoomRules)
 	case event.StatePolicyServer, event.StateLegacyPolicyServer, event.StateUnstablePolicyServer:
 		added, removed = r.updatePolicyList(evt, EntityTypeServer, r.ServerRules)
+	case StatePolicyContent:
+		r.updateContentPolicy(evt)
 	case event.EventRedaction:
 		redacts := evt.Redacts
 		if redacts == "" {
@@ -91,6 +105,8 @@ func (r *Room) Update(evt *event.Event) (added, removed *Policy) {
 				removed = r.RoomRules.Remove(target.Type, target.StateKey)
 			case event.StatePolicyServer, event.StateLegacyPolicyServer, event.StateUnstablePolicyServer:
 				removed = r.ServerRules.Remove(target.Type, target.StateKey)
+			case StatePolicyContent:
+				r.removeContentPolicy(target.StateKey)
 			}
 		}
 	}
@@ -105,6 +121,9 @@ func (r *Room) ParseState(state map[event.Type]map[string]*event.Event) *Room {
 	r.massUpdatePolicyList(userPolicies, EntityTypeUser, r.UserRules)
 	r.massUpdatePolicyList(roomPolicies, EntityTypeRoom, r.RoomRules)
 	r.massUpdatePolicyList(serverPolicies, EntityTypeServer, r.ServerRules)
+	for _, evt := range state[StatePolicyContent] {
+		r.updateContentPolicy(evt)
+	}
 	return r
 }
 
@@ -131,6 +150,9 @@ func (r *Room) massUpdatePolicyList(input map[string]*event.Event, entityType En
 
 var HackyRuleFilter []string
 
+// RecommendationMapping maps non-standard recommendations used by some policy lists to ones Meowlnir understands.
+var RecommendationMapping map[event.PolicyRecommendation]event.PolicyRecommendation
+
 func (r *Room) updatePolicyList(evt *event.Event, entityType EntityType, rules *List) (added, removed *Policy) {
 	content, ok := evt.Content.Parsed.(*event.ModPolicyContent)
 	if !ok || evt.StateKey == nil {
@@ -141,9 +163,15 @@ func (r *Room) updatePolicyList(evt *event.Event, entityType EntityType, rules *
 		removed = rules.Remove(evt.Type, *evt.StateKey)
 		return
 	}
+	if mapped, ok := RecommendationMapping[content.Recommendation]; ok {
+		content.Recommendation = mapped
+	}
 	if content.Recommendation == event.PolicyRecommendationUnstableBan {
 		content.Recommendation = event.PolicyRecommendationBan
 	}
+	if entityType == EntityTypeServer {
+		content.Entity = normalizeHost(content.Entity)
+	}
 	added = &Policy{
 		ModPolicyContent: content,
 		Pattern:          glob.Compile(content.Entity),
@@ -156,6 +184,10 @@ func (r *Room) updatePolicyList(evt *event.Event, entityType EntityType, rules *
 		Timestamp:  evt.Timestamp,
 		ID:         evt.ID,
 	}
+	added.SilentReason, _ = evt.Content.Raw[SilentReasonKey].(bool)
+	if code, _ := evt.Content.Raw[ReasonCodeKey].(string); IsValidReasonCode(code) {
+		added.ReasonCode = code
+	}
 	if added.Recommendation == event.PolicyRecommendationBan {
 		for _, entry := range HackyRuleFilter {
 			if added.Pattern.Match(entry) {
@@ -0,0 +1,51 @@
+package policylist
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// normalizeHost lowercases the given host and converts any internationalized labels to punycode,
+// so that the Unicode and `xn--` forms of the same domain are treated identically.
+// Labels that can't be converted (e.g. because they contain glob characters) are left as-is.
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if isASCII(host) {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		} else if ascii, err := idna.Lookup.ToASCII(label); err == nil {
+			labels[i] = ascii
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+func isASCII(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeServerName prepares a server name for matching against server policies.
+// The port is removed (like in server ACLs), and the host is lowercased and converted to punycode.
+func NormalizeServerName(serverName string) string {
+	if strings.HasPrefix(serverName, "[") {
+		// IPv6 literal
+		if end := strings.IndexByte(serverName, ']'); end > 0 {
+			return strings.ToLower(serverName[:end+1])
+		}
+		return strings.ToLower(serverName)
+	} else if idx := strings.LastIndexByte(serverName, ':'); idx >= 0 {
+		serverName = serverName[:idx]
+	}
+	return normalizeHost(serverName)
+}
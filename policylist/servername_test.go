@@ -0,0 +1,81 @@
+package policylist
+
+import (
+	"testing"
+
+	"go.mau.fi/util/glob"
+)
+
+func TestNormalizeServerName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"ASCII", "example.com", "example.com"},
+		{"MixedCase", "ExAmPle.COM", "example.com"},
+		{"TrailingDot", "example.com.", "example.com"},
+		{"Port", "example.com:8448", "example.com"},
+		{"Unicode", "bücher.example", "xn--bcher-kva.example"},
+		{"UnicodeMixedCase", "BÜCHER.example", "xn--bcher-kva.example"},
+		{"UnicodePort", "bücher.example:8448", "xn--bcher-kva.example"},
+		{"Punycode", "xn--bcher-kva.example", "xn--bcher-kva.example"},
+		{"PunycodeMixedCase", "XN--BCHER-KVA.Example", "xn--bcher-kva.example"},
+		// Cyrillic "а" looks like a Latin "a", but must not normalize to the same name
+		{"CyrillicHomograph", "аpple.com", "xn--pple-43d.com"},
+		{"IPv4", "127.0.0.1", "127.0.0.1"},
+		{"IPv4Port", "127.0.0.1:8448", "127.0.0.1"},
+		{"IPv6", "[::1]", "[::1]"},
+		{"IPv6Port", "[::1]:8448", "[::1]"},
+		{"IPv6MixedCase", "[2001:DB8::1]:8448", "[2001:db8::1]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := NormalizeServerName(test.input); output != test.expected {
+				t.Errorf("NormalizeServerName(%q) = %q, expected %q", test.input, output, test.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeHost_Globs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Wildcard", "*.example.com", "*.example.com"},
+		{"WildcardMixedCase", "*.EXAMPLE.com", "*.example.com"},
+		{"WildcardUnicode", "*.bücher.example", "*.xn--bcher-kva.example"},
+		{"WildcardPunycode", "*.xn--bcher-kva.example", "*.xn--bcher-kva.example"},
+		// Labels with glob characters can't be converted to punycode, so they're only lowercased
+		{"GlobInUnicodeLabel", "bü*.example", "bü*.example"},
+		{"QuestionMark", "ex?mple.com", "ex?mple.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := normalizeHost(test.input); output != test.expected {
+				t.Errorf("normalizeHost(%q) = %q, expected %q", test.input, output, test.expected)
+			}
+		})
+	}
+}
+
+func TestMatchServer_Homographs(t *testing.T) {
+	unicodePolicy := glob.Compile(normalizeHost("bücher.example"))
+	for _, server := range []string{"bücher.example", "xn--bcher-kva.example", "BÜCHER.example:8448"} {
+		if !unicodePolicy.Match(NormalizeServerName(server)) {
+			t.Errorf("expected policy for bücher.example to match %q", server)
+		}
+	}
+	latinPolicy := glob.Compile(normalizeHost("apple.com"))
+	if latinPolicy.Match(NormalizeServerName("аpple.com")) {
+		t.Errorf("policy for apple.com shouldn't match the Cyrillic homograph")
+	}
+	cyrillicPolicy := glob.Compile(normalizeHost("аpple.com"))
+	if !cyrillicPolicy.Match(NormalizeServerName("xn--pple-43d.com")) {
+		t.Errorf("policy for the Cyrillic homograph should match its punycode form")
+	} else if cyrillicPolicy.Match(NormalizeServerName("apple.com")) {
+		t.Errorf("policy for the Cyrillic homograph shouldn't match apple.com")
+	}
+}
@@ -35,8 +35,9 @@ func (s *Store) MatchRoom(listIDs []id.RoomID, roomID id.RoomID) Match {
 }
 
 // MatchServer finds all matching policies for the given server name in the given policy rooms.
+// The server name is normalized with NormalizeServerName before matching.
 func (s *Store) MatchServer(listIDs []id.RoomID, serverName string) Match {
-	return s.match(listIDs, serverName, (*Room).GetServerRules)
+	return s.match(listIDs, NormalizeServerName(serverName), (*Room).GetServerRules)
 }
 
 // Update updates the store with the given policy event.
@@ -53,7 +54,7 @@ func (s *Store) Update(evt *event.Event) (added, removed *Policy) {
 	case event.StatePolicyUser, event.StateLegacyPolicyUser, event.StateUnstablePolicyUser,
 		event.StatePolicyRoom, event.StateLegacyPolicyRoom, event.StateUnstablePolicyRoom,
 		event.StatePolicyServer, event.StateLegacyPolicyServer, event.StateUnstablePolicyServer,
-		event.EventRedaction:
+		StatePolicyContent, event.EventRedaction:
 	default:
 		return
 	}
@@ -77,6 +78,13 @@ func (s *Store) Add(roomID id.RoomID, state map[event.Type]map[string]*event.Eve
 	s.roomsLock.Unlock()
 }
 
+// GetRoom returns the policy room with the given ID, or nil if it's not in the store.
+func (s *Store) GetRoom(roomID id.RoomID) *Room {
+	s.roomsLock.RLock()
+	defer s.roomsLock.RUnlock()
+	return s.rooms[roomID]
+}
+
 func (s *Store) Contains(roomID id.RoomID) bool {
 	s.roomsLock.RLock()
 	_, ok := s.rooms[roomID]
@@ -84,6 +92,19 @@ func (s *Store) Contains(roomID id.RoomID) bool {
 	return ok
 }
 
+// MatchContent finds all content policies in the given policy rooms that match the given message body.
+func (s *Store) MatchContent(listIDs []id.RoomID, body string) (output []*ContentPolicy) {
+	for _, roomID := range listIDs {
+		s.roomsLock.RLock()
+		list, ok := s.rooms[roomID]
+		s.roomsLock.RUnlock()
+		if ok {
+			output = append(output, list.MatchContent(body)...)
+		}
+	}
+	return
+}
+
 func (s *Store) match(listIDs []id.RoomID, entity string, listGetter func(*Room) *List) (output Match) {
 	if listIDs == nil {
 		s.roomsLock.Lock()
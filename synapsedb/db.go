@@ -2,6 +2,7 @@ package synapsedb
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/lib/pq"
@@ -58,6 +59,31 @@ const getEventQuery = `
 	WHERE events.event_id = $1
 `
 
+const getRecentMessagesBySenderQuery = `
+	SELECT events.event_id, events.room_id, sender, type, state_key, origin_server_ts, json
+	FROM events
+	LEFT JOIN event_json ON events.event_id=event_json.event_id
+	LEFT JOIN redactions ON events.event_id=redactions.redacts
+	WHERE events.sender = $1 AND events.room_id = ANY($2) AND events.type IN ('m.room.message', 'm.sticker')
+	  AND redactions.redacts IS NULL
+	ORDER BY events.origin_server_ts DESC
+	LIMIT $3
+`
+
+const getRoomsByCreatorQuery = `
+	SELECT room_id FROM rooms WHERE creator = $1
+`
+
+// room_memberships is indexed by user ID, so this is much cheaper than scanning events,
+// and every room where the user has sent events will have a membership row.
+const getUserActiveRoomsQuery = `
+	SELECT DISTINCT room_id FROM room_memberships WHERE user_id = $1 AND room_id = ANY($2)
+`
+
+const getRoomIDBatchQuery = `
+	SELECT room_id FROM rooms WHERE room_id > $1 ORDER BY room_id LIMIT $2
+`
+
 type roomEventTuple struct {
 	RoomID    id.RoomID
 	EventID   id.EventID
@@ -98,6 +124,86 @@ func (s *SynapseDB) GetEvent(ctx context.Context, eventID id.EventID) (*event.Ev
 	return &evt, nil
 }
 
+var scanEvent = dbutil.ConvertRowFn[*event.Event](func(row dbutil.Scannable) (*event.Event, error) {
+	var evt event.Event
+	err := row.Scan(&evt.ID, &evt.RoomID, &evt.Sender, &evt.Type.Type, &evt.StateKey, &evt.Timestamp, dbutil.JSON{Data: &evt})
+	if err != nil {
+		return nil, err
+	}
+	evt.Type.Class = event.MessageEventType
+	if evt.StateKey != nil {
+		evt.Type.Class = event.StateEventType
+	}
+	return &evt, nil
+})
+
+// GetRecentMessages returns the most recent unredacted messages sent by the given user in the given rooms.
+func (s *SynapseDB) GetRecentMessages(ctx context.Context, sender id.UserID, inRooms []id.RoomID, limit int) ([]*event.Event, error) {
+	return scanEvent.NewRowIter(
+		s.DB.Query(ctx, getRecentMessagesBySenderQuery, sender, pq.Array(exslices.CastToString[string](inRooms)), limit),
+	).AsList()
+}
+
+var roomIDScanner = dbutil.ConvertRowFn[id.RoomID](dbutil.ScanSingleColumn[id.RoomID])
+
+const (
+	roomBatchSize       = 10000
+	roomBatchMaxRetries = 5
+)
+
+// IterateRooms calls the given function for every room in the database.
+//
+// Rooms are fetched in batches ordered by room ID, which means a failed batch can be retried
+// without starting over. Iteration stops if the callback returns false or an error.
+func (s *SynapseDB) IterateRooms(ctx context.Context, fn func(id.RoomID) (bool, error)) error {
+	log := zerolog.Ctx(ctx)
+	var cursor id.RoomID
+	var total int
+	for {
+		var batch []id.RoomID
+		var err error
+		for attempt := 1; ; attempt++ {
+			batch, err = roomIDScanner.NewRowIter(s.DB.Query(ctx, getRoomIDBatchQuery, cursor, roomBatchSize)).AsList()
+			if err == nil || attempt >= roomBatchMaxRetries || ctx.Err() != nil {
+				break
+			}
+			log.Warn().Err(err).
+				Stringer("cursor", cursor).
+				Int("attempt", attempt).
+				Msg("Failed to fetch batch of rooms, retrying")
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch rooms after %s: %w", cursor, err)
+		}
+		for _, roomID := range batch {
+			if cont, err := fn(roomID); err != nil || !cont {
+				return err
+			}
+		}
+		total += len(batch)
+		if len(batch) < roomBatchSize {
+			log.Debug().Int("room_count", total).Msg("Finished iterating rooms")
+			return nil
+		}
+		cursor = batch[len(batch)-1]
+		log.Debug().Int("room_count", total).Stringer("cursor", cursor).Msg("Iterating rooms")
+	}
+}
+
+// GetRoomsCreatedBy returns the IDs of all rooms created by the given user.
+func (s *SynapseDB) GetRoomsCreatedBy(ctx context.Context, creator id.UserID) ([]id.RoomID, error) {
+	return roomIDScanner.NewRowIter(s.DB.Query(ctx, getRoomsByCreatorQuery, creator)).AsList()
+}
+
+// GetUserActiveRooms returns the subset of the given rooms where the user has any membership events,
+// which is used to narrow down the rooms that need to be scanned when redacting.
+func (s *SynapseDB) GetUserActiveRooms(ctx context.Context, userID id.UserID, inRooms []id.RoomID) ([]id.RoomID, error) {
+	return roomIDScanner.NewRowIter(
+		s.DB.Query(ctx, getUserActiveRoomsQuery, userID, pq.Array(exslices.CastToString[string](inRooms))),
+	).AsList()
+}
+
 func (s *SynapseDB) Close() error {
 	return s.DB.Close()
 }